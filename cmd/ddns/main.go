@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"ddns/pkg/addr"
 	"ddns/pkg/config"
 	"ddns/pkg/engine"
 	"ddns/pkg/log"
@@ -32,6 +33,7 @@ func main() {
 	configPath := flag.String("c", "", "请输入配置文件路径")
 	enableWeb := flag.Bool("web", false, "是否启动 Web 控制台")
 	listenPort := flag.String("p", "8686", "Web 控制台监听端口")
+	statePath := flag.String("state", "", "同步状态文件路径，默认保存在配置文件旁边")
 	showVersion := flag.Bool("version", false, "输出当前版本")
 	flag.Parse()
 	if *showVersion {
@@ -70,7 +72,11 @@ func main() {
 
 	}
 
-	ddnsEngine := engine.NewEngine(configManager)
+	// 同步状态保存在配置文件旁边，重启和热重载后不必重新查询所有子域名
+	if *statePath == "" {
+		*statePath = engine.StatePath(path)
+	}
+	ddnsEngine := engine.NewEngine(configManager, engine.NewFileStateStore(*statePath))
 
 	// 监听操作系统停止信号，ctr+c
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -84,8 +90,9 @@ func main() {
 			ConfigChanges: configManager,
 			Logs:          log.DefaultBuffer,
 			CloudOperatorFactory: func(p config.Provider) (web.CloudOperator, error) {
-				return engine.NewOperator(p.Provider, p.KeyID, p.KeySecret)
+				return engine.NewOperator(p)
 			},
+			Pusher: addr.DefaultPushHub,
 		})
 		if err != nil {
 			slog.Error("Web 控制台初始化失败", "error", err)
@@ -2,6 +2,7 @@ package addr
 
 import (
 	"context"
+	"ddns/pkg/provider"
 	"fmt"
 	"net/netip"
 	"regexp"
@@ -10,8 +11,15 @@ import (
 // Addr 获取IP地址，通过系统命令、DUID、系统网卡、URL等方式获取IP地址
 // 系统命令支持linux、windows、macOS操作系统
 // DUID支持OpenWrt软路由系统
+// MAC支持通过邻居表、DHCP租约和EUI-64查找局域网主机的IP地址
+// OpenWrt支持通过ubus读取逻辑接口的地址和委派前缀
 // 系统网卡支持获取本地网卡的IP地址
 // URL支持通过访问URL获取IP地址
+// 系统命令和URL可以通过 Extractor 读取 JSON 字段、正则捕获组或指定行列，不指定时扫描全部内容
+// STUN支持通过STUN服务器获取NAT映射后的公网IP地址
+// DNS支持通过查询返回来源地址的DNS服务器获取公网IP地址
+// 路由器支持通过 UPnP IGD、NAT-PMP、PCP 获取路由器的WAN地址
+// DynDNS 接收客户端通过 /nic/update 推送的IP地址
 // 返回netip.Addr切片或者error
 
 var (
@@ -28,16 +36,52 @@ type Fetcher interface {
 	Fetch(context.Context) ([]netip.Addr, error)
 }
 
-func NewFetcher(getType string, getValue string) (Fetcher, error) {
+// Options 创建 Fetcher 时的可选参数
+type Options struct {
+	// Version 记录的 IP 版本，DNS 方式按版本选择查询使用的网络
+	Version provider.Version
+	// Extractor 只对 cmd、url 方式生效，为 nil 时扫描全部输出
+	Extractor Extractor
+	// Quorum 只对 url 方式生效，大于 0 时至少 Quorum 个 URL 返回同一地址才采用
+	Quorum int
+	// Accept URL 一致性模式只统计满足条件的地址，通常与记录的版本和地址策略一致
+	Accept Filter
+	// Command 只对 cmd 方式生效，结构化命令的参数、工作目录、环境变量和超时，为 nil 时通过 shell 执行 getValue
+	Command *Execute
+}
+
+// NewFetcher 根据获取方式创建 Fetcher
+func NewFetcher(getType string, getValue string, opts Options) (Fetcher, error) {
 	switch getType {
 	case "cmd":
-		return NewCommand(getValue), nil
+		command := NewCommand(getValue)
+		if opts.Command != nil {
+			executor := *opts.Command
+			executor.Command = getValue
+			command.executor = &executor
+		}
+		command.Extractor = opts.Extractor
+		return command, nil
 	case "duid":
-		return NewDuid(getValue), nil
+		return NewDuid(getValue)
+	case "mac":
+		return NewMac(getValue)
 	case "nic":
 		return NewNic(getValue), nil
+	case "openwrt":
+		return NewOpenWrt(getValue)
 	case "url":
-		return NewUrl(getValue), nil
+		url := NewUrl(getValue)
+		url.Extractor, url.Quorum, url.Accept = opts.Extractor, opts.Quorum, opts.Accept
+		return url, nil
+	case "stun":
+		return NewStun(getValue), nil
+	case "dns":
+		return NewDns(getValue, opts.Version), nil
+	case "router":
+		return NewRouter(getValue), nil
+	case "dyndns":
+		return NewPush(getValue), nil
 	default:
 		return nil, fmt.Errorf("addr NewFetcher: 不支持的获取方式: %s", getType)
 	}
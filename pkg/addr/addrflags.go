@@ -0,0 +1,124 @@
+package addr
+
+import (
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// 本机地址的内核属性：标志和生存期
+// Linux 通过 netlink 读取，网卡方式获取到的地址、URL 方式返回的本机出口地址都可以查询
+
+// 地址标志，与 Linux 内核的 IFA_F_* 一致
+const (
+	ifaFlagTemporary  = 0x01
+	ifaFlagDeprecated = 0x20
+	ifaFlagTentative  = 0x40
+)
+
+// LifetimeForever 永久地址的生存期
+const LifetimeForever = time.Duration(math.MaxInt64)
+
+// AddrInfo 本机地址的内核属性
+type AddrInfo struct {
+	// Iface 地址所在的网卡名
+	Iface string
+	// Flags IFA_F_* 标志
+	Flags uint32
+	// Preferred 剩余的首选生存期，到期后地址变为 deprecated，永久地址为 LifetimeForever
+	Preferred time.Duration
+	// Valid 剩余的有效生存期，到期后地址被删除
+	Valid time.Duration
+}
+
+// Temporary 是否为隐私扩展生成的临时地址
+func (i AddrInfo) Temporary() bool {
+	return i.Flags&ifaFlagTemporary != 0
+}
+
+// Deprecated 首选生存期是否已经到期，新连接不再使用该地址
+func (i AddrInfo) Deprecated() bool {
+	return i.Flags&ifaFlagDeprecated != 0
+}
+
+// Tentative 是否还在进行重复地址检测
+func (i AddrInfo) Tentative() bool {
+	return i.Flags&ifaFlagTentative != 0
+}
+
+// AddrKey 本机地址属性的索引，同一地址（如链路本地地址 fe80::1、网桥和成员网卡上的 ULA）可以出现在多个网卡上
+type AddrKey struct {
+	// Index 网卡序号
+	Index int
+	Addr  netip.Addr
+}
+
+// AddrInfos 本机所有地址的属性，按网卡和地址索引
+type AddrInfos map[AddrKey]AddrInfo
+
+// Lookup 返回地址的属性，ifaces 为按顺序优先查找的网卡名，如 nic 获取方式读取的网卡
+// 地址不在这些网卡上时在所有网卡中查找，多个网卡上都有时合并：所有网卡上都有的标志才保留，生存期取最长的
+func (m AddrInfos) Lookup(addr netip.Addr, ifaces []string) AddrInfo {
+	addr = addr.WithZone("").Unmap()
+	for _, iface := range ifaces {
+		for key, info := range m {
+			if key.Addr == addr && info.Iface == iface {
+				return info
+			}
+		}
+	}
+	var merged AddrInfo
+	found := false
+	for key, info := range m {
+		if key.Addr != addr {
+			continue
+		}
+		if !found {
+			merged, found = info, true
+			continue
+		}
+		merged.Iface = ""
+		merged.Flags &= info.Flags
+		merged.Preferred = max(merged.Preferred, info.Preferred)
+		merged.Valid = max(merged.Valid, info.Valid)
+	}
+	return merged
+}
+
+// addrInfoTTL 本机地址属性快照的有效时间，一次过滤的所有地址共用同一次读取
+const addrInfoTTL = time.Second
+
+// addrInfoSnapshot 缓存最近一次读取的本机地址属性，避免逐个地址读取全部地址
+type addrInfoSnapshot struct {
+	lookup func() (AddrInfos, error)
+
+	mu    sync.Mutex
+	at    time.Time
+	infos AddrInfos
+	err   error
+}
+
+func newAddrInfoSnapshot(lookup func() (AddrInfos, error)) *addrInfoSnapshot {
+	return &addrInfoSnapshot{lookup: lookup}
+}
+
+// get 返回本机地址属性，快照过期后重新读取
+func (s *addrInfoSnapshot) get() (AddrInfos, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.at.IsZero() || time.Since(s.at) >= addrInfoTTL {
+		s.infos, s.err = s.lookup()
+		s.at = time.Now()
+	}
+	return s.infos, s.err
+}
+
+// isTemporary 地址是否为本机隐私扩展生成的临时地址，ifaces 为优先查找的网卡名，无法读取地址属性时返回 false
+func (s *addrInfoSnapshot) isTemporary(addr netip.Addr, ifaces []string) bool {
+	infos, err := s.get()
+	if err != nil {
+		return false
+	}
+	return infos.Lookup(addr, ifaces).Temporary()
+}
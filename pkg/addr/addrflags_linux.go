@@ -0,0 +1,95 @@
+//go:build linux
+
+package addr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const (
+	// ifaFlagsAttr IFA_FLAGS 属性，32 位的完整标志，ifaddrmsg 中只有低 8 位
+	ifaFlagsAttr = 8
+	// lifetimeInfinity ifa_cacheinfo 中表示永久的生存期
+	lifetimeInfinity = 0xFFFFFFFF
+)
+
+// LocalAddrInfo 通过 netlink（RTM_GETADDR）读取本机所有地址的标志和生存期
+func LocalAddrInfo() (AddrInfos, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETADDR, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("读取地址属性失败: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("解析地址属性失败: %w", err)
+	}
+	names := make(map[uint32]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[uint32(iface.Index)] = iface.Name
+		}
+	}
+	return parseAddrMessages(msgs, names), nil
+}
+
+// parseAddrMessages 解析 RTM_NEWADDR 消息，names 为网卡序号到网卡名的映射
+// 按网卡序号和地址索引，同一地址在多个网卡上时分别保留
+func parseAddrMessages(msgs []syscall.NetlinkMessage, names map[uint32]string) AddrInfos {
+	infos := make(AddrInfos)
+	for _, msg := range msgs {
+		if msg.Header.Type != syscall.RTM_NEWADDR || len(msg.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+		// struct ifaddrmsg: family, prefixlen, flags, scope, index
+		index := binary.NativeEndian.Uint32(msg.Data[4:8])
+		info := AddrInfo{
+			Iface:     names[index],
+			Flags:     uint32(msg.Data[2]),
+			Preferred: LifetimeForever,
+			Valid:     LifetimeForever,
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&msg)
+		if err != nil {
+			continue
+		}
+		var address, local netip.Addr
+		for _, attr := range attrs {
+			switch attr.Attr.Type {
+			case syscall.IFA_ADDRESS:
+				address, _ = netip.AddrFromSlice(attr.Value)
+			case syscall.IFA_LOCAL:
+				// 点对点网卡的 IFA_ADDRESS 为对端地址，本机地址在 IFA_LOCAL 中
+				local, _ = netip.AddrFromSlice(attr.Value)
+			case ifaFlagsAttr:
+				if len(attr.Value) >= 4 {
+					info.Flags = binary.NativeEndian.Uint32(attr.Value)
+				}
+			case syscall.IFA_CACHEINFO:
+				// struct ifa_cacheinfo: ifa_prefered, ifa_valid, cstamp, tstamp
+				if len(attr.Value) >= 8 {
+					info.Preferred = lifetime(binary.NativeEndian.Uint32(attr.Value[0:4]))
+					info.Valid = lifetime(binary.NativeEndian.Uint32(attr.Value[4:8]))
+				}
+			}
+		}
+		if local.IsValid() {
+			address = local
+		}
+		if address.IsValid() {
+			infos[AddrKey{Index: int(index), Addr: address.Unmap()}] = info
+		}
+	}
+	return infos
+}
+
+func lifetime(seconds uint32) time.Duration {
+	if seconds == lifetimeInfinity {
+		return LifetimeForever
+	}
+	return time.Duration(seconds) * time.Second
+}
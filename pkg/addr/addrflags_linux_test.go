@@ -0,0 +1,68 @@
+//go:build linux
+
+package addr
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestParseAddrMessages(t *testing.T) {
+	msgs := []syscall.NetlinkMessage{
+		addrMessage(2, 0, netip.MustParseAddr("2001:db8::1"), ifaFlagDeprecated|ifaFlagTemporary, 0, 3600),
+		addrMessage(2, 0x80, netip.MustParseAddr("2001:db8::2"), 0x80, lifetimeInfinity, lifetimeInfinity),
+	}
+	infos := parseAddrMessages(msgs, map[uint32]string{2: "pppoe-wan"})
+
+	old := infos.Lookup(netip.MustParseAddr("2001:db8::1"), nil)
+	if !old.Temporary() || !old.Deprecated() || old.Preferred != 0 || old.Valid != time.Hour || old.Iface != "pppoe-wan" {
+		t.Fatalf("deprecated temporary address = %+v", old)
+	}
+	stable := infos.Lookup(netip.MustParseAddr("2001:db8::2"), nil)
+	if stable.Temporary() || stable.Deprecated() || stable.Preferred != LifetimeForever {
+		t.Fatalf("permanent address = %+v", stable)
+	}
+}
+
+func TestParseAddrMessagesKeepsAddressPerInterface(t *testing.T) {
+	// 网桥和成员网卡上有同一个 ULA，只有成员网卡上是临时地址
+	ula := netip.MustParseAddr("fd00::1")
+	msgs := []syscall.NetlinkMessage{
+		addrMessage(3, 0, ula, ifaFlagTemporary, 600, 3600),
+		addrMessage(4, 0, ula, 0, lifetimeInfinity, lifetimeInfinity),
+	}
+	infos := parseAddrMessages(msgs, map[uint32]string{3: "lan1", 4: "br-lan"})
+	if len(infos) != 2 {
+		t.Fatalf("parseAddrMessages() = %v, want one entry per interface", infos)
+	}
+	if info := infos.Lookup(ula, []string{"br-lan"}); info.Iface != "br-lan" || info.Temporary() {
+		t.Fatalf("Lookup(br-lan) = %+v", info)
+	}
+	if info := infos.Lookup(ula, []string{"lan1"}); info.Iface != "lan1" || !info.Temporary() {
+		t.Fatalf("Lookup(lan1) = %+v", info)
+	}
+	// 没有指定网卡时只保留所有网卡上都有的标志
+	if info := infos.Lookup(ula, nil); info.Temporary() || info.Preferred != LifetimeForever {
+		t.Fatalf("Lookup() = %+v, want merged stable address", info)
+	}
+}
+
+// addrMessage 构造带 IFA_ADDRESS、IFA_FLAGS 和 IFA_CACHEINFO 属性的 RTM_NEWADDR 消息
+func addrMessage(index uint32, shortFlags uint8, addr netip.Addr, flags, preferred, valid uint32) syscall.NetlinkMessage {
+	data := []byte{syscall.AF_INET6, 64, shortFlags, 0}
+	data = binary.NativeEndian.AppendUint32(data, index)
+	attr := func(kind uint16, value []byte) {
+		data = binary.NativeEndian.AppendUint16(data, uint16(4+len(value)))
+		data = binary.NativeEndian.AppendUint16(data, kind)
+		data = append(data, value...)
+	}
+	attr(syscall.IFA_ADDRESS, addr.AsSlice())
+	attr(ifaFlagsAttr, binary.NativeEndian.AppendUint32(nil, flags))
+	cacheinfo := binary.NativeEndian.AppendUint32(nil, preferred)
+	cacheinfo = binary.NativeEndian.AppendUint32(cacheinfo, valid)
+	attr(syscall.IFA_CACHEINFO, append(cacheinfo, make([]byte, 8)...))
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWADDR}, Data: data}
+}
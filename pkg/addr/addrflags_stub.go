@@ -0,0 +1,10 @@
+//go:build !linux
+
+package addr
+
+import "fmt"
+
+// LocalAddrInfo 在非 Linux 系统下直接返回错误
+func LocalAddrInfo() (AddrInfos, error) {
+	return nil, fmt.Errorf("读取地址属性仅支持 Linux 系统")
+}
@@ -1,8 +1,22 @@
 package addr
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"net/netip"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultCommandTimeout 命令的默认超时时间
+	DefaultCommandTimeout = 5 * time.Second
+	// maxStderrBytes 错误信息中保留的标准错误输出长度
+	maxStderrBytes = 1024
 )
 
 // Command 获取IP地址，通过系统命令获取IP地址
@@ -10,6 +24,8 @@ import (
 type Command struct {
 	// executor 执行系统命令的工具
 	executor *Execute
+	// Extractor 从命令输出中读取IP地址，为 nil 时扫描全部内容
+	Extractor Extractor
 }
 
 // NewCommand 创建一个新的Command实例
@@ -27,5 +43,95 @@ func (c *Command) Fetch(ctx context.Context) ([]netip.Addr, error) {
 		return nil, err
 	}
 	// 解析命令输出，提取IP地址
-	return extractFromString(string(output))
+	return extract(c.Extractor, output)
+}
+
+// Execute 执行系统命令
+// 填写 Args 时直接执行程序，不经过 shell；否则通过系统的 shell（sh、zsh、powershell）执行 Command
+type Execute struct {
+	Command string
+	// Args 程序和参数，第一个为程序名或路径
+	Args []string
+	// Dir 工作目录，为空时使用当前目录
+	Dir string
+	// Env 追加的环境变量，格式 KEY=VALUE
+	Env []string
+	// Timeout 超时时间，为 0 时使用 DefaultCommandTimeout
+	Timeout time.Duration
+}
+
+// NewExecute 创建一个新的Execute实例
+func NewExecute(command string) *Execute {
+	return &Execute{
+		Command: command,
+	}
+}
+
+// Execute 执行系统命令，返回命令输出的字节切片或者error
+// 命令失败时错误中包含退出码和标准错误输出
+func (e *Execute) Execute(ctx context.Context) ([]byte, error) {
+	args := e.Args
+	if len(args) == 0 {
+		if e.Command == "" {
+			return nil, errors.New(emptyCommandHint)
+		}
+		args = shellArgs(e.Command)
+	}
+	// 设置一个超时时间，防止参数没有设置超时和命令执行时间过长
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = e.Dir
+	if len(e.Env) > 0 {
+		cmd.Env = append(os.Environ(), e.Env...)
+	}
+	stderr := &limitedBuffer{max: maxStderrBytes}
+	cmd.Stderr = stderr
+	// 子进程继承了输出管道时，命令退出后最多再等待1秒
+	cmd.WaitDelay = time.Second
+	output, err := cmd.Output()
+	if err == nil {
+		return output, nil
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("Execute：命令执行超时（%v）%s", timeout, stderr.suffix())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("Execute：命令退出码 %d%s", exitErr.ExitCode(), stderr.suffix())
+	}
+	return nil, fmt.Errorf("Execute：执行命令失败: %w", err)
+}
+
+// limitedBuffer 只保留前 max 字节的输出，超出部分丢弃
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remain := b.max - b.buf.Len(); remain < len(p) {
+		b.buf.Write(p[:max(remain, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// suffix 返回附加在错误信息后的标准错误输出，没有输出时为空
+func (b *limitedBuffer) suffix() string {
+	text := strings.TrimSpace(strings.ToValidUTF8(b.buf.String(), ""))
+	if text == "" {
+		return ""
+	}
+	if b.truncated {
+		text += "..."
+	}
+	return ", stderr: " + text
 }
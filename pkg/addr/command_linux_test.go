@@ -0,0 +1,43 @@
+package addr
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteArgsDirAndEnv(t *testing.T) {
+	command := NewCommand("")
+	command.executor = &Execute{
+		Args: []string{"sh", "-c", `test "$(pwd)" = / && echo "$PREFIX.1"`, "ignored"},
+		Dir:  "/",
+		Env:  []string{"PREFIX=203.0.113"},
+	}
+	ips, err := command.Fetch(context.Background())
+	if err != nil || !slices.Equal(ips, []netip.Addr{netip.MustParseAddr("203.0.113.1")}) {
+		t.Fatalf("Fetch() = %v, %v", ips, err)
+	}
+}
+
+func TestExecuteReportsFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		exec Execute
+		want string
+	}{
+		{"exit code", Execute{Command: "echo 'no route' >&2; exit 3"}, "命令退出码 3, stderr: no route"},
+		{"timeout", Execute{Args: []string{"sleep", "5"}, Timeout: 50 * time.Millisecond}, "命令执行超时（50ms）"},
+		{"not found", Execute{Args: []string{"/nonexistent/ddns-test"}}, "执行命令失败"},
+		{"empty", Execute{}, "请提供Linux系统命令"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.exec.Execute(context.Background()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Execute() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
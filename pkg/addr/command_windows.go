@@ -2,32 +2,10 @@
 
 package addr
 
-import (
-	"context"
-	"fmt"
-	"os/exec"
-	"time"
-)
+// emptyCommandHint 没有填写命令时的提示
+const emptyCommandHint = "Execute：请提供Windows系统命令，如：Get-NetIPAddress"
 
-// Execute 执行系统命令
-type Execute struct {
-	Command string
-}
-
-// NewExecute 创建一个新的Execute实例
-func NewExecute(command string) *Execute {
-	return &Execute{
-		Command: command,
-	}
-}
-
-// Execute 执行系统命令，返回命令输出的字节切片或者error
-func (e *Execute) Execute(ctx context.Context) ([]byte, error) {
-	if e.Command == "" {
-		return nil, fmt.Errorf("Execute：请提供Windows系统命令，如：Get-NetIPAddress")
-	}
-	// 设置一个超时时间，防止参数没有设置超时和命令执行时间过长
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-	return exec.CommandContext(ctx, "powershell", "-Command", e.Command).Output()
+// shellArgs 返回通过 shell 执行命令的参数
+func shellArgs(command string) []string {
+	return []string{"powershell", "-Command", command}
 }
@@ -0,0 +1,288 @@
+package addr
+
+import (
+	"context"
+	"crypto/rand"
+	"ddns/pkg/provider"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// 通过 DNS 查询获取公网IP地址
+// 一些 DNS 服务器会把查询来源地址作为应答返回，例如：
+//   myip.opendns.com @resolver1.opendns.com
+//   o-o.myaddr.l.google.com TXT @ns1.google.com
+//   whoami.cloudflare CH TXT @one.one.one.one
+// 查询固定通过 IPv4 或 IPv6 发送，返回的就是对应版本的公网地址
+
+const (
+	dnsDefaultPort = "53"
+	dnsTimeout     = 5 * time.Second
+	// dnsMaxUDPBytes 通过 EDNS0 声明的 UDP 报文大小
+	dnsMaxUDPBytes = 1232
+)
+
+// Dns 通过 DNS 查询获取公网IP地址
+type Dns struct {
+	// Queries 查询列表，多个使用英文逗号分隔，格式与 dig 类似：name [type] [class] @server
+	Queries string
+	version provider.Version
+	dialer  net.Dialer
+}
+
+func NewDns(queries string, version provider.Version) *Dns {
+	return &Dns{Queries: queries, version: version, dialer: net.Dialer{Timeout: dnsTimeout}}
+}
+
+// dnsQuery 解析后的一条查询
+type dnsQuery struct {
+	name   string
+	qtype  dnsmessage.Type
+	class  dnsmessage.Class
+	server string
+}
+
+var (
+	dnsTypes   = map[string]dnsmessage.Type{"A": dnsmessage.TypeA, "AAAA": dnsmessage.TypeAAAA, "TXT": dnsmessage.TypeTXT}
+	dnsClasses = map[string]dnsmessage.Class{"IN": dnsmessage.ClassINET, "CH": dnsmessage.ClassCHAOS}
+)
+
+// parseDnsQuery 解析 "name [type] [class] @server" 格式的查询，没有指定类型时按 IP 版本查询 A 或 AAAA
+func parseDnsQuery(value string, version provider.Version) (dnsQuery, error) {
+	query := dnsQuery{qtype: dnsmessage.TypeA, class: dnsmessage.ClassINET}
+	if version == provider.IPv6 {
+		query.qtype = dnsmessage.TypeAAAA
+	}
+	for _, field := range strings.Fields(value) {
+		if server, ok := strings.CutPrefix(field, "@"); ok {
+			query.server = server
+			continue
+		}
+		if qtype, ok := dnsTypes[strings.ToUpper(field)]; ok {
+			query.qtype = qtype
+			continue
+		}
+		if class, ok := dnsClasses[strings.ToUpper(field)]; ok {
+			query.class = class
+			continue
+		}
+		if query.name != "" {
+			return query, fmt.Errorf("无法识别的字段 %q", field)
+		}
+		query.name = field
+	}
+	if query.name == "" {
+		return query, fmt.Errorf("缺少查询的域名")
+	}
+	if query.server == "" {
+		return query, fmt.Errorf("缺少 @DNS服务器")
+	}
+	if _, _, err := net.SplitHostPort(query.server); err != nil {
+		query.server = net.JoinHostPort(strings.Trim(query.server, "[]"), dnsDefaultPort)
+	}
+	if !strings.HasSuffix(query.name, ".") {
+		query.name += "."
+	}
+	return query, nil
+}
+
+func (d *Dns) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	var queries []dnsQuery
+	for _, value := range strings.Split(d.Queries, ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		query, err := parseDnsQuery(value, d.version)
+		if err != nil {
+			return nil, fmt.Errorf("DNS Fetcher: 查询 %q 格式无效: %w", strings.TrimSpace(value), err)
+		}
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("DNS Fetcher: 请提供查询的域名和DNS服务器")
+	}
+
+	// 并发查询，结果按配置顺序保存
+	results := make([][]netip.Addr, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = d.lookup(ctx, query)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s@%s: %w", query.name, query.server, errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var ips []netip.Addr
+	for _, result := range results {
+		ips = append(ips, result...)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DNS Fetcher: 没有解析到IP地址: %w", errors.Join(errs...))
+	}
+	return ips, nil
+}
+
+// network 按 IP 版本返回网络类型，保证查询从对应版本的地址发出
+func (d *Dns) network(base string) string {
+	if d.version == provider.IPv6 {
+		return base + "6"
+	}
+	return base + "4"
+}
+
+// lookup 先通过 UDP 查询，应答被截断时改用 TCP
+func (d *Dns) lookup(ctx context.Context, query dnsQuery) ([]netip.Addr, error) {
+	request, id, err := buildDnsQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, dnsTimeout)
+	defer cancel()
+
+	response, err := d.exchange(ctx, d.network("udp"), query.server, request)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := parseDnsAnswer(response, id)
+	if errors.Is(err, errDnsTruncated) {
+		if response, err = d.exchange(ctx, d.network("tcp"), query.server, request); err != nil {
+			return nil, err
+		}
+		addrs, err = parseDnsAnswer(response, id)
+	}
+	return addrs, err
+}
+
+// exchange 发送查询并读取一个应答，TCP 报文带两字节长度前缀
+func (d *Dns) exchange(ctx context.Context, network, server string, request []byte) ([]byte, error) {
+	conn, err := d.dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if strings.HasPrefix(network, "tcp") {
+		if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(request)))); err != nil {
+			return nil, err
+		}
+		if _, err := conn.Write(request); err != nil {
+			return nil, err
+		}
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return nil, err
+		}
+		response := make([]byte, binary.BigEndian.Uint16(length[:]))
+		_, err := io.ReadFull(conn, response)
+		return response, err
+	}
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+	response := make([]byte, dnsMaxUDPBytes)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+	return response[:n], nil
+}
+
+func buildDnsQuery(query dnsQuery) ([]byte, uint16, error) {
+	name, err := dnsmessage.NewName(query.name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("域名无效: %w", err)
+	}
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return nil, 0, err
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	builder := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{ID: id, RecursionDesired: true})
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: name, Type: query.qtype, Class: query.class}); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(dnsMaxUDPBytes, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, 0, err
+	}
+	if err := builder.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := builder.Finish()
+	return msg, id, err
+}
+
+var errDnsTruncated = errors.New("DNS 应答被截断")
+
+// parseDnsAnswer 读取应答中的 A、AAAA 记录和 TXT 记录中的IP地址
+func parseDnsAnswer(response []byte, id uint16) ([]netip.Addr, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(response)
+	if err != nil {
+		return nil, fmt.Errorf("解析 DNS 应答失败: %w", err)
+	}
+	if header.ID != id || !header.Response {
+		return nil, fmt.Errorf("DNS 应答与查询不匹配")
+	}
+	if header.Truncated {
+		return nil, errDnsTruncated
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("DNS 服务器返回错误: %v", header.RCode)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, fmt.Errorf("解析 DNS 应答失败: %w", err)
+	}
+	var ips []netip.Addr
+	for {
+		answer, err := parser.Answer()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 DNS 应答失败: %w", err)
+		}
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			ips = append(ips, netip.AddrFrom4(body.A))
+		case *dnsmessage.AAAAResource:
+			ips = append(ips, netip.AddrFrom16(body.AAAA).Unmap())
+		case *dnsmessage.TXTResource:
+			if found, err := extractFromString(strings.Join(body.TXT, " ")); err == nil {
+				ips = append(ips, found...)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("DNS 应答中没有IP地址")
+	}
+	return ips, nil
+}
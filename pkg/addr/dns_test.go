@@ -0,0 +1,81 @@
+package addr
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"ddns/pkg/provider"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestDnsFetchReadsAddressAndTXTAnswers(t *testing.T) {
+	server := startDnsServer(t)
+	fetcher := NewDns("myip.example.com @"+server+", whoami.example.com CH TXT @"+server, provider.IPv4)
+	got, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].String() != "203.0.113.5" || got[1].String() != "198.51.100.7" {
+		t.Fatalf("Fetch() = %v", got)
+	}
+}
+
+func TestParseDnsQuery(t *testing.T) {
+	query, err := parseDnsQuery("o-o.myaddr.l.google.com TXT @ns1.google.com", provider.IPv6)
+	if err != nil || query.name != "o-o.myaddr.l.google.com." || query.qtype != dnsmessage.TypeTXT || query.server != "ns1.google.com:53" {
+		t.Fatalf("parseDnsQuery() = %+v, %v", query, err)
+	}
+	query, err = parseDnsQuery("myip.opendns.com @2620:119:35::35", provider.IPv6)
+	if err != nil || query.qtype != dnsmessage.TypeAAAA || query.server != "[2620:119:35::35]:53" {
+		t.Fatalf("parseDnsQuery() = %+v, %v", query, err)
+	}
+	if _, err := parseDnsQuery("myip.opendns.com", provider.IPv4); err == nil {
+		t.Fatal("parseDnsQuery() accepted a query without server")
+	}
+}
+
+// startDnsServer 启动本地 UDP DNS 服务器：IN A 查询返回 203.0.113.5，CH TXT 查询返回 198.51.100.7
+func startDnsServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buf[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+			builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: header.ID, Response: true})
+			_ = builder.StartQuestions()
+			_ = builder.Question(question)
+			_ = builder.StartAnswers()
+			rh := dnsmessage.ResourceHeader{Name: question.Name, Class: question.Class, TTL: 0}
+			switch question.Type {
+			case dnsmessage.TypeA:
+				_ = builder.AResource(rh, dnsmessage.AResource{A: [4]byte{203, 0, 113, 5}})
+			case dnsmessage.TypeTXT:
+				_ = builder.TXTResource(rh, dnsmessage.TXTResource{TXT: []string{"198.51.100.7"}})
+			}
+			msg, err := builder.Finish()
+			if err == nil {
+				_, _ = conn.WriteTo(msg, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr).AddrPort().String()
+}
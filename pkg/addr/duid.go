@@ -0,0 +1,577 @@
+package addr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duid 通过 DHCPv6 唯一标识（DUID）从 DHCPv6 服务器的租约中获取局域网主机的 IPv6 地址
+// getValue 格式为 DUID 或 DUID@来源，多个来源使用英文逗号分隔，依次读取并合并结果：
+//   ubus            OpenWrt 的 ubus call dhcp ipv6leases，包含所有网卡
+//   dnsmasq[:文件]   dnsmasq 租约文件
+//   odhcpd[:文件]    odhcpd 的 leasefile（hosts 文件）
+//   isc[:文件]       ISC dhcpd 的 dhcpd6.leases
+//   kea[:文件]       Kea 的 memfile 租约文件 kea-leases6.csv
+//   keactl[:套接字]  Kea 的控制套接字，需要加载 lease_cmds 钩子
+// 不填写来源时自动使用系统中存在的所有来源
+
+const leaseTimeout = 5 * time.Second
+
+// leaseDefaults 各来源的默认路径，按顺序使用第一个存在的文件
+var leaseDefaults = map[string][]string{
+	"dnsmasq": {"/tmp/dhcp.leases", "/var/lib/misc/dnsmasq.leases", "/var/lib/dnsmasq/dnsmasq.leases"},
+	"odhcpd":  {"/tmp/hosts/odhcpd", "/tmp/odhcpd.leases"},
+	"isc":     {"/var/lib/dhcp/dhcpd6.leases", "/var/lib/dhcpd/dhcpd6.leases", "/var/db/dhcpd6.leases"},
+	"kea":     {"/var/lib/kea/kea-leases6.csv", "/var/lib/kea/dhcp6.leases"},
+	"keactl":  {"/run/kea/kea6-ctrl-socket", "/tmp/kea6-ctrl-socket"},
+}
+
+// leaseSourceOrder 自动检测时的来源顺序
+var leaseSourceOrder = []string{"ubus", "dnsmasq", "odhcpd", "isc", "kea", "keactl"}
+
+// LeaseSource DHCPv6 租约来源，返回 DUID（小写十六进制，不含分隔符）到IP地址列表的映射
+type LeaseSource interface {
+	Leases(ctx context.Context) (map[string][]netip.Addr, error)
+}
+
+// Duid
+type Duid struct {
+	Duid string
+	// Sources 租约来源，为空时自动检测
+	Sources []LeaseSource
+}
+
+// NewDuid 解析 DUID 和租约来源，DUID 可以使用冒号或短横线分隔
+func NewDuid(value string) (*Duid, error) {
+	duidStr, sourcesStr, _ := strings.Cut(value, "@")
+	duid, err := NormalizeDuid(duidStr)
+	if err != nil {
+		return nil, err
+	}
+	d := &Duid{Duid: duid}
+	for _, spec := range strings.Split(sourcesStr, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		source, err := NewLeaseSource(spec)
+		if err != nil {
+			return nil, err
+		}
+		d.Sources = append(d.Sources, source)
+	}
+	return d, nil
+}
+
+// NormalizeDuid 去掉 DUID 中的冒号、短横线并转为小写，DUID 必须是十六进制
+func NormalizeDuid(duid string) (string, error) {
+	duid = strings.ToLower(strings.NewReplacer(":", "", "-", "", " ", "").Replace(duid))
+	if duid == "" {
+		return "", fmt.Errorf("Duid Fetcher: 请提供duid")
+	}
+	if _, err := hex.DecodeString(duid); err != nil {
+		return "", fmt.Errorf("Duid Fetcher: duid 不是有效的十六进制: %s", duid)
+	}
+	return duid, nil
+}
+
+// NewLeaseSource 根据 "类型[:路径]" 创建租约来源，不填写路径时使用第一个存在的默认路径
+func NewLeaseSource(spec string) (LeaseSource, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	path = strings.TrimSpace(path)
+	if kind == "ubus" {
+		if path != "" {
+			return nil, fmt.Errorf("Duid Fetcher: ubus 来源不需要路径")
+		}
+		return &Ubus{}, nil
+	}
+	if _, ok := leaseDefaults[kind]; !ok {
+		return nil, fmt.Errorf("Duid Fetcher: 不支持的租约来源: %s，请使用 %s", kind, strings.Join(leaseSourceOrder, "、"))
+	}
+	if path == "" {
+		path = defaultLeasePath(kind)
+	}
+	switch kind {
+	case "dnsmasq":
+		return &LeaseFile{Path: path, parse: func(r io.Reader) (map[string][]netip.Addr, error) {
+			return parseDnsmasqLeases(r, time.Now())
+		}}, nil
+	case "odhcpd":
+		return &LeaseFile{Path: path, parse: func(r io.Reader) (map[string][]netip.Addr, error) {
+			return parseOdhcpdLeases(r, time.Now())
+		}}, nil
+	case "isc":
+		return &LeaseFile{Path: path, parse: parseISCLeases}, nil
+	case "kea":
+		return &LeaseFile{Path: path, parse: func(r io.Reader) (map[string][]netip.Addr, error) {
+			return parseKeaLeases(r, time.Now())
+		}}, nil
+	default:
+		return &KeaSocket{Path: path}, nil
+	}
+}
+
+// defaultLeasePath 返回第一个存在的默认路径，都不存在时返回第一个
+func defaultLeasePath(kind string) string {
+	paths := leaseDefaults[kind]
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return paths[0]
+}
+
+// detectLeaseSources 返回系统中存在的租约来源
+func detectLeaseSources() []LeaseSource {
+	var sources []LeaseSource
+	for _, kind := range leaseSourceOrder {
+		if kind == "ubus" {
+			if _, err := exec.LookPath("ubus"); err == nil {
+				sources = append(sources, &Ubus{})
+			}
+			continue
+		}
+		for _, path := range leaseDefaults[kind] {
+			if _, err := os.Stat(path); err == nil {
+				source, _ := NewLeaseSource(kind + ":" + path)
+				sources = append(sources, source)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// GetAllDuid 读取租约来源，合并所有DUID和对应的IP地址列表，sources 为空时自动检测
+// 部分来源读取失败时使用其他来源的结果，全部失败时返回错误
+func GetAllDuid(ctx context.Context, sources ...LeaseSource) (map[string][]netip.Addr, error) {
+	// 设置一个超时时间，防止ctx没有设置超时和命令执行时间过长
+	ctx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	defer cancel()
+	if len(sources) == 0 {
+		sources = detectLeaseSources()
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("Duid Fetcher: 没有找到 DHCPv6 租约来源，请在 getValue 中指定，如 DUID@dnsmasq:/var/lib/misc/dnsmasq.leases")
+		}
+	}
+
+	result := make(map[string][]netip.Addr)
+	var errs []error
+	for _, source := range sources {
+		leases, err := source.Leases(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for duid, ips := range leases {
+			for _, ip := range ips {
+				if !slices.Contains(result[duid], ip) {
+					result[duid] = append(result[duid], ip)
+				}
+			}
+		}
+	}
+	if len(result) == 0 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, fmt.Errorf("Duid Fetcher: 没有解析到IP地址")
+	}
+	return result, nil
+}
+
+// Fetch 根据DUID获取对应的IP地址列表，如果没有找到对应的IP地址，则返回错误
+func (d *Duid) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	if d.Duid == "" {
+		return nil, fmt.Errorf("Duid Fetcher: 请提供duid")
+	}
+
+	duidMap, err := GetAllDuid(ctx, d.Sources...)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, ok := duidMap[d.Duid]
+	if !ok {
+		return nil, fmt.Errorf("Duid Fetcher: duid %s 没有IP地址", d.Duid)
+	}
+
+	return ips, nil
+}
+
+// addLease 记录一个租约地址，DUID 统一格式，无效的地址和 DUID 忽略
+func addLease(result map[string][]netip.Addr, duid, address string) {
+	duid, err := NormalizeDuid(duid)
+	if err != nil {
+		return
+	}
+	ip, err := parseExtracted(address)
+	if err != nil || !ip.Is6() {
+		return
+	}
+	if !slices.Contains(result[duid], ip) {
+		result[duid] = append(result[duid], ip)
+	}
+}
+
+// Ubus 读取 OpenWrt odhcpd 通过 ubus 提供的所有网卡的 DHCPv6 租约
+type Ubus struct{}
+
+func (u *Ubus) Leases(ctx context.Context) (map[string][]netip.Addr, error) {
+	out, err := exec.CommandContext(ctx, "ubus", "call", "dhcp", "ipv6leases").Output()
+	if err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: ubus call dhcp ipv6leases 失败: %w", err)
+	}
+	return parseUbusLeases(out)
+}
+
+// parseUbusLeases 解析 ubus call dhcp ipv6leases 的输出，device 下每个网卡都有各自的租约
+func parseUbusLeases(data []byte) (map[string][]netip.Addr, error) {
+	var out struct {
+		Device map[string]struct {
+			Leases []struct {
+				Duid     string `json:"duid"`
+				Ipv6Addr []struct {
+					Address string `json:"address"`
+				} `json:"ipv6-addr"`
+			} `json:"leases"`
+		} `json:"device"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 解析 ubus 输出失败: %w", err)
+	}
+	result := make(map[string][]netip.Addr)
+	// 按网卡名排序，保证多次获取的顺序一致
+	for _, name := range slices.Sorted(maps.Keys(out.Device)) {
+		for _, lease := range out.Device[name].Leases {
+			for _, addr := range lease.Ipv6Addr {
+				addLease(result, lease.Duid, addr.Address)
+			}
+		}
+	}
+	return result, nil
+}
+
+// LeaseFile 读取 DHCP 服务器的租约文件
+type LeaseFile struct {
+	Path  string
+	parse func(r io.Reader) (map[string][]netip.Addr, error)
+}
+
+func (l *LeaseFile) Leases(ctx context.Context) (map[string][]netip.Addr, error) {
+	f, err := os.Open(l.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 读取租约文件失败: %w", err)
+	}
+	defer f.Close()
+	leases, err := l.parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 解析租约文件 %s 失败: %w", l.Path, err)
+	}
+	return leases, nil
+}
+
+// dhcpLease 租约文件中的一条地址租约，DHCPv4 租约记录 MAC 地址，DHCPv6 租约记录 DUID
+type dhcpLease struct {
+	duid string
+	mac  string
+	addr string
+}
+
+// parseDnsmasqLeases 解析 dnsmasq 租约文件中的 DHCPv6 租约
+func parseDnsmasqLeases(r io.Reader, now time.Time) (map[string][]netip.Addr, error) {
+	return groupByDuid(readDnsmasqLeases(r, now))
+}
+
+// readDnsmasqLeases 读取 dnsmasq 租约文件
+// DHCPv4 租约每行为 "过期时间 MAC地址 IPv4地址 主机名 客户端标识"，
+// DHCPv6 租约每行为 "过期时间 IAID IPv6地址 主机名 客户端DUID"，"duid" 开头的行是服务器自己的 DUID
+// 过期时间为 Unix 时间，0 表示永不过期，已过期的租约跳过
+func readDnsmasqLeases(r io.Reader, now time.Time) ([]dhcpLease, error) {
+	var leases []dhcpLease
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] == "duid" {
+			continue
+		}
+		if fields[0] != "0" && leaseExpired(fields[0], now) {
+			continue
+		}
+		if strings.Contains(fields[2], ":") {
+			leases = append(leases, dhcpLease{duid: fields[4], addr: fields[2]})
+		} else {
+			leases = append(leases, dhcpLease{mac: fields[1], addr: fields[2]})
+		}
+	}
+	return leases, scanner.Err()
+}
+
+// parseOdhcpdLeases 解析 odhcpd 的 leasefile 中的 DHCPv6 租约
+func parseOdhcpdLeases(r io.Reader, now time.Time) (map[string][]netip.Addr, error) {
+	return groupByDuid(readOdhcpdLeases(r, now))
+}
+
+// readOdhcpdLeases 读取 odhcpd 的 leasefile
+// DHCPv6 租约行为 "# 网卡 DUID IAID 主机名 过期时间 编号 前缀长度 地址/长度 ..."，
+// DHCPv4 租约行的 DUID 位置为 MAC 地址，IAID 位置为 "ipv4"，其他行是 hosts 格式
+// 过期时间为 Unix 时间，-1 表示永不过期，0 表示已过期，已过期的租约跳过
+func readOdhcpdLeases(r io.Reader, now time.Time) ([]dhcpLease, error) {
+	var leases []dhcpLease
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 || fields[0] != "#" || leaseExpired(fields[5], now) {
+			continue
+		}
+		for _, address := range fields[8:] {
+			if fields[3] == "ipv4" {
+				leases = append(leases, dhcpLease{mac: fields[2], addr: address})
+			} else {
+				leases = append(leases, dhcpLease{duid: fields[2], addr: address})
+			}
+		}
+	}
+	return leases, scanner.Err()
+}
+
+// leaseExpired 租约的过期时间（Unix 时间）是否已过，负数和无法解析的过期时间视为未过期
+func leaseExpired(expire string, now time.Time) bool {
+	t, err := strconv.ParseInt(expire, 10, 64)
+	return err == nil && t >= 0 && t <= now.Unix()
+}
+
+// groupByDuid 按 DUID 汇总 DHCPv6 租约的地址
+func groupByDuid(leases []dhcpLease, err error) (map[string][]netip.Addr, error) {
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]netip.Addr)
+	for _, lease := range leases {
+		if lease.duid != "" {
+			addLease(result, lease.duid, lease.addr)
+		}
+	}
+	return result, nil
+}
+
+// parseISCLeases 解析 ISC dhcpd 的 dhcpd6.leases
+// ia-na 的标识为 4 字节 IAID 加 DUID，文件按时间追加，同一地址以最后出现的状态为准
+func parseISCLeases(r io.Reader) (map[string][]netip.Addr, error) {
+	type lease struct {
+		duid   string
+		active bool
+	}
+	leases := make(map[string]lease)
+	var order []string
+	var duid, address string
+	active := true
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "ia-na ") || strings.HasPrefix(line, "ia-ta "):
+			id := iscString(strings.TrimSuffix(strings.TrimSpace(line[6:]), "{"))
+			duid = ""
+			if len(id) > 4 {
+				duid = hex.EncodeToString(id[4:])
+			}
+		case strings.HasPrefix(line, "ia-pd "):
+			duid = ""
+		case strings.HasPrefix(line, "iaaddr ") && duid != "":
+			address = strings.TrimSpace(strings.TrimSuffix(line[len("iaaddr "):], "{"))
+			active = true
+		case strings.HasPrefix(line, "binding state ") && address != "":
+			active = strings.TrimSuffix(line[len("binding state "):], ";") == "active"
+		case line == "}" && address != "":
+			if _, ok := leases[address]; !ok {
+				order = append(order, address)
+			}
+			leases[address] = lease{duid: duid, active: active}
+			address = ""
+		}
+	}
+	result := make(map[string][]netip.Addr)
+	for _, address := range order {
+		if l := leases[address]; l.active {
+			addLease(result, l.duid, address)
+		}
+	}
+	return result, scanner.Err()
+}
+
+// iscString 解析 ISC 租约文件中的标识，可以是带八进制转义的字符串，或者冒号分隔的十六进制
+func iscString(s string) []byte {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, `"`) {
+		b, _ := hex.DecodeString(strings.ReplaceAll(s, ":", ""))
+		return b
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, `"`), `"`)
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			b = append(b, s[i])
+			continue
+		}
+		if i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			n, _ := strconv.ParseUint(s[i+1:i+4], 8, 8)
+			b = append(b, byte(n))
+			i += 3
+			continue
+		}
+		b = append(b, s[i+1])
+		i++
+	}
+	return b
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
+
+// parseKeaLeases 解析 Kea memfile 的 kea-leases6.csv
+// 按表头读取 address、duid、expire、lease_type、state 列，文件按时间追加，同一地址以最后一行为准
+func parseKeaLeases(r io.Reader, now time.Time) (map[string][]netip.Addr, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"address", "duid", "expire"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("缺少 %s 列", name)
+		}
+	}
+	get := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	leases := make(map[string]string)
+	var order []string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		address := get(row, "address")
+		if _, ok := leases[address]; !ok {
+			order = append(order, address)
+		}
+		expire, _ := strconv.ParseInt(get(row, "expire"), 10, 64)
+		// lease_type 2 为前缀委派，state 非 0 为已拒绝或已回收
+		leaseType, state := get(row, "lease_type"), get(row, "state")
+		if expire <= now.Unix() || leaseType == "2" || (state != "" && state != "0") {
+			leases[address] = ""
+			continue
+		}
+		leases[address] = get(row, "duid")
+	}
+	result := make(map[string][]netip.Addr)
+	for _, address := range order {
+		if duid := leases[address]; duid != "" {
+			addLease(result, duid, address)
+		}
+	}
+	return result, nil
+}
+
+// KeaSocket 通过 Kea 的控制套接字执行 lease6-get-all 读取租约
+type KeaSocket struct {
+	Path string
+}
+
+func (k *KeaSocket) Leases(ctx context.Context) (map[string][]netip.Addr, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", k.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 连接 Kea 控制套接字失败: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write([]byte(`{"command": "lease6-get-all"}`)); err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 发送 Kea 命令失败: %w", err)
+	}
+	// Kea 发送完响应后关闭连接
+	data, err := io.ReadAll(io.LimitReader(conn, 16<<20))
+	if err != nil && len(data) == 0 {
+		return nil, fmt.Errorf("Duid Fetcher: 读取 Kea 响应失败: %w", err)
+	}
+	return parseKeaResponse(data, time.Now())
+}
+
+// parseKeaResponse 解析 lease6-get-all 的响应，result 为 3 表示没有租约
+func parseKeaResponse(data []byte, now time.Time) (map[string][]netip.Addr, error) {
+	var resp struct {
+		Result    int    `json:"result"`
+		Text      string `json:"text"`
+		Arguments struct {
+			Leases []struct {
+				Address string `json:"ip-address"`
+				Duid    string `json:"duid"`
+				Type    string `json:"type"`
+				State   int    `json:"state"`
+				Cltt    int64  `json:"cltt"`
+				Valid   int64  `json:"valid-lft"`
+			} `json:"leases"`
+		} `json:"arguments"`
+	}
+	// 部分版本返回只有一个元素的数组
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil || len(list) == 0 {
+			return nil, fmt.Errorf("Duid Fetcher: Kea 响应格式无效")
+		}
+		data = list[0]
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("Duid Fetcher: 解析 Kea 响应失败: %w", err)
+	}
+	result := make(map[string][]netip.Addr)
+	switch resp.Result {
+	case 0:
+	case 3:
+		return result, nil
+	default:
+		return nil, fmt.Errorf("Duid Fetcher: Kea 返回错误: %s", resp.Text)
+	}
+	for _, lease := range resp.Arguments.Leases {
+		if lease.Type == "IA_PD" || lease.State != 0 || (lease.Valid > 0 && lease.Cltt+lease.Valid <= now.Unix()) {
+			continue
+		}
+		addLease(result, lease.Duid, lease.Address)
+	}
+	return result, nil
+}
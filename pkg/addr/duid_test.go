@@ -0,0 +1,189 @@
+package addr
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDuid = "000300019009d009781d"
+
+func TestParseLeaseSources(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		parse func(string) (map[string][]netip.Addr, error)
+		data  string
+		want  []string
+	}{
+		{
+			name:  "ubus all devices",
+			parse: func(s string) (map[string][]netip.Addr, error) { return parseUbusLeases([]byte(s)) },
+			data: `{"device":{"br-lan":{"leases":[{"duid":"000300019009d009781d","ipv6-addr":[{"address":"2001:db8::10"}]}]},
+				"br-guest":{"leases":[{"duid":"000300019009D009781D","ipv6-addr":[{"address":"2001:db8:1::10"}]}]}}}`,
+			want: []string{"2001:db8:1::10", "2001:db8::10"},
+		},
+		{
+			name: "dnsmasq",
+			parse: func(s string) (map[string][]netip.Addr, error) {
+				return parseDnsmasqLeases(strings.NewReader(s), now)
+			},
+			data: "1700000000 00:11:22:33:44:55 192.168.1.10 host1 01:00:11:22:33:44:55\n" +
+				"duid 00:01:00:01:2a:2b:2c:2d:00:11:22:33:44:55\n" +
+				"1700003600 1234567 2001:db8::10 nas 00:03:00:01:90:09:d0:09:78:1d\n" +
+				"1699990000 1234567 2001:db8::11 nas 00:03:00:01:90:09:d0:09:78:1d\n" +
+				"0 1234567 2001:db8::12 nas 00:03:00:01:90:09:d0:09:78:1d\n",
+			want: []string{"2001:db8::10", "2001:db8::12"},
+		},
+		{
+			name: "odhcpd",
+			parse: func(s string) (map[string][]netip.Addr, error) {
+				return parseOdhcpdLeases(strings.NewReader(s), now)
+			},
+			data: "# br-lan 000300019009d009781d 1234abcd nas 1700003600 5 128 2001:db8::10/128 fd00::10/128\n" +
+				"# br-lan 000300019009d009781d 1234abcd nas 1699990000 7 128 2001:db8::11/128\n" +
+				"# br-lan 000300019009d009781d 1234abcd nas 0 8 128 2001:db8::12/128\n" +
+				"2001:db8::10\tnas\n" +
+				"# br-guest 00010001aabbccdd 1 - 1700003600 6 128 2001:db8:1::20/128\n",
+			want: []string{"2001:db8::10", "fd00::10"},
+		},
+		{
+			name: "isc",
+			parse: func(s string) (map[string][]netip.Addr, error) {
+				return parseISCLeases(strings.NewReader(s))
+			},
+			data: `ia-na "\001\000\000\000\000\003\000\001\220\011\320\011x\035" {
+  cltt 4 2024/01/01 00:00:00;
+  iaaddr 2001:db8::10 {
+    binding state active;
+    preferred-life 3600;
+  }
+}
+ia-na "\001\000\000\000\000\003\000\001\220\011\320\011x\035" {
+  iaaddr 2001:db8::11 {
+    binding state expired;
+  }
+}
+ia-pd "\001\000\000\000\000\003\000\001\220\011\320\011x\035" {
+  iaprefix 2001:db8:ff00::/56 {
+    binding state active;
+  }
+}
+ia-na 01:00:00:00:00:03:00:01:90:09:d0:09:78:1d {
+  iaaddr 2001:db8::12 {
+    binding state active;
+  }
+}
+`,
+			want: []string{"2001:db8::10", "2001:db8::12"},
+		},
+		{
+			name: "kea csv",
+			parse: func(s string) (map[string][]netip.Addr, error) {
+				return parseKeaLeases(strings.NewReader(s), now)
+			},
+			data: "address,duid,valid_lifetime,expire,subnet_id,pref_lifetime,lease_type,iaid,prefix_len,fqdn_fwd,fqdn_rev,hostname,hwaddr,state\n" +
+				"2001:db8::10,00:03:00:01:90:09:d0:09:78:1d,7200,2000000000,1,3600,0,1,128,0,0,nas,,0\n" +
+				"2001:db8::11,00:03:00:01:90:09:d0:09:78:1d,7200,1000,1,3600,0,1,128,0,0,nas,,0\n" +
+				"2001:db8:ff00::,00:03:00:01:90:09:d0:09:78:1d,7200,2000000000,1,3600,2,1,56,0,0,nas,,0\n" +
+				"2001:db8::12,00:03:00:01:90:09:d0:09:78:1d,7200,2000000000,1,3600,0,1,128,0,0,nas,,0\n" +
+				"2001:db8::12,00:03:00:01:90:09:d0:09:78:1d,0,0,1,0,0,1,128,0,0,nas,,0\n",
+			want: []string{"2001:db8::10"},
+		},
+		{
+			name: "kea response",
+			parse: func(s string) (map[string][]netip.Addr, error) {
+				return parseKeaResponse([]byte(s), now)
+			},
+			data: `[{"result":0,"arguments":{"leases":[
+				{"ip-address":"2001:db8::10","duid":"00:03:00:01:90:09:d0:09:78:1d","type":"IA_NA","state":0,"cltt":1700000000,"valid-lft":3600},
+				{"ip-address":"2001:db8::11","duid":"00:03:00:01:90:09:d0:09:78:1d","type":"IA_NA","state":0,"cltt":1600000000,"valid-lft":3600},
+				{"ip-address":"2001:db8:ff00::","duid":"00:03:00:01:90:09:d0:09:78:1d","type":"IA_PD","state":0,"cltt":1700000000,"valid-lft":3600}]}}]`,
+			want: []string{"2001:db8::10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leases, err := tt.parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ip := range leases[testDuid] {
+				got = append(got, ip.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("leases[%s] = %v, want %v", testDuid, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDuid(t *testing.T) {
+	d, err := NewDuid("00:03:00:01:90:09:D0:09:78:1D@dnsmasq:/tmp/test.leases, keactl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Duid != testDuid || len(d.Sources) != 2 {
+		t.Fatalf("NewDuid() = %#v", d)
+	}
+	if file, ok := d.Sources[0].(*LeaseFile); !ok || file.Path != "/tmp/test.leases" {
+		t.Fatalf("source = %#v", d.Sources[0])
+	}
+	for _, value := range []string{"", "not-hex", testDuid + "@dhcpcd", testDuid + "@ubus:/tmp/x"} {
+		if _, err := NewDuid(value); err == nil {
+			t.Fatalf("NewDuid(%q) returned no error", value)
+		}
+	}
+}
+
+func TestDuidFetchMergesSources(t *testing.T) {
+	dir := t.TempDir()
+	dnsmasq := filepath.Join(dir, "dnsmasq.leases")
+	if err := os.WriteFile(dnsmasq, []byte("0 1 2001:db8::10 nas 00:03:00:01:90:09:d0:09:78:1d\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	socketDir, err := os.MkdirTemp("", "kea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(socketDir)
+	socket := filepath.Join(socketDir, "ctrl")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix socket unavailable: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 256)
+		n, _ := conn.Read(buf)
+		if !strings.Contains(string(buf[:n]), "lease6-get-all") {
+			return
+		}
+		conn.Write([]byte(`{"result":0,"arguments":{"leases":[{"ip-address":"2001:db8:1::10","duid":"000300019009d009781d","type":"IA_NA","state":0}]}}`))
+	}()
+
+	d, err := NewDuid(testDuid + "@dnsmasq:" + dnsmasq + ",keactl:" + socket + ",isc:" + filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := d.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("2001:db8::10"), netip.MustParseAddr("2001:db8:1::10")}
+	if !slices.Equal(ips, want) {
+		t.Fatalf("Fetch() = %v, want %v", ips, want)
+	}
+}
@@ -0,0 +1,225 @@
+package addr
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Extractor 从 URL 响应或命令输出中提取IP地址
+
+//规则1，空值扫描全部内容，提取所有像IP地址的字符串
+//规则2，json@路径，解析 JSON 并读取路径指向的字段，如 json@data.ip、json@$.ips[0]，* 表示数组或对象的所有元素
+//规则3，regex@正则表达式，有捕获组时使用第一个捕获组，否则使用整个匹配
+//规则4，line@n 或 line@n@m，选择第n行，指定m时再按空白分隔选择第m个字段，n、m从1开始计数
+
+// Extractor 接口定义了一个Extract方法，用于从文本中读取IP地址
+type Extractor interface {
+	Extract(data []byte) ([]netip.Addr, error)
+}
+
+// NewExtractor 根据规则创建提取器，规则为空时返回 nil，表示扫描全部内容
+func NewExtractor(rule string) (Extractor, error) {
+	if rule == "" {
+		return nil, nil
+	}
+	kind, value, _ := strings.Cut(rule, "@")
+	switch kind {
+	case "json":
+		return NewJSONPath(value)
+	case "regex":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("正则表达式无效: %w", err)
+		}
+		return &Regex{Regexp: re}, nil
+	case "line":
+		lineStr, fieldStr, hasField := strings.Cut(value, "@")
+		line, err := strconv.Atoi(lineStr)
+		if err != nil || line <= 0 {
+			return nil, fmt.Errorf("行号无效: %q", lineStr)
+		}
+		field := 0
+		if hasField {
+			if field, err = strconv.Atoi(fieldStr); err != nil || field <= 0 {
+				return nil, fmt.Errorf("字段序号无效: %q", fieldStr)
+			}
+		}
+		return &Line{Line: line, Field: field}, nil
+	default:
+		return nil, fmt.Errorf("不支持的提取规则: %s，请使用 json@、regex@ 或 line@", kind)
+	}
+}
+
+// extract 使用提取器读取IP地址，提取器为 nil 时扫描全部内容
+func extract(e Extractor, data []byte) ([]netip.Addr, error) {
+	if e == nil {
+		return extractFromString(string(data))
+	}
+	return e.Extract(data)
+}
+
+// parseExtracted 解析提取到的字段，允许带有方括号、区域或前缀长度，如 [2001:db8::1]、192.168.1.2/24
+func parseExtracted(s string) (netip.Addr, error) {
+	s = strings.Trim(strings.TrimSpace(s), "[]")
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr.WithZone(""), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%q 不是有效的IP地址", s)
+	}
+	return prefix.Addr(), nil
+}
+
+// JSONPath 提取器，读取 JSON 中指定字段的IP地址
+// 字段值可以是字符串，或者字符串数组
+type JSONPath struct {
+	Path string
+	// steps 路径的每一级，对象的键或者数组下标，"*" 表示所有元素
+	steps []string
+}
+
+// NewJSONPath 解析 "data.ip"、"$.ips[0]"、"items[*].ip" 格式的路径
+func NewJSONPath(path string) (*JSONPath, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var steps []string
+	for _, part := range strings.Split(trimmed, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			steps = append(steps, key)
+		}
+		if rest == "" {
+			continue
+		}
+		if !strings.HasSuffix(rest, "]") {
+			return nil, fmt.Errorf("JSON 路径 %q 缺少 ]", path)
+		}
+		for _, index := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+			if _, err := strconv.Atoi(index); err != nil && index != "*" {
+				return nil, fmt.Errorf("JSON 路径 %q 的数组下标无效: %s", path, index)
+			}
+			steps = append(steps, "["+index)
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("JSON 路径不能为空")
+	}
+	return &JSONPath{Path: path, steps: steps}, nil
+}
+
+func (j *JSONPath) Extract(data []byte) ([]netip.Addr, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("JSONPath Extractor: 内容不是有效的 JSON: %w", err)
+	}
+	values := []any{root}
+	for _, step := range j.steps {
+		var next []any
+		for _, value := range values {
+			next = append(next, jsonStep(value, step)...)
+		}
+		values = next
+	}
+
+	var ips []netip.Addr
+	for _, value := range values {
+		items, ok := value.([]any)
+		if !ok {
+			items = []any{value}
+		}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				continue
+			}
+			if addr, err := parseExtracted(s); err == nil {
+				ips = append(ips, addr)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("JSONPath Extractor: 字段 %s 中没有有效的IP地址", j.Path)
+	}
+	return ips, nil
+}
+
+// jsonStep 读取一级路径，字段不存在时返回空
+func jsonStep(value any, step string) []any {
+	index, isIndex := strings.CutPrefix(step, "[")
+	switch v := value.(type) {
+	case map[string]any:
+		if step == "*" || index == "*" {
+			// 按键排序，保证多次获取的顺序一致
+			values := make([]any, 0, len(v))
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				values = append(values, v[key])
+			}
+			return values
+		}
+		if item, ok := v[step]; ok && !isIndex {
+			return []any{item}
+		}
+	case []any:
+		if index == "*" || step == "*" {
+			return v
+		}
+		if i, err := strconv.Atoi(index); err == nil && isIndex && i >= 0 && i < len(v) {
+			return []any{v[i]}
+		}
+	}
+	return nil
+}
+
+// Regex 提取器，读取正则表达式匹配的IP地址
+type Regex struct {
+	Regexp *regexp.Regexp
+}
+
+func (r *Regex) Extract(data []byte) ([]netip.Addr, error) {
+	var ips []netip.Addr
+	for _, match := range r.Regexp.FindAllSubmatch(data, -1) {
+		text := match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+		if addr, err := parseExtracted(string(text)); err == nil {
+			ips = append(ips, addr)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("Regex Extractor: %s 没有匹配到有效的IP地址", r.Regexp)
+	}
+	return ips, nil
+}
+
+// Line 提取器，读取指定行或者指定行的第m个字段
+type Line struct {
+	Line  int // 第n行，n从1开始计数
+	Field int // 第m个字段，m从1开始计数，为0时使用整行
+}
+
+func (l *Line) Extract(data []byte) ([]netip.Addr, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if l.Line > len(lines) {
+		return nil, fmt.Errorf("Line Extractor: 内容只有 %d 行", len(lines))
+	}
+	text := lines[l.Line-1]
+	if l.Field > 0 {
+		fields := strings.Fields(text)
+		if l.Field > len(fields) {
+			return nil, fmt.Errorf("Line Extractor: 第 %d 行只有 %d 个字段", l.Line, len(fields))
+		}
+		text = fields[l.Field-1]
+	}
+	addr, err := parseExtracted(text)
+	if err != nil {
+		return nil, fmt.Errorf("Line Extractor: %w", err)
+	}
+	return []netip.Addr{addr}, nil
+}
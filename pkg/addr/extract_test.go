@@ -0,0 +1,64 @@
+package addr
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestNewExtractor(t *testing.T) {
+	const body = `{"headers":{"X-Forwarded-For":"10.0.0.8"},"origin":"203.0.113.7","ips":["2001:db8::1","198.51.100.1"]}`
+	tests := []struct {
+		rule string
+		data string
+		want string
+	}{
+		{rule: "json@origin", data: body, want: "203.0.113.7"},
+		{rule: "json@$.ips[0]", data: body, want: "2001:db8::1"},
+		{rule: "json@ips", data: body, want: "2001:db8::1 198.51.100.1"},
+		{rule: "json@headers.*", data: body, want: "10.0.0.8"},
+		{rule: `regex@wan_ip=(\S+)`, data: "lan_ip=192.168.1.1\nwan_ip=203.0.113.7\n", want: "203.0.113.7"},
+		{rule: "line@2@4", data: "1: lo inet 127.0.0.1/8\n2: eth0 inet 203.0.113.7/24 brd 203.0.113.255\n", want: "203.0.113.7"},
+		{rule: "line@1", data: "[2001:db8::2]\r\n", want: "2001:db8::2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			extractor, err := NewExtractor(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addrs, err := extractor.Extract([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, addr := range addrs {
+				got = append(got, addr.String())
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Fatalf("Extract() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, rule := range []string{"xpath@//ip", "regex@(", "line@0", "json@ips[x]"} {
+		if _, err := NewExtractor(rule); err == nil {
+			t.Fatalf("NewExtractor(%q) accepted an invalid rule", rule)
+		}
+	}
+}
+
+func TestURLFetchUsesExtractor(t *testing.T) {
+	fetcher := NewUrl("https://example.com")
+	fetcher.Extractor, _ = NewExtractor("json@origin")
+	fetcher.client.Transport = roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		body := `{"headers":{"X-Real-Ip":"198.51.100.9"},"origin":"203.0.113.7"}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}, nil
+	})
+	got, err := fetcher.Fetch(context.Background())
+	if err != nil || len(got) != 1 || got[0].String() != "203.0.113.7" {
+		t.Fatalf("Fetch() = %v, %v, want [203.0.113.7]", got, err)
+	}
+}
@@ -47,7 +47,6 @@ func IsPublic(addr netip.Addr) bool {
 	if !addr.IsGlobalUnicast() {
 		return false
 	}
-	var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
 	// IPv4 CGNAT
 	if addr.Is4() && cgnatPrefix.Contains(addr) {
 		return false
@@ -106,28 +105,15 @@ func SpliceIPv6(addr netip.Addr, suffix string) (netip.Addr, error) {
 		return netip.Addr{}, fmt.Errorf("SpliceIPv6: IP地址不是IPv6")
 	}
 
-	// 解析后缀地址（例如将 "::1"、“::9209:d0ff:fe09:781d“ 解析为标准的 netip.Addr）
-	suffixAddr, err := netip.ParseAddr(suffix)
+	// 解析后缀地址（例如将 "::1"、“::9209:d0ff:fe09:781d“ 解析为后64位）
+	suffixBytes, err := parseIPv6Suffix(suffix)
 	if err != nil {
-		// 移除开头可能存在的任意多个冒号（兼容 ":" 或 "::"）
-		cleanSuffix := strings.TrimLeft(suffix, ":")
-
-		// 统一在前面加上标准的双冒号 "::" 重新解析
-		var retryErr error
-		suffixAddr, retryErr = netip.ParseAddr("::" + cleanSuffix)
-		if retryErr != nil {
-			return netip.Addr{}, fmt.Errorf("SpliceIPv6: 后缀格式非法: %w", err)
-		}
+		return netip.Addr{}, fmt.Errorf("SpliceIPv6: %w", err)
 	}
 
-	//  提取两者的字节数组
-	ipBytes := addr.As16()           // 原始 IP 的 16 字节
-	suffixBytes := suffixAddr.As16() // 后缀 IP 的 16 字节
-
-	// 组合：前 8 字节用原始前缀，后 8 字节用后缀的后 8 字节
-	var finalBytes [16]byte
-	copy(finalBytes[0:8], ipBytes[0:8])       // 复制前 64 位前缀
-	copy(finalBytes[8:16], suffixBytes[8:16]) // 复制后 64 位后缀
+	// 组合：前 8 字节用原始前缀，后 8 字节用后缀
+	finalBytes := addr.As16()
+	copy(finalBytes[8:16], suffixBytes[:])
 
 	// 重新生成 Addr 对象（带上原始的 Zone，如果有的话）
 	return netip.AddrFrom16(finalBytes).WithZone(addr.Zone()), nil
@@ -0,0 +1,22 @@
+//go:build linux
+
+package addr
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+)
+
+// routeFile IPv4 路由表
+const routeFile = "/proc/net/route"
+
+// defaultGateway 从路由表中读取 IPv4 默认网关
+func defaultGateway() (netip.Addr, error) {
+	file, err := os.Open(routeFile)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("读取路由表失败: %w", err)
+	}
+	defer file.Close()
+	return parseDefaultGateway(file)
+}
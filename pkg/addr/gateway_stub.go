@@ -0,0 +1,13 @@
+//go:build !linux
+
+package addr
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// defaultGateway 在非 Linux 系统下直接返回错误
+func defaultGateway() (netip.Addr, error) {
+	return netip.Addr{}, fmt.Errorf("自动获取默认网关仅支持 Linux 系统，请在协议后使用 @ 指定网关地址")
+}
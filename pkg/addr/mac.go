@@ -0,0 +1,267 @@
+package addr
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 按 MAC 地址查找局域网主机的 IPv4、IPv6 地址，适用于使用 SLAAC、没有 DHCPv6 租约的打印机、摄像头等设备
+// getValue 格式为 MAC 或 MAC@来源，多个来源使用英文逗号分隔，依次读取并合并结果：
+//   neigh           内核邻居表（ARP、NDP），仅 Linux
+//   dnsmasq[:文件]   dnsmasq 租约文件，DHCPv6 租约按 DUID-LL、DUID-LLT 中的 MAC 地址匹配
+//   odhcpd[:文件]    odhcpd 的 leasefile
+//   eui64[:网卡]     本机网卡的 IPv6 /64 前缀拼接 MAC 地址生成的 EUI-64 接口标识，不填写网卡时使用所有网卡
+// 不填写来源时使用邻居表和系统中存在的 dnsmasq、odhcpd 租约文件
+
+// macSourceOrder 支持的来源
+var macSourceOrder = []string{"neigh", "dnsmasq", "odhcpd", "eui64"}
+
+// MacSource 按 MAC 地址查找IP地址的来源
+type MacSource interface {
+	Lookup(ctx context.Context, mac net.HardwareAddr) ([]netip.Addr, error)
+}
+
+// Mac 按 MAC 地址获取局域网主机的IP地址
+type Mac struct {
+	MAC net.HardwareAddr
+	// Sources 查找来源，为空时自动检测
+	Sources []MacSource
+}
+
+// NewMac 解析 MAC 地址和查找来源
+func NewMac(value string) (*Mac, error) {
+	macStr, sourcesStr, _ := strings.Cut(value, "@")
+	mac, err := ParseMAC(macStr)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mac{MAC: mac}
+	for _, spec := range strings.Split(sourcesStr, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		source, err := NewMacSource(spec)
+		if err != nil {
+			return nil, err
+		}
+		m.Sources = append(m.Sources, source)
+	}
+	return m, nil
+}
+
+// ParseMAC 解析 48 位 MAC 地址，支持冒号、短横线、点分隔，以及不带分隔符的十六进制
+func ParseMAC(s string) (net.HardwareAddr, error) {
+	s = strings.TrimSpace(s)
+	mac, err := net.ParseMAC(s)
+	if err != nil && len(s) == 12 {
+		mac, err = hex.DecodeString(s)
+	}
+	if err != nil || len(mac) != 6 {
+		return nil, fmt.Errorf("Mac Fetcher: MAC 地址无效: %q", s)
+	}
+	return mac, nil
+}
+
+// NewMacSource 根据 "类型[:路径]" 创建查找来源
+func NewMacSource(spec string) (MacSource, error) {
+	kind, path, _ := strings.Cut(spec, ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	path = strings.TrimSpace(path)
+	switch kind {
+	case "neigh":
+		if path != "" {
+			return nil, fmt.Errorf("Mac Fetcher: neigh 来源不需要路径")
+		}
+		return &Neigh{}, nil
+	case "dnsmasq", "odhcpd":
+		if path == "" {
+			path = defaultLeasePath(kind)
+		}
+		read := readDnsmasqLeases
+		if kind == "odhcpd" {
+			read = readOdhcpdLeases
+		}
+		return &MacLeases{Path: path, read: read}, nil
+	case "eui64":
+		return &EUI64Prefix{Iface: path}, nil
+	default:
+		return nil, fmt.Errorf("Mac Fetcher: 不支持的来源: %s，请使用 %s", kind, strings.Join(macSourceOrder, "、"))
+	}
+}
+
+// detectMacSources 返回邻居表和系统中存在的租约文件
+func detectMacSources() []MacSource {
+	sources := []MacSource{&Neigh{}}
+	for _, kind := range []string{"dnsmasq", "odhcpd"} {
+		for _, path := range leaseDefaults[kind] {
+			if _, err := os.Stat(path); err == nil {
+				source, _ := NewMacSource(kind + ":" + path)
+				sources = append(sources, source)
+				break
+			}
+		}
+	}
+	return sources
+}
+
+// Fetch 从所有来源查找 MAC 地址对应的IP地址，部分来源失败时使用其他来源的结果
+func (m *Mac) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	defer cancel()
+	sources := m.Sources
+	if len(sources) == 0 {
+		sources = detectMacSources()
+	}
+
+	var ips []netip.Addr
+	var errs []error
+	for _, source := range sources {
+		found, err := source.Lookup(ctx, m.MAC)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, ip := range found {
+			if !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, fmt.Errorf("Mac Fetcher: MAC %s 没有IP地址", m.MAC)
+	}
+	return ips, nil
+}
+
+// Neighbor 内核邻居表中的一条记录
+type Neighbor struct {
+	Addr  netip.Addr
+	MAC   net.HardwareAddr
+	Iface string
+}
+
+// Neigh 从内核邻居表（IPv4 ARP、IPv6 NDP）查找，只包含最近通信过的主机
+type Neigh struct{}
+
+func (n *Neigh) Lookup(ctx context.Context, mac net.HardwareAddr) ([]netip.Addr, error) {
+	neighbors, err := Neighbors()
+	if err != nil {
+		return nil, fmt.Errorf("Mac Fetcher: %w", err)
+	}
+	var ips []netip.Addr
+	for _, neighbor := range neighbors {
+		if bytes.Equal(neighbor.MAC, mac) && !slices.Contains(ips, neighbor.Addr) {
+			ips = append(ips, neighbor.Addr)
+		}
+	}
+	return ips, nil
+}
+
+// MacLeases 从 DHCP 租约文件查找
+type MacLeases struct {
+	Path string
+	read func(r io.Reader, now time.Time) ([]dhcpLease, error)
+}
+
+func (l *MacLeases) Lookup(ctx context.Context, mac net.HardwareAddr) ([]netip.Addr, error) {
+	f, err := os.Open(l.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Mac Fetcher: 读取租约文件失败: %w", err)
+	}
+	defer f.Close()
+	leases, err := l.read(f, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("Mac Fetcher: 解析租约文件 %s 失败: %w", l.Path, err)
+	}
+	return leasesByMAC(leases, mac), nil
+}
+
+// leasesByMAC 返回 MAC 地址的租约地址，DHCPv6 租约使用 DUID 中的 MAC 地址
+func leasesByMAC(leases []dhcpLease, mac net.HardwareAddr) []netip.Addr {
+	var ips []netip.Addr
+	for _, lease := range leases {
+		leaseMAC, err := ParseMAC(lease.mac)
+		if err != nil {
+			leaseMAC = macFromDuid(lease.duid)
+		}
+		if !bytes.Equal(leaseMAC, mac) {
+			continue
+		}
+		if ip, err := parseExtracted(lease.addr); err == nil && !slices.Contains(ips, ip) {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// macFromDuid 读取 DUID-LLT（类型1）、DUID-LL（类型3）中的以太网 MAC 地址，其他类型返回 nil
+func macFromDuid(duid string) net.HardwareAddr {
+	normalized, err := NormalizeDuid(duid)
+	if err != nil {
+		return nil
+	}
+	b, _ := hex.DecodeString(normalized)
+	switch {
+	case len(b) == 14 && b[0] == 0 && b[1] == 1 && b[2] == 0 && b[3] == 1:
+		return b[8:14]
+	case len(b) == 10 && b[0] == 0 && b[1] == 3 && b[2] == 0 && b[3] == 1:
+		return b[4:10]
+	default:
+		return nil
+	}
+}
+
+// EUI64Prefix 使用本机网卡的 IPv6 前缀拼接 MAC 地址生成的 EUI-64 接口标识
+// 只适用于没有开启隐私扩展和稳定隐私地址（RFC 7217）的设备
+type EUI64Prefix struct {
+	// Iface 网卡名，为空时使用所有网卡
+	Iface string
+}
+
+func (e *EUI64Prefix) Lookup(ctx context.Context, mac net.HardwareAddr) ([]netip.Addr, error) {
+	iid, err := EUI64(mac)
+	if err != nil {
+		return nil, fmt.Errorf("Mac Fetcher: %w", err)
+	}
+	nics, err := GetAllNic()
+	if err != nil {
+		return nil, fmt.Errorf("Mac Fetcher: %w", err)
+	}
+	var ips []netip.Addr
+	for name, addrs := range nics {
+		if e.Iface != "" && name != e.Iface {
+			continue
+		}
+		for _, addr := range addrs {
+			if !addr.Is6() || !addr.IsGlobalUnicast() || addr.Is4In6() {
+				continue
+			}
+			ip, err := Host{IID: iid}.Addr(addr, MaxPrefixLength)
+			if err == nil && !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 && e.Iface != "" {
+		return nil, fmt.Errorf("Mac Fetcher: 网卡 %s 没有 IPv6 前缀", e.Iface)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("Mac Fetcher: 本机网卡没有 IPv6 前缀")
+	}
+	slices.SortFunc(ips, netip.Addr.Compare)
+	return ips, nil
+}
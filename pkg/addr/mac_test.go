@@ -0,0 +1,107 @@
+package addr
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewMac(t *testing.T) {
+	tests := []struct {
+		value   string
+		sources int
+		wantErr string
+	}{
+		{value: "00:11:22:33:44:55"},
+		{value: "00-11-22-33-44-55@neigh,eui64:br-lan", sources: 2},
+		{value: "001122334455@dnsmasq:/tmp/dhcp.leases,odhcpd", sources: 2},
+		{value: "00:11:22:33:44", wantErr: "MAC 地址无效"},
+		{value: "00:11:22:33:44:55:66:77", wantErr: "MAC 地址无效"},
+		{value: "00:11:22:33:44:55@arp", wantErr: "不支持的来源"},
+		{value: "00:11:22:33:44:55@neigh:eth0", wantErr: "不需要路径"},
+	}
+	for _, tt := range tests {
+		m, err := NewMac(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewMac(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || m.MAC.String() != "00:11:22:33:44:55" || len(m.Sources) != tt.sources {
+			t.Fatalf("NewMac(%q) = %+v, %v", tt.value, m, err)
+		}
+	}
+}
+
+func TestLeasesByMAC(t *testing.T) {
+	now := time.Unix(1690000000, 0)
+	tests := []struct {
+		name string
+		read func(string) ([]dhcpLease, error)
+		data string
+		want []string
+	}{
+		{
+			name: "dnsmasq",
+			read: func(s string) ([]dhcpLease, error) { return readDnsmasqLeases(strings.NewReader(s), now) },
+			data: "1700000000 00:11:22:33:44:55 192.168.1.10 printer 01:00:11:22:33:44:55\n" +
+				"1700000000 00:11:22:33:44:66 192.168.1.11 camera *\n" +
+				"duid 00:01:00:01:2a:2b:2c:2d:00:11:22:33:44:55\n" +
+				"1700003600 1234567 2001:db8::10 printer 00:03:00:01:00:11:22:33:44:55\n" +
+				"1700003600 1234568 2001:db8::11 nas 00:01:00:01:2a:2b:2c:2d:00:11:22:33:44:55\n" +
+				"1700003600 1234569 2001:db8::12 other 00:03:00:01:90:09:d0:09:78:1d\n",
+			want: []string{"192.168.1.10", "2001:db8::10", "2001:db8::11"},
+		},
+		{
+			name: "odhcpd",
+			read: func(s string) ([]dhcpLease, error) { return readOdhcpdLeases(strings.NewReader(s), now) },
+			data: "# br-lan 001122334455 ipv4 printer 1700000000 1 32 192.168.1.10/32\n" +
+				"# br-lan 00030001001122334455 1 printer 1700003600 2 128 2001:db8::10/128\n" +
+				"# br-lan 000300019009d009781d 1 other 1700003600 3 128 2001:db8::12/128\n",
+			want: []string{"192.168.1.10", "2001:db8::10"},
+		},
+	}
+	mac, _ := ParseMAC("00:11:22:33:44:55")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leases, err := tt.read(tt.data)
+			if err != nil {
+				t.Fatalf("read leases error = %v", err)
+			}
+			var got []string
+			for _, ip := range leasesByMAC(leases, mac) {
+				got = append(got, ip.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("leasesByMAC() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMacFetchFromLeaseFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dhcp.leases")
+	data := "0 00:11:22:33:44:55 192.168.1.10 printer *\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewMac("00:11:22:33:44:55@dnsmasq:" + path + ",odhcpd:" + filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := m.Fetch(context.Background())
+	if err != nil || !slices.Equal(ips, []netip.Addr{netip.MustParseAddr("192.168.1.10")}) {
+		t.Fatalf("Fetch() = %v, %v", ips, err)
+	}
+
+	m, _ = NewMac("00:11:22:33:44:66@dnsmasq:" + path)
+	if _, err := m.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "没有IP地址") {
+		t.Fatalf("Fetch() unknown MAC error = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+//go:build linux
+
+package addr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+const (
+	// sizeofNdmsg struct ndmsg 的长度
+	sizeofNdmsg = 12
+	// ndaDst、ndaLLAddr 邻居的IP地址和链路层地址属性
+	ndaDst    = 1
+	ndaLLAddr = 2
+	// nudIncomplete、nudFailed 地址解析还没有完成或者已经失败的邻居
+	nudIncomplete = 0x01
+	nudFailed     = 0x20
+)
+
+// Neighbors 通过 netlink（RTM_GETNEIGH）读取内核邻居表
+func Neighbors() ([]Neighbor, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETNEIGH, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("读取邻居表失败: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("解析邻居表失败: %w", err)
+	}
+	names := make(map[uint32]string)
+	if ifaces, err := net.Interfaces(); err == nil {
+		for _, iface := range ifaces {
+			names[uint32(iface.Index)] = iface.Name
+		}
+	}
+	return parseNeighMessages(msgs, names), nil
+}
+
+// parseNeighMessages 解析 RTM_NEWNEIGH 消息，跳过没有链路层地址和解析失败的邻居
+func parseNeighMessages(msgs []syscall.NetlinkMessage, names map[uint32]string) []Neighbor {
+	var neighbors []Neighbor
+	for _, msg := range msgs {
+		if msg.Header.Type != syscall.RTM_NEWNEIGH || len(msg.Data) < sizeofNdmsg {
+			continue
+		}
+		// struct ndmsg: family, pad1, pad2, ifindex, state, flags, type
+		state := binary.NativeEndian.Uint16(msg.Data[8:10])
+		if state&(nudIncomplete|nudFailed) != 0 {
+			continue
+		}
+		// syscall.ParseNetlinkRouteAttr 不支持邻居消息，按 rtattr 格式手动解析
+		neighbor := Neighbor{Iface: names[binary.NativeEndian.Uint32(msg.Data[4:8])]}
+		for b := msg.Data[sizeofNdmsg:]; len(b) >= syscall.SizeofRtAttr; {
+			length := int(binary.NativeEndian.Uint16(b[0:2]))
+			if length < syscall.SizeofRtAttr || length > len(b) {
+				break
+			}
+			value := b[syscall.SizeofRtAttr:length]
+			switch binary.NativeEndian.Uint16(b[2:4]) {
+			case ndaDst:
+				neighbor.Addr, _ = netip.AddrFromSlice(value)
+			case ndaLLAddr:
+				neighbor.MAC = net.HardwareAddr(append([]byte(nil), value...))
+			}
+			// 属性按 4 字节对齐
+			next := (length + syscall.RTA_ALIGNTO - 1) &^ (syscall.RTA_ALIGNTO - 1)
+			if next > len(b) {
+				break
+			}
+			b = b[next:]
+		}
+		if neighbor.Addr.IsValid() && len(neighbor.MAC) == 6 {
+			neighbor.Addr = neighbor.Addr.Unmap()
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors
+}
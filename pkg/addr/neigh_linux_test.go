@@ -0,0 +1,55 @@
+//go:build linux
+
+package addr
+
+import (
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+)
+
+func TestParseNeighMessages(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	msgs := []syscall.NetlinkMessage{
+		neighMessage(3, 0x02, netip.MustParseAddr("192.168.1.10"), mac),
+		neighMessage(3, 0x04, netip.MustParseAddr("2001:db8::10"), mac),
+		neighMessage(3, nudFailed, netip.MustParseAddr("192.168.1.11"), mac),
+		neighMessage(3, 0x02, netip.MustParseAddr("192.168.1.12"), nil),
+	}
+	neighbors := parseNeighMessages(msgs, map[uint32]string{3: "br-lan"})
+	if len(neighbors) != 2 {
+		t.Fatalf("parseNeighMessages() = %+v", neighbors)
+	}
+	if neighbors[0].Addr != netip.MustParseAddr("192.168.1.10") || neighbors[0].MAC.String() != mac.String() || neighbors[0].Iface != "br-lan" {
+		t.Fatalf("ipv4 neighbor = %+v", neighbors[0])
+	}
+	if neighbors[1].Addr != netip.MustParseAddr("2001:db8::10") {
+		t.Fatalf("ipv6 neighbor = %+v", neighbors[1])
+	}
+}
+
+// neighMessage 构造带 NDA_DST 和 NDA_LLADDR 属性的 RTM_NEWNEIGH 消息
+func neighMessage(index int32, state uint16, addr netip.Addr, mac net.HardwareAddr) syscall.NetlinkMessage {
+	data := []byte{syscall.AF_INET6, 0, 0, 0}
+	if addr.Is4() {
+		data[0] = syscall.AF_INET
+	}
+	data = binary.NativeEndian.AppendUint32(data, uint32(index))
+	data = binary.NativeEndian.AppendUint16(data, state)
+	data = append(data, 0, 1)
+	attr := func(kind uint16, value []byte) {
+		data = binary.NativeEndian.AppendUint16(data, uint16(4+len(value)))
+		data = binary.NativeEndian.AppendUint16(data, kind)
+		data = append(data, value...)
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+	}
+	attr(ndaDst, addr.AsSlice())
+	if mac != nil {
+		attr(ndaLLAddr, mac)
+	}
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: syscall.RTM_NEWNEIGH}, Data: data}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package addr
+
+import "fmt"
+
+// Neighbors 在非 Linux 系统下直接返回错误
+func Neighbors() ([]Neighbor, error) {
+	return nil, fmt.Errorf("读取邻居表仅支持 Linux 系统")
+}
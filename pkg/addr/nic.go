@@ -3,8 +3,11 @@ package addr
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/netip"
+	"sync"
+	"time"
 )
 
 // Nic 获取网卡信息
@@ -88,3 +91,83 @@ func (n *Nic) Fetch(ctx context.Context) ([]netip.Addr, error) {
 
 	return ips, nil
 }
+
+// Changed 返回一个通道，网卡地址下一次变化时关闭，系统不支持监听地址变化时返回 nil
+func (n *Nic) Changed() <-chan struct{} {
+	return defaultNicWatcher.Changed(n.Name)
+}
+
+// nicSettleDelay 收到地址事件后等待的时间，拨号、DAD 等过程会连续产生多个事件，合并后只通知一次
+const nicSettleDelay = 300 * time.Millisecond
+
+// nicWatcher 汇总系统的网卡地址变化事件，通知监听对应网卡的记录
+type nicWatcher struct {
+	once     sync.Once
+	disabled bool
+
+	mu      sync.Mutex
+	changed map[string]chan struct{}
+	pending map[string]struct{}
+	timer   *time.Timer
+}
+
+// defaultNicWatcher 全局共享的网卡事件监听，第一次使用时启动
+var defaultNicWatcher = newNicWatcher()
+
+func newNicWatcher() *nicWatcher {
+	return &nicWatcher{
+		changed: make(map[string]chan struct{}),
+		pending: make(map[string]struct{}),
+	}
+}
+
+// Changed 启动系统事件监听并返回网卡的通知通道，监听失败时返回 nil，记录只按定时器检测
+func (w *nicWatcher) Changed(name string) <-chan struct{} {
+	w.once.Do(func() {
+		if err := watchNicAddrs(w.event); err != nil {
+			w.disabled = true
+			slog.Warn("无法监听网卡地址变化，只按检测间隔定时获取", "err", err)
+		}
+	})
+	if w.disabled {
+		return nil
+	}
+	return w.subscribe(name)
+}
+
+func (w *nicWatcher) subscribe(name string) <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.changed[name]
+	if !ok {
+		ch = make(chan struct{})
+		w.changed[name] = ch
+	}
+	return ch
+}
+
+// event 记录一个网卡的地址变化，name 为空表示无法确定网卡（例如事件丢失），通知所有网卡
+func (w *nicWatcher) event(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending[name] = struct{}{}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(nicSettleDelay, w.flush)
+	} else {
+		w.timer.Reset(nicSettleDelay)
+	}
+}
+
+// flush 事件平静下来后关闭对应网卡的通知通道
+func (w *nicWatcher) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, all := w.pending[""]
+	for name, ch := range w.changed {
+		if _, ok := w.pending[name]; ok || all {
+			close(ch)
+			delete(w.changed, name)
+		}
+	}
+	clear(w.pending)
+}
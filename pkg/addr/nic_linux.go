@@ -0,0 +1,75 @@
+//go:build linux
+
+package addr
+
+import (
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"syscall"
+)
+
+// watchNicAddrs 订阅 netlink 的 IPv4、IPv6 地址变化通知（RTNLGRP_IPV4_IFADDR、RTNLGRP_IPV6_IFADDR）
+// 每个 RTM_NEWADDR、RTM_DELADDR 消息调用一次 event，参数为网卡名
+func watchNicAddrs(event func(name string)) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	// 绑定时的组掩码为 1 << (组号 - 1)
+	groups := uint32(1<<(syscall.RTNLGRP_IPV4_IFADDR-1) | 1<<(syscall.RTNLGRP_IPV6_IFADDR-1))
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, 1<<16)
+		for {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			if errors.Is(err, syscall.ENOBUFS) {
+				// 接收缓冲区溢出，丢失的事件无法确定网卡，通知所有记录
+				event("")
+				continue
+			}
+			if err != nil {
+				slog.Error("读取网卡地址事件失败，只按检测间隔定时获取", "err", err)
+				return
+			}
+			for _, name := range parseNicAddrEvents(buf[:n]) {
+				event(name)
+			}
+		}
+	}()
+	return nil
+}
+
+// parseNicAddrEvents 解析 netlink 消息，返回地址发生变化的网卡名，无法确定网卡时返回空字符串
+func parseNicAddrEvents(buf []byte) []string {
+	msgs, err := syscall.ParseNetlinkMessage(buf)
+	if err != nil {
+		return []string{""}
+	}
+	var names []string
+	for _, msg := range msgs {
+		if msg.Header.Type != syscall.RTM_NEWADDR && msg.Header.Type != syscall.RTM_DELADDR {
+			continue
+		}
+		if len(msg.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+		// struct ifaddrmsg: family, prefixlen, flags, scope, index
+		index := binary.NativeEndian.Uint32(msg.Data[4:8])
+		name := ""
+		if iface, err := net.InterfaceByIndex(int(index)); err == nil {
+			name = iface.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
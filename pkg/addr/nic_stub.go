@@ -0,0 +1,10 @@
+//go:build !linux
+
+package addr
+
+import "fmt"
+
+// watchNicAddrs 在非 Linux 系统下直接返回错误
+func watchNicAddrs(event func(name string)) error {
+	return fmt.Errorf("监听网卡地址变化仅支持 Linux 系统")
+}
@@ -0,0 +1,36 @@
+package addr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNicWatcherMergesEventsPerInterface(t *testing.T) {
+	w := newNicWatcher()
+	wan := w.subscribe("pppoe-wan")
+	lan := w.subscribe("br-lan")
+
+	w.event("pppoe-wan")
+	w.event("pppoe-wan")
+	select {
+	case <-wan:
+	case <-time.After(5 * nicSettleDelay):
+		t.Fatal("pppoe-wan was not notified")
+	}
+	select {
+	case <-lan:
+		t.Fatal("br-lan was notified for a pppoe-wan event")
+	default:
+	}
+
+	// 新的订阅得到新的通道，无法确定网卡的事件通知所有网卡
+	wan = w.subscribe("pppoe-wan")
+	w.event("")
+	for name, ch := range map[string]<-chan struct{}{"pppoe-wan": wan, "br-lan": lan} {
+		select {
+		case <-ch:
+		case <-time.After(5 * nicSettleDelay):
+			t.Fatalf("%s was not notified for an unknown interface event", name)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package addr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ubusInterfaceReg OpenWrt 逻辑接口名
+var ubusInterfaceReg = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// ubusPrefixSuffix 接口名后缀，读取接口的委派前缀而不是地址
+const ubusPrefixSuffix = "#prefix"
+
+// OpenWrt 通过 ubus call network.interface.<接口> status 读取 OpenWrt 逻辑接口（如 wan、wan6）的地址
+// getValue 格式为 接口 或 接口@ubus套接字，多个接口使用英文逗号分隔，依次读取并合并结果
+// 默认返回 ipv4-address、ipv6-address 中的地址；接口名后加 #prefix（如 wan6#prefix）时只返回 ipv6-prefix 中运营商委派前缀的网络地址
+type OpenWrt struct {
+	// Interfaces 接口名，读取委派前缀的接口带 #prefix 后缀
+	Interfaces []string
+	// Socket ubus 套接字路径，为空时使用 ubus 命令的默认路径
+	Socket string
+	// call 执行 ubus 命令，测试时替换
+	call func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// NewOpenWrt 解析接口列表和 ubus 套接字
+func NewOpenWrt(value string) (*OpenWrt, error) {
+	names, socket, _ := strings.Cut(value, "@")
+	o := &OpenWrt{Socket: strings.TrimSpace(socket), call: callUbus}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !ubusInterfaceReg.MatchString(strings.TrimSuffix(name, ubusPrefixSuffix)) {
+			return nil, fmt.Errorf("OpenWrt Fetcher: 接口名无效: %q", name)
+		}
+		o.Interfaces = append(o.Interfaces, name)
+	}
+	if len(o.Interfaces) == 0 {
+		return nil, fmt.Errorf("OpenWrt Fetcher: 请填写接口名，如 wan、wan6")
+	}
+	return o, nil
+}
+
+// callUbus 执行 ubus 命令，失败时返回标准错误输出
+func callUbus(ctx context.Context, args ...string) ([]byte, error) {
+	out, err := exec.CommandContext(ctx, "ubus", args...).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+		return nil, fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+	}
+	return out, err
+}
+
+// Fetch 读取所有接口的地址，部分接口失败时使用其他接口的结果
+func (o *OpenWrt) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, leaseTimeout)
+	defer cancel()
+
+	var ips []netip.Addr
+	var errs []error
+	for _, iface := range o.Interfaces {
+		name, prefix := strings.CutSuffix(iface, ubusPrefixSuffix)
+		var args []string
+		if o.Socket != "" {
+			args = append(args, "-s", o.Socket)
+		}
+		args = append(args, "call", "network.interface."+name, "status")
+		out, err := o.call(ctx, args...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OpenWrt Fetcher: ubus call network.interface.%s status 失败: %w", name, err))
+			continue
+		}
+		found, err := parseInterfaceStatus(out, prefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OpenWrt Fetcher: 接口 %s %w", name, err))
+			continue
+		}
+		for _, ip := range found {
+			if !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	if len(ips) == 0 {
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return nil, fmt.Errorf("OpenWrt Fetcher: 接口 %s 没有IP地址", strings.Join(o.Interfaces, "、"))
+	}
+	return ips, nil
+}
+
+// ubusAddress network.interface 状态中的一个地址或前缀
+type ubusAddress struct {
+	Address string `json:"address"`
+}
+
+// parseInterfaceStatus 解析 network.interface.<接口> status 的输出
+// prefix 为 false 时按 ipv4-address、ipv6-address 的顺序返回接口地址；
+// 为 true 时只返回 ipv6-prefix 中委派前缀的网络地址，可以配合 hosts 或 splice 规则生成主机地址。
+// 前缀的网络地址不是可以访问的主机地址，不能混在接口地址中被默认规则选中
+func parseInterfaceStatus(data []byte, prefix bool) ([]netip.Addr, error) {
+	var status struct {
+		Up          bool          `json:"up"`
+		IPv4Address []ubusAddress `json:"ipv4-address"`
+		IPv6Address []ubusAddress `json:"ipv6-address"`
+		IPv6Prefix  []ubusAddress `json:"ipv6-prefix"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("解析 ubus 输出失败: %w", err)
+	}
+	if !status.Up {
+		return nil, fmt.Errorf("未连接")
+	}
+	lists := [][]ubusAddress{status.IPv4Address, status.IPv6Address}
+	if prefix {
+		lists = [][]ubusAddress{status.IPv6Prefix}
+	}
+	var ips []netip.Addr
+	for _, list := range lists {
+		for _, entry := range list {
+			ip, err := netip.ParseAddr(entry.Address)
+			if err != nil {
+				continue
+			}
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
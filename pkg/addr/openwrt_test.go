@@ -0,0 +1,116 @@
+package addr
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testWanStatus = `{
+	"up": true,
+	"interface": "wan",
+	"l3_device": "pppoe-wan",
+	"ipv4-address": [{"address": "203.0.113.7", "mask": 32}],
+	"ipv6-address": [{"address": "2001:db8:1::2", "mask": 64}],
+	"ipv6-prefix": [{"address": "2001:db8:100::", "mask": 56, "class": "wan6", "assigned": {"lan": {"address": "2001:db8:100::", "mask": 64}}}],
+	"ipv6-prefix-assignment": [{"address": "2001:db8:100::", "mask": 64}]
+}`
+
+func TestParseInterfaceStatus(t *testing.T) {
+	ips, err := parseInterfaceStatus([]byte(testWanStatus), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("203.0.113.7"), netip.MustParseAddr("2001:db8:1::2")}
+	if !slices.Equal(ips, want) {
+		t.Fatalf("parseInterfaceStatus() = %v, want %v", ips, want)
+	}
+	ips, err = parseInterfaceStatus([]byte(testWanStatus), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []netip.Addr{netip.MustParseAddr("2001:db8:100::")}; !slices.Equal(ips, want) {
+		t.Fatalf("parseInterfaceStatus(prefix) = %v, want %v", ips, want)
+	}
+
+	if _, err := parseInterfaceStatus([]byte(`{"up": false, "ipv4-address": []}`), false); err == nil {
+		t.Fatal("interface down was not reported")
+	}
+	if _, err := parseInterfaceStatus([]byte("Command failed: Not found"), false); err == nil {
+		t.Fatal("invalid output was not reported")
+	}
+}
+
+func TestNewOpenWrt(t *testing.T) {
+	o, err := NewOpenWrt(" wan , wan6#prefix @/var/run/ubus/ubus.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(o.Interfaces, []string{"wan", "wan6#prefix"}) || o.Socket != "/var/run/ubus/ubus.sock" {
+		t.Fatalf("NewOpenWrt() = %v %q", o.Interfaces, o.Socket)
+	}
+	for _, value := range []string{"", "@/var/run/ubus.sock", "wan;reboot", "wan6#prefixes", "#prefix"} {
+		if _, err := NewOpenWrt(value); err == nil {
+			t.Errorf("NewOpenWrt(%q) error = nil", value)
+		}
+	}
+}
+
+func TestOpenWrtFetchMergesInterfaces(t *testing.T) {
+	o, err := NewOpenWrt("wan,wan6#prefix,vpn@/tmp/ubus.sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	o.call = func(_ context.Context, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[3] {
+		case "network.interface.wan":
+			return []byte(testWanStatus), nil
+		case "network.interface.wan6":
+			return []byte(`{"up": true, "ipv6-address": [{"address": "2001:db8:1::2"}], "ipv6-prefix": [{"address": "2001:db8:200::", "mask": 60}]}`), nil
+		}
+		return nil, errors.New("Command failed: Not found")
+	}
+	ips, err := o.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("203.0.113.7"), netip.MustParseAddr("2001:db8:1::2"), netip.MustParseAddr("2001:db8:200::")}
+	if !slices.Equal(ips, want) {
+		t.Fatalf("Fetch() = %v", ips)
+	}
+	if calls[0] != "-s /tmp/ubus.sock call network.interface.wan status" || len(calls) != 3 {
+		t.Fatalf("ubus calls = %q", calls)
+	}
+
+	o.Interfaces = []string{"vpn"}
+	if _, err := o.Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "network.interface.vpn") {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+}
+
+func TestOpenWrtFetchPrefixOnlyStatus(t *testing.T) {
+	const status = `{"up": true, "ipv6-address": [], "ipv6-prefix": [{"address": "2001:db8:300::", "mask": 56}]}`
+	o, err := NewOpenWrt("wan6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.call = func(context.Context, ...string) ([]byte, error) { return []byte(status), nil }
+	// 没有加 #prefix 时委派前缀不作为接口地址返回
+	if ips, err := o.Fetch(context.Background()); err == nil {
+		t.Fatalf("Fetch() = %v, want no address error", ips)
+	}
+
+	o.Interfaces = []string{"wan6#prefix"}
+	ips, err := o.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []netip.Addr{netip.MustParseAddr("2001:db8:300::")}; !slices.Equal(ips, want) {
+		t.Fatalf("Fetch() = %v, want %v", ips, want)
+	}
+}
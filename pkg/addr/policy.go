@@ -0,0 +1,116 @@
+package addr
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Policy 地址分类策略，决定获取到的哪些地址可以用于解析记录
+// 默认只接受公网地址，可以额外允许私网、ULA、CGNAT 地址，再按 CIDR 列表包含或排除
+// 回环、链路本地、组播等地址始终被过滤
+
+// IPv6 地址偏好
+const (
+	// IPv6Any 不区分稳定地址和临时地址
+	IPv6Any = ""
+	// IPv6Stable 排除隐私扩展生成的临时地址（RFC 8981）
+	IPv6Stable = "stable"
+	// IPv6Temporary 只使用临时地址
+	IPv6Temporary = "temporary"
+)
+
+var (
+	cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+	ulaPrefix   = netip.MustParsePrefix("fc00::/7")
+)
+
+// Policy 地址策略
+type Policy struct {
+	// Include 不为空时只接受这些网段内的地址
+	Include []netip.Prefix
+	// Exclude 排除这些网段内的地址，优先于 Include
+	Exclude []netip.Prefix
+	// AllowPrivate 允许 IPv4 私网地址（10.0.0.0/8、172.16.0.0/12、192.168.0.0/16）
+	AllowPrivate bool
+	// AllowULA 允许 IPv6 唯一本地地址（fc00::/7）
+	AllowULA bool
+	// AllowCGNAT 允许运营商级 NAT 地址（100.64.0.0/10）
+	AllowCGNAT bool
+	// IPv6 稳定地址和临时地址的偏好，IPv6Any、IPv6Stable 或 IPv6Temporary
+	IPv6 string
+	// Ifaces 读取地址属性时优先查找的网卡名，同一地址在多个网卡上时使用这些网卡上的属性
+	Ifaces []string
+}
+
+// ParsePrefixes 解析 CIDR 列表，单个IP地址按 /32 或 /128 处理
+func ParsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是有效的 CIDR", value)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Filter 返回按策略过滤地址的函数
+func (p Policy) Filter() Filter {
+	return p.filter(LocalAddrInfo)
+}
+
+// filter 返回按策略过滤地址的函数，lookup 读取本机地址属性，短时间内的多次过滤共用同一次读取
+func (p Policy) filter(lookup func() (AddrInfos, error)) Filter {
+	infos := newAddrInfoSnapshot(lookup)
+	return func(addr netip.Addr) bool {
+		addr = addr.Unmap()
+		if containsAddr(p.Exclude, addr) {
+			return false
+		}
+		if len(p.Include) > 0 && !containsAddr(p.Include, addr) {
+			return false
+		}
+		if !p.allowsClass(addr) {
+			return false
+		}
+		if addr.Is6() && p.IPv6 != IPv6Any {
+			return infos.isTemporary(addr, p.Ifaces) == (p.IPv6 == IPv6Temporary)
+		}
+		return true
+	}
+}
+
+// allowsClass 按地址分类判断是否接受
+func (p Policy) allowsClass(addr netip.Addr) bool {
+	switch {
+	case IsPublic(addr):
+		return true
+	case addr.Is4() && addr.IsPrivate():
+		return p.AllowPrivate
+	case ulaPrefix.Contains(addr):
+		return p.AllowULA
+	case cgnatPrefix.Contains(addr):
+		return p.AllowCGNAT
+	default:
+		return false
+	}
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package addr
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestPolicyFilter(t *testing.T) {
+	mustPrefixes := func(values ...string) []netip.Prefix {
+		prefixes, err := ParsePrefixes(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return prefixes
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		accept []string
+		reject []string
+	}{
+		{
+			name:   "default public only",
+			accept: []string{"8.8.8.8", "2001:4860::1"},
+			reject: []string{"192.168.1.2", "fd00::1", "100.64.0.1", "127.0.0.1", "fe80::1"},
+		},
+		{
+			name:   "allow intranet",
+			policy: Policy{AllowPrivate: true, AllowULA: true},
+			accept: []string{"192.168.1.2", "fd00::1", "8.8.8.8"},
+			reject: []string{"100.64.0.1", "fe80::1"},
+		},
+		{
+			name:   "allow cgnat",
+			policy: Policy{AllowCGNAT: true},
+			accept: []string{"100.64.0.1"},
+			reject: []string{"10.0.0.1"},
+		},
+		{
+			name:   "exclude before include",
+			policy: Policy{Include: mustPrefixes("2001:db8::/32", "203.0.113.7"), Exclude: mustPrefixes("2001:db8:1::/48")},
+			accept: []string{"2001:db8::1", "203.0.113.7"},
+			reject: []string{"2001:db8:1::1", "203.0.113.8", "2002:c000:204::1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.policy.Filter()
+			for _, s := range tt.accept {
+				if !filter(netip.MustParseAddr(s)) {
+					t.Errorf("filter rejected %s", s)
+				}
+			}
+			for _, s := range tt.reject {
+				if filter(netip.MustParseAddr(s)) {
+					t.Errorf("filter accepted %s", s)
+				}
+			}
+		})
+	}
+
+	if _, err := ParsePrefixes([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("ParsePrefixes() accepted an invalid prefix")
+	}
+}
+
+func TestPolicyFilterReadsAddrInfoOncePerPass(t *testing.T) {
+	temporary := netip.MustParseAddr("2001:db8::2")
+	calls := 0
+	filter := Policy{IPv6: IPv6Stable}.filter(func() (AddrInfos, error) {
+		calls++
+		return AddrInfos{{Index: 2, Addr: temporary}: {Flags: ifaFlagTemporary}}, nil
+	})
+	got := FilterAddrs([]netip.Addr{netip.MustParseAddr("2001:db8::1"), temporary, netip.MustParseAddr("2001:db8::3")}, filter)
+	if len(got) != 2 || slices.Contains(got, temporary) {
+		t.Fatalf("FilterAddrs() = %v, want stable addresses", got)
+	}
+	if calls != 1 {
+		t.Fatalf("address info read %d times, want 1", calls)
+	}
+}
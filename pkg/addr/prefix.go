@@ -0,0 +1,115 @@
+package addr
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// 前缀跟踪：一次获取委派前缀，为多个局域网主机生成地址
+// 主机地址由三部分组成：获取到的地址的前 n 位（委派前缀）、子网序号（委派前缀短于 /64 时）、接口标识（后64位）
+// 接口标识可以是静态后缀，也可以由 MAC 地址按 EUI-64 生成
+
+const (
+	// MinPrefixLength 委派前缀的最短长度
+	MinPrefixLength = 48
+	// MaxPrefixLength 委派前缀的最长长度，此时没有子网序号
+	MaxPrefixLength = 64
+)
+
+// Host 前缀跟踪中的一个主机
+type Host struct {
+	// Subnet 子网序号，位于委派前缀之后、第64位之前
+	Subnet uint64
+	// IID 接口标识，即地址的后64位
+	IID [8]byte
+}
+
+// NewHost 根据静态后缀或 MAC 地址创建主机，两者必须填写一个
+func NewHost(suffix, mac string, subnet int) (Host, error) {
+	if subnet < 0 {
+		return Host{}, fmt.Errorf("子网序号不能为负数")
+	}
+	host := Host{Subnet: uint64(subnet)}
+	switch {
+	case suffix != "" && mac != "":
+		return Host{}, fmt.Errorf("后缀和 MAC 地址只能填写一个")
+	case suffix != "":
+		iid, err := parseIPv6Suffix(suffix)
+		if err != nil {
+			return Host{}, err
+		}
+		host.IID = iid
+	case mac != "":
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return Host{}, fmt.Errorf("MAC 地址无效: %q", mac)
+		}
+		iid, err := EUI64(hw)
+		if err != nil {
+			return Host{}, err
+		}
+		host.IID = iid
+	default:
+		return Host{}, fmt.Errorf("请填写后缀或 MAC 地址")
+	}
+	return host, nil
+}
+
+// EUI64 按 RFC 4291 附录 A 由 48 位 MAC 地址生成接口标识
+func EUI64(mac net.HardwareAddr) ([8]byte, error) {
+	if len(mac) != 6 {
+		return [8]byte{}, fmt.Errorf("只支持 48 位 MAC 地址: %s", mac)
+	}
+	return [8]byte{mac[0] ^ 0x02, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}, nil
+}
+
+// CheckSubnet 检查子网序号是否能放进委派前缀和第64位之间
+func (h Host) CheckSubnet(bits int) error {
+	if bits < MinPrefixLength || bits > MaxPrefixLength {
+		return fmt.Errorf("委派前缀长度无效，请填写 %d-%d", MinPrefixLength, MaxPrefixLength)
+	}
+	if h.Subnet >= 1<<(MaxPrefixLength-bits) {
+		return fmt.Errorf("子网序号 %d 超出 /%d 委派前缀的范围（0-%d）", h.Subnet, bits, uint64(1)<<(MaxPrefixLength-bits)-1)
+	}
+	return nil
+}
+
+// Addr 取 from 的前 bits 位作为委派前缀，拼接子网序号和接口标识生成主机地址
+func (h Host) Addr(from netip.Addr, bits int) (netip.Addr, error) {
+	from = from.Unmap()
+	if !from.Is6() {
+		return netip.Addr{}, fmt.Errorf("前缀跟踪只支持 IPv6 地址")
+	}
+	if err := h.CheckSubnet(bits); err != nil {
+		return netip.Addr{}, err
+	}
+	prefix, err := from.WithZone("").Prefix(bits)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	b := prefix.Addr().As16()
+	network := binary.BigEndian.Uint64(b[:8]) | h.Subnet
+	binary.BigEndian.PutUint64(b[:8], network)
+	copy(b[8:], h.IID[:])
+	return netip.AddrFrom16(b), nil
+}
+
+// parseIPv6Suffix 解析 IPv6 后缀，返回后64位，如 "::1"、"::9209:d0ff:fe09:781d" 或 "0:0:0:1"
+func parseIPv6Suffix(suffix string) ([8]byte, error) {
+	suffixAddr, err := netip.ParseAddr(suffix)
+	if err != nil {
+		// 移除开头可能存在的任意多个冒号（兼容 ":" 或 "::"），统一在前面加上标准的双冒号重新解析
+		var retryErr error
+		suffixAddr, retryErr = netip.ParseAddr("::" + strings.TrimLeft(suffix, ":"))
+		if retryErr != nil || !suffixAddr.Is6() {
+			return [8]byte{}, fmt.Errorf("后缀格式非法: %w", err)
+		}
+	}
+	var iid [8]byte
+	b := suffixAddr.As16()
+	copy(iid[:], b[8:])
+	return iid, nil
+}
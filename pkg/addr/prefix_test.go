@@ -0,0 +1,62 @@
+package addr
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestHostAddr(t *testing.T) {
+	from := netip.MustParseAddr("2001:db8:aa00:1:9209:d0ff:fe09:781d")
+	tests := []struct {
+		name   string
+		suffix string
+		mac    string
+		subnet int
+		bits   int
+		want   string
+	}{
+		{name: "suffix", suffix: "::10", bits: 64, want: "2001:db8:aa00:1::10"},
+		{name: "suffix without colons", suffix: "0:0:0:1", bits: 64, want: "2001:db8:aa00:1::1"},
+		{name: "eui64", mac: "00:11:22:33:44:55", bits: 64, want: "2001:db8:aa00:1:211:22ff:fe33:4455"},
+		{name: "delegated subnet", suffix: "::10", bits: 56, want: "2001:db8:aa00::10"},
+		{name: "second subnet", mac: "02-11-22-33-44-55", subnet: 2, bits: 56, want: "2001:db8:aa00:2:11:22ff:fe33:4455"},
+		{name: "last subnet of /48", suffix: "::1", subnet: 0xffff, bits: 48, want: "2001:db8:aa00:ffff::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, err := NewHost(tt.suffix, tt.mac, tt.subnet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := host.Addr(from, tt.bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != netip.MustParseAddr(tt.want) {
+				t.Fatalf("Addr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostRejectsInvalidValues(t *testing.T) {
+	if _, err := NewHost("", "", 0); err == nil {
+		t.Fatal("NewHost() accepted a host without suffix or MAC")
+	}
+	if _, err := NewHost("::1", "00:11:22:33:44:55", 0); err == nil {
+		t.Fatal("NewHost() accepted both suffix and MAC")
+	}
+	if _, err := NewHost("", "00:11:22:33:44:55:66:77", 0); err == nil {
+		t.Fatal("NewHost() accepted a 64-bit MAC")
+	}
+	host, err := NewHost("::1", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.Addr(netip.MustParseAddr("2001:db8::1"), 64); err == nil {
+		t.Fatal("Addr() accepted a subnet without delegated bits")
+	}
+	if _, err := host.Addr(netip.MustParseAddr("192.0.2.1"), 56); err == nil {
+		t.Fatal("Addr() accepted an IPv4 address")
+	}
+}
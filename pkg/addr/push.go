@@ -0,0 +1,122 @@
+package addr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sync"
+)
+
+// 接收客户端通过内置 DynDNS2 接口（/nic/update）推送的 IP 地址
+// 路由器、摄像头等设备调用 Web 控制台的 /nic/update，PushHub 保存最新地址并通知对应记录立即同步
+
+// ErrNotReady 客户端还没有推送过 IP 地址
+var ErrNotReady = errors.New("尚未收到客户端推送的 IP 地址")
+
+// Notifier 可以主动通知 IP 地址变化的 Fetcher
+type Notifier interface {
+	// Changed 返回一个通道，IP 地址下一次变化时关闭
+	Changed() <-chan struct{}
+}
+
+// PushHub 保存每个客户端最近推送的 IPv4 和 IPv6 地址
+type PushHub struct {
+	mu      sync.Mutex
+	addrs   map[string]pushedAddrs
+	changed map[string]chan struct{}
+}
+
+type pushedAddrs struct {
+	v4 netip.Addr
+	v6 netip.Addr
+}
+
+// DefaultPushHub 全局共享的 PushHub，重载配置后仍保留已推送的地址
+var DefaultPushHub = NewPushHub()
+
+func NewPushHub() *PushHub {
+	return &PushHub{
+		addrs:   make(map[string]pushedAddrs),
+		changed: make(map[string]chan struct{}),
+	}
+}
+
+// Push 保存客户端推送的 IP 地址，同一版本只保留最后一个
+// 返回值：地址是否发生变化
+func (h *PushHub) Push(client string, addrs []netip.Addr) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.addrs[client]
+	current := old
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if addr.Is4() {
+			current.v4 = addr
+		} else if addr.Is6() {
+			current.v6 = addr
+		}
+	}
+	if current == old {
+		return false
+	}
+	h.addrs[client] = current
+	if ch, ok := h.changed[client]; ok {
+		close(ch)
+		delete(h.changed, client)
+	}
+	return true
+}
+
+// Addrs 获取客户端最近推送的 IP 地址
+func (h *PushHub) Addrs(client string) []netip.Addr {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pushed := h.addrs[client]
+	var addrs []netip.Addr
+	if pushed.v4.IsValid() {
+		addrs = append(addrs, pushed.v4)
+	}
+	if pushed.v6.IsValid() {
+		addrs = append(addrs, pushed.v6)
+	}
+	return addrs
+}
+
+// Changed 返回一个通道，客户端下一次推送新地址时关闭
+func (h *PushHub) Changed(client string) <-chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch, ok := h.changed[client]
+	if !ok {
+		ch = make(chan struct{})
+		h.changed[client] = ch
+	}
+	return ch
+}
+
+// Push 从 PushHub 获取客户端推送的 IP 地址
+type Push struct {
+	// Client DynDNS2 客户端用户名
+	Client string
+	hub    *PushHub
+}
+
+func NewPush(client string) *Push {
+	return &Push{Client: client, hub: DefaultPushHub}
+}
+
+func (p *Push) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	if p.Client == "" {
+		return nil, fmt.Errorf("Push Fetcher: 请提供客户端用户名")
+	}
+	addrs := p.hub.Addrs(p.Client)
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("Push Fetcher: %s: %w", p.Client, ErrNotReady)
+	}
+	return addrs, nil
+}
+
+func (p *Push) Changed() <-chan struct{} {
+	return p.hub.Changed(p.Client)
+}
@@ -0,0 +1,42 @@
+package addr
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestPushHubKeepsLatestAddressPerVersion(t *testing.T) {
+	hub := NewPushHub()
+	fetcher := &Push{Client: "camera", hub: hub}
+	if _, err := fetcher.Fetch(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Fetch() error = %v, want ErrNotReady", err)
+	}
+
+	changed := fetcher.Changed()
+	if !hub.Push("camera", []netip.Addr{netip.MustParseAddr("::ffff:1.2.3.4"), netip.MustParseAddr("2001:db8::1")}) {
+		t.Fatal("Push() reported no change for first address")
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("Changed() was not closed after push")
+	}
+	if hub.Push("camera", []netip.Addr{netip.MustParseAddr("1.2.3.4")}) {
+		t.Fatal("Push() reported change for same address")
+	}
+	hub.Push("camera", []netip.Addr{netip.MustParseAddr("5.6.7.8")})
+
+	addrs, err := fetcher.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Addr{netip.MustParseAddr("5.6.7.8"), netip.MustParseAddr("2001:db8::1")}
+	if len(addrs) != len(want) || addrs[0] != want[0] || addrs[1] != want[1] {
+		t.Fatalf("Fetch() = %v, want %v", addrs, want)
+	}
+	if other := hub.Addrs("router"); len(other) != 0 {
+		t.Fatalf("Addrs(router) = %v, want empty", other)
+	}
+}
@@ -0,0 +1,428 @@
+package addr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通过家庭路由器获取 WAN 口地址
+// 支持 UPnP IGD（SSDP 发现后调用 GetExternalIPAddress）、NAT-PMP 和 PCP 三种协议，
+// 适用于在 NAS 等局域网设备上运行，网卡只有内网地址，URL 方式又会经过代理或 VPN 的场景
+
+const (
+	routerTimeout      = 3 * time.Second
+	natpmpPort         = "5351"
+	ssdpAddr           = "239.255.255.250:1900"
+	upnpMaxBodyBytes   = 1 << 20
+	natpmpResponseSize = 12
+	pcpVersion         = 2
+	pcpOpMap           = 1
+	pcpMapRequestSize  = 60
+	pcpMapResponseSize = 60
+	// pcpLifetime 查询使用的临时映射的有效期，获取地址后立即删除
+	pcpLifetime = 30
+	// rtfGateway 路由标志 RTF_GATEWAY
+	rtfGateway = 0x2
+)
+
+// routerMethods 没有配置 getValue 时依次尝试的协议
+var routerMethods = []string{"upnp", "natpmp", "pcp"}
+
+// upnpSearchTargets SSDP 搜索的设备和服务类型
+var upnpSearchTargets = []string{
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:1",
+	"urn:schemas-upnp-org:device:InternetGatewayDevice:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+}
+
+// upnpServiceTypes 支持 GetExternalIPAddress 的服务类型
+var upnpServiceTypes = []string{
+	"urn:schemas-upnp-org:service:WANIPConnection:2",
+	"urn:schemas-upnp-org:service:WANIPConnection:1",
+	"urn:schemas-upnp-org:service:WANPPPConnection:1",
+}
+
+// Router 通过路由器获取 WAN 口地址
+type Router struct {
+	// Methods 使用的协议，格式 method[@target]，多个使用英文逗号分隔，按顺序尝试直到成功
+	// upnp 的 target 为设备描述 URL，natpmp 和 pcp 的 target 为网关地址，不填写时自动发现
+	Methods string
+	client  http.Client
+}
+
+func NewRouter(methods string) *Router {
+	return &Router{Methods: methods, client: http.Client{Timeout: routerTimeout}}
+}
+
+func (r *Router) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	methods := routerMethods
+	if strings.TrimSpace(r.Methods) != "" {
+		methods = strings.Split(r.Methods, ",")
+	}
+
+	var errs []error
+	for _, method := range methods {
+		method, target, _ := strings.Cut(strings.TrimSpace(method), "@")
+		if method == "" {
+			continue
+		}
+		var (
+			addr netip.Addr
+			err  error
+		)
+		switch strings.ToLower(method) {
+		case "upnp":
+			addr, err = r.fetchUPnP(ctx, target)
+		case "natpmp":
+			addr, err = fetchNATPMP(ctx, target)
+		case "pcp":
+			addr, err = fetchPCP(ctx, target)
+		default:
+			return nil, fmt.Errorf("Router Fetcher: 不支持的协议: %s", method)
+		}
+		if err == nil {
+			return []netip.Addr{addr}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", method, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("Router Fetcher: 没有获取到路由器 WAN 地址: %w", errors.Join(errs...))
+}
+
+// gatewayAddr 返回 NAT-PMP、PCP 服务器地址，没有指定时使用默认网关
+func gatewayAddr(target string) (string, error) {
+	if target == "" {
+		gateway, err := defaultGateway()
+		if err != nil {
+			return "", err
+		}
+		target = gateway.String()
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		target = net.JoinHostPort(strings.Trim(target, "[]"), natpmpPort)
+	}
+	return target, nil
+}
+
+// parseDefaultGateway 解析 /proc/net/route 格式的路由表，字段依次为 Iface Destination Gateway Flags ...，地址为小端序的十六进制
+func parseDefaultGateway(r io.Reader) (netip.Addr, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || fields[1] != "00000000" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&rtfGateway == 0 {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		var ip [4]byte
+		binary.BigEndian.PutUint32(ip[:], binary.LittleEndian.Uint32(raw))
+		return netip.AddrFrom4(ip), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return netip.Addr{}, fmt.Errorf("读取路由表失败: %w", err)
+	}
+	return netip.Addr{}, fmt.Errorf("没有找到默认网关，请在协议后使用 @ 指定网关地址")
+}
+
+// exchangeUDP 发送请求并等待第一个满足 accept 的应答，按 RFC 6886 的方式重传
+func exchangeUDP(ctx context.Context, server string, request func(local netip.AddrPort) []byte, accept func([]byte) bool) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, routerTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp4", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	local, _ := netip.ParseAddrPort(conn.LocalAddr().String())
+	payload := request(local)
+	buf := make([]byte, 1100)
+	for wait := 250 * time.Millisecond; ; wait *= 2 {
+		if _, err := conn.Write(payload); err != nil {
+			return nil, err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(wait))
+		for {
+			n, err := conn.Read(buf)
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("等待网关应答超时")
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if accept(buf[:n]) {
+				return bytes.Clone(buf[:n]), nil
+			}
+		}
+	}
+}
+
+// fetchNATPMP 发送 NAT-PMP 外部地址请求（RFC 6886）
+func fetchNATPMP(ctx context.Context, target string) (netip.Addr, error) {
+	server, err := gatewayAddr(target)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	response, err := exchangeUDP(ctx, server, func(netip.AddrPort) []byte { return []byte{0, 0} }, func(msg []byte) bool {
+		return len(msg) >= natpmpResponseSize && msg[0] == 0 && msg[1] == 128
+	})
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if code := binary.BigEndian.Uint16(response[2:4]); code != 0 {
+		return netip.Addr{}, fmt.Errorf("网关返回错误码: %d", code)
+	}
+	addr := netip.AddrFrom4([4]byte(response[8:12]))
+	if addr.IsUnspecified() {
+		return netip.Addr{}, fmt.Errorf("网关还没有获取到 WAN 地址")
+	}
+	return addr, nil
+}
+
+// fetchPCP 发送一个短期 MAP 请求（RFC 6887），从应答中读取分配的外部地址，然后删除映射
+func fetchPCP(ctx context.Context, target string) (netip.Addr, error) {
+	server, err := gatewayAddr(target)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := rand.Read(nonce); err != nil {
+		return netip.Addr{}, err
+	}
+	var internalPort uint16
+	accept := func(msg []byte) bool {
+		return len(msg) >= pcpMapResponseSize && msg[0] == pcpVersion && msg[1] == 0x80|pcpOpMap && bytes.Equal(msg[24:36], nonce)
+	}
+	response, err := exchangeUDP(ctx, server, func(local netip.AddrPort) []byte {
+		internalPort = local.Port()
+		return pcpMapRequest(local, nonce, pcpLifetime)
+	}, accept)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	if code := response[3]; code != 0 {
+		return netip.Addr{}, fmt.Errorf("网关返回错误码: %d", code)
+	}
+	addr := netip.AddrFrom16([16]byte(response[44:60])).Unmap()
+
+	// 删除临时映射，失败时等待映射自然过期
+	_, _ = exchangeUDP(ctx, server, func(local netip.AddrPort) []byte {
+		return pcpMapRequest(netip.AddrPortFrom(local.Addr(), internalPort), nonce, 0)
+	}, accept)
+	if !addr.IsValid() || addr.IsUnspecified() {
+		return netip.Addr{}, fmt.Errorf("网关没有返回外部地址")
+	}
+	return addr, nil
+}
+
+// pcpMapRequest 构造 UDP 协议的 MAP 请求
+func pcpMapRequest(local netip.AddrPort, nonce []byte, lifetime uint32) []byte {
+	msg := make([]byte, pcpMapRequestSize)
+	msg[0] = pcpVersion
+	msg[1] = pcpOpMap
+	binary.BigEndian.PutUint32(msg[4:8], lifetime)
+	clientIP := local.Addr().As16()
+	copy(msg[8:24], clientIP[:])
+	copy(msg[24:36], nonce)
+	msg[36] = 17
+	binary.BigEndian.PutUint16(msg[40:42], local.Port())
+	// 建议的外部地址使用 IPv4 映射的全零地址，表示由网关分配
+	unspecified := netip.IPv4Unspecified().As16()
+	copy(msg[44:60], unspecified[:])
+	return msg
+}
+
+// fetchUPnP 发现 IGD 设备并调用 GetExternalIPAddress，target 为设备描述 URL，为空时通过 SSDP 发现
+func (r *Router) fetchUPnP(ctx context.Context, target string) (netip.Addr, error) {
+	location := target
+	if location == "" {
+		var err error
+		if location, err = discoverIGD(ctx); err != nil {
+			return netip.Addr{}, err
+		}
+	}
+	controlURL, serviceType, err := r.upnpControlURL(ctx, location)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return r.getExternalIPAddress(ctx, controlURL, serviceType)
+}
+
+// discoverIGD 通过 SSDP 组播搜索 IGD 设备，返回第一个应答的设备描述地址
+func discoverIGD(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, routerTimeout)
+	defer cancel()
+	conn, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	dst, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return "", err
+	}
+	for _, st := range upnpSearchTargets {
+		request := "M-SEARCH * HTTP/1.1\r\nHOST: " + ssdpAddr + "\r\nMAN: \"ssdp:discover\"\r\nMX: 2\r\nST: " + st + "\r\n\r\n"
+		if _, err := conn.WriteTo([]byte(request), dst); err != nil {
+			return "", err
+		}
+	}
+	buf := make([]byte, 2048)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return "", fmt.Errorf("没有发现 UPnP IGD 设备")
+		}
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(buf[:n])), nil)
+		if err != nil {
+			continue
+		}
+		response.Body.Close()
+		if location := response.Header.Get("Location"); location != "" {
+			return location, nil
+		}
+	}
+}
+
+// upnpDevice 设备描述中用到的字段
+type upnpDevice struct {
+	Services []struct {
+		ServiceType string `xml:"serviceType"`
+		ControlURL  string `xml:"controlURL"`
+	} `xml:"serviceList>service"`
+	Devices []upnpDevice `xml:"deviceList>device"`
+}
+
+// findService 在设备树中按优先级查找 WAN 连接服务
+func (d upnpDevice) findService(serviceType string) (string, bool) {
+	for _, service := range d.Services {
+		if service.ServiceType == serviceType {
+			return service.ControlURL, true
+		}
+	}
+	for _, child := range d.Devices {
+		if controlURL, ok := child.findService(serviceType); ok {
+			return controlURL, true
+		}
+	}
+	return "", false
+}
+
+// upnpControlURL 读取设备描述，返回 WAN 连接服务的控制地址和服务类型
+func (r *Router) upnpControlURL(ctx context.Context, location string) (string, string, error) {
+	body, err := r.httpDo(ctx, http.MethodGet, location, nil, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("读取设备描述失败: %w", err)
+	}
+	var root struct {
+		URLBase string     `xml:"URLBase"`
+		Device  upnpDevice `xml:"device"`
+	}
+	if err := xml.Unmarshal(body, &root); err != nil {
+		return "", "", fmt.Errorf("解析设备描述失败: %w", err)
+	}
+	base, err := url.Parse(location)
+	if err != nil {
+		return "", "", err
+	}
+	if root.URLBase != "" {
+		if base, err = url.Parse(strings.TrimSpace(root.URLBase)); err != nil {
+			return "", "", err
+		}
+	}
+	for _, serviceType := range upnpServiceTypes {
+		if controlURL, ok := root.Device.findService(serviceType); ok {
+			ref, err := url.Parse(strings.TrimSpace(controlURL))
+			if err != nil {
+				return "", "", err
+			}
+			return base.ResolveReference(ref).String(), serviceType, nil
+		}
+	}
+	return "", "", fmt.Errorf("设备不支持 WANIPConnection 或 WANPPPConnection 服务")
+}
+
+// getExternalIPAddress 调用 SOAP 接口 GetExternalIPAddress
+func (r *Router) getExternalIPAddress(ctx context.Context, controlURL, serviceType string) (netip.Addr, error) {
+	request := `<?xml version="1.0"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body><u:GetExternalIPAddress xmlns:u="` + serviceType + `"></u:GetExternalIPAddress></s:Body></s:Envelope>`
+	header := http.Header{
+		"Content-Type": {`text/xml; charset="utf-8"`},
+		"SOAPAction":   {`"` + serviceType + `#GetExternalIPAddress"`},
+	}
+	body, err := r.httpDo(ctx, http.MethodPost, controlURL, header, strings.NewReader(request))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("调用 GetExternalIPAddress 失败: %w", err)
+	}
+	var envelope struct {
+		Address string `xml:"Body>GetExternalIPAddressResponse>NewExternalIPAddress"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		return netip.Addr{}, fmt.Errorf("解析 GetExternalIPAddress 应答失败: %w", err)
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(envelope.Address))
+	if err != nil || addr.IsUnspecified() {
+		return netip.Addr{}, fmt.Errorf("路由器没有返回有效的 WAN 地址: %q", envelope.Address)
+	}
+	return addr, nil
+}
+
+func (r *Router) httpDo(ctx context.Context, method, target string, header http.Header, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("HTTP 状态码: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, upnpMaxBodyBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > upnpMaxBodyBytes {
+		return nil, fmt.Errorf("应答超过 1 MiB 限制")
+	}
+	return data, nil
+}
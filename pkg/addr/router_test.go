@@ -0,0 +1,124 @@
+package addr
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouterFetchUPnP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rootDesc.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:InternetGatewayDevice:1</deviceType>
+    <deviceList><device>
+      <deviceType>urn:schemas-upnp-org:device:WANDevice:1</deviceType>
+      <deviceList><device>
+        <deviceType>urn:schemas-upnp-org:device:WANConnectionDevice:1</deviceType>
+        <serviceList><service>
+          <serviceType>urn:schemas-upnp-org:service:WANIPConnection:1</serviceType>
+          <controlURL>/ctl/IPConn</controlURL>
+        </service></serviceList>
+      </device></deviceList>
+    </device></deviceList>
+  </device>
+</root>`)
+	})
+	mux.HandleFunc("/ctl/IPConn", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("SOAPAction") != `"urn:schemas-upnp-org:service:WANIPConnection:1#GetExternalIPAddress"` {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, `<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>
+<u:GetExternalIPAddressResponse xmlns:u="urn:schemas-upnp-org:service:WANIPConnection:1">
+<NewExternalIPAddress>203.0.113.9</NewExternalIPAddress>
+</u:GetExternalIPAddressResponse></s:Body></s:Envelope>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// 第一个协议失败时继续尝试下一个
+	got, err := NewRouter("upnp@" + server.URL + "/missing.xml, upnp@" + server.URL + "/rootDesc.xml").Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].String() != "203.0.113.9" {
+		t.Fatalf("Fetch() = %v, want [203.0.113.9]", got)
+	}
+}
+
+func TestRouterFetchNATPMPAndPCP(t *testing.T) {
+	gateway := startRouterGateway(t, [4]byte{198, 51, 100, 20})
+	for _, method := range []string{"natpmp", "pcp"} {
+		got, err := NewRouter(method + "@" + gateway).Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if len(got) != 1 || got[0].String() != "198.51.100.20" {
+			t.Fatalf("%s: Fetch() = %v, want [198.51.100.20]", method, got)
+		}
+	}
+}
+
+func TestParseDefaultGateway(t *testing.T) {
+	table := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\n" +
+		"eth0\t0000A8C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\n" +
+		"eth0\t00000000\t0100A8C0\t0003\t0\t0\t100\t00000000\n"
+	got, err := parseDefaultGateway(strings.NewReader(table))
+	if err != nil || got.String() != "192.168.0.1" {
+		t.Fatalf("parseDefaultGateway() = %v, %v, want 192.168.0.1", got, err)
+	}
+}
+
+func TestRouterRejectsUnknownMethod(t *testing.T) {
+	if _, err := NewRouter("igd").Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "不支持的协议") {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+}
+
+// startRouterGateway 启动一个本地 UDP 网关，同时应答 NAT-PMP 外部地址请求和 PCP MAP 请求
+func startRouterGateway(t *testing.T, external [4]byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1100)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var response []byte
+			switch {
+			case n == 2 && buf[0] == 0 && buf[1] == 0:
+				response = make([]byte, natpmpResponseSize)
+				response[1] = 128
+				copy(response[8:12], external[:])
+			case n == pcpMapRequestSize && buf[0] == pcpVersion && buf[1] == pcpOpMap:
+				response = make([]byte, pcpMapResponseSize)
+				response[0] = pcpVersion
+				response[1] = 0x80 | pcpOpMap
+				copy(response[4:8], buf[4:8])
+				copy(response[24:44], buf[24:44])
+				binary.BigEndian.PutUint16(response[42:44], 40000)
+				response[54], response[55] = 0xff, 0xff
+				copy(response[56:60], external[:])
+			default:
+				continue
+			}
+			_, _ = conn.WriteTo(response, from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
@@ -1,7 +1,10 @@
 package addr
 
 import (
+	"cmp"
+	"fmt"
 	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -12,6 +15,11 @@ import (
 //规则2，index@n, 选择第n个IP地址，n从1开始计数，超出范围选择第一个IP地址
 //规则3，splice@n@后缀，选择第n个IP地址的前64位拼接后缀，后缀可以是8字节的数组、切片，或者标准的IPv6后缀字符串（如 "::1"、“::9209:d0ff:fe09:781d“ 或 "0:0:0:1"）
 //规则4，contain@substr，选择包含substr的第一个IP地址
+//规则5，以上规则前可以用逗号串联地址属性规则，按顺序调整地址列表后再选择，如 nodeprecated,stable,index@1
+//  stable 稳定地址优先，隐私扩展生成的临时地址排在后面，没有稳定地址时仍然选择临时地址；需要完全排除临时地址时使用地址策略 Policy.IPv6
+//  nodeprecated 跳过首选生存期已到期（deprecated）的地址
+//  lifetime 按剩余首选生存期从长到短排序
+//  地址属性只能读取本机地址（Linux），读取不到属性的地址视为稳定、未到期、生存期为0
 
 // Selector 接口定义了一个Select方法，用于从给定的IP地址列表中选择一个满足特定条件的地址。
 // 实现这个接口的类型可以根据不同的选择规则来筛选IP地址，例如选择第n个地址、选择包含特定子串的地址，或者根据IPv6地址的前缀和后缀进行组合选择。
@@ -102,7 +110,54 @@ func (s *Contain) Select(addrs []netip.Addr) netip.Addr {
 // - "index@n"：选择第n个IP地址，n从1开始计数。
 // - "splice@n@后缀"：选择第n个IP地址的前64位拼接后缀，后缀可以是8字节的数组、切片，或者标准的IPv6后缀字符串（如 "::1"、“::9209:d0ff:fe09:781d“ 或 "0:0:0:1"）。
 // - "contain@substr"：选择包含substr的第一个IP地址。
-func NewSelector(rule string) Selector {
+// - 以上规则前可以用逗号串联 "stable"、"nodeprecated"、"lifetime"，如 "nodeprecated,lifetime,index@1"。
+// ifaces 为读取地址属性时优先查找的网卡名，只对地址属性规则生效。
+func NewSelector(rule string, ifaces ...string) Selector {
+	var rules []string
+	final := ""
+	for _, part := range strings.Split(rule, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case RuleStable, RuleNoDeprecated, RuleLifetime:
+			rules = append(rules, part)
+		default:
+			if final == "" {
+				final = part
+			}
+		}
+	}
+	if len(rules) == 0 {
+		return newSelector(final)
+	}
+	return &Chain{Rules: rules, Selector: newSelector(final), Ifaces: ifaces}
+}
+
+// ValidateRule 检查规则字符串中的地址属性规则
+// 只检查 stable、nodeprecated、lifetime 的位置和拼写：属性规则需要写在选择规则前面，逗号串联的规则中只能有一个选择规则。
+// 选择规则本身不检查，序号无效或无法识别时仍然按原来的方式选择第一个地址
+func ValidateRule(rule string) error {
+	final := ""
+	for _, part := range strings.Split(rule, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+			continue
+		case RuleStable, RuleNoDeprecated, RuleLifetime:
+			if final != "" {
+				return fmt.Errorf("%q 需要写在选择规则 %q 前面", part, final)
+			}
+			continue
+		}
+		if final != "" {
+			return fmt.Errorf("未知的地址属性规则 %q，请填写 stable、nodeprecated、lifetime", final)
+		}
+		final = part
+	}
+	return nil
+}
+
+// newSelector 创建单个规则的选择器
+func newSelector(rule string) Selector {
 	if rule == "" {
 		return &Index{Index: 1}
 	}
@@ -132,3 +187,69 @@ func NewSelector(rule string) Selector {
 	// 都不匹配返回Index选择器，选择第一个IP地址
 	return &Index{Index: 1}
 }
+
+// 地址属性规则
+const (
+	// RuleStable 稳定地址优先
+	RuleStable = "stable"
+	// RuleNoDeprecated 跳过 deprecated 地址
+	RuleNoDeprecated = "nodeprecated"
+	// RuleLifetime 按剩余首选生存期从长到短排序
+	RuleLifetime = "lifetime"
+)
+
+// Chain 选择器，先按地址属性规则依次调整地址列表，再交给 Selector 选择
+type Chain struct {
+	Rules    []string
+	Selector Selector
+	// Ifaces 读取地址属性时优先查找的网卡名，同一地址在多个网卡上时使用这些网卡上的属性
+	Ifaces []string
+	// lookup 读取本机地址属性，为 nil 时使用 LocalAddrInfo
+	lookup func() (AddrInfos, error)
+}
+
+func (c *Chain) Select(addrs []netip.Addr) netip.Addr {
+	lookup := c.lookup
+	if lookup == nil {
+		lookup = LocalAddrInfo
+	}
+	infos, err := lookup()
+	if err != nil {
+		// 无法读取地址属性时不调整顺序
+		return c.Selector.Select(addrs)
+	}
+	info := func(addr netip.Addr) AddrInfo {
+		return infos.Lookup(addr, c.Ifaces)
+	}
+
+	addrs = slices.Clone(addrs)
+	for _, rule := range c.Rules {
+		switch rule {
+		case RuleStable:
+			slices.SortStableFunc(addrs, func(a, b netip.Addr) int {
+				return boolCompare(info(a).Temporary(), info(b).Temporary())
+			})
+		case RuleNoDeprecated:
+			addrs = slices.DeleteFunc(addrs, func(addr netip.Addr) bool {
+				return info(addr).Deprecated()
+			})
+		case RuleLifetime:
+			slices.SortStableFunc(addrs, func(a, b netip.Addr) int {
+				return cmp.Compare(info(b).Preferred, info(a).Preferred)
+			})
+		}
+	}
+	return c.Selector.Select(addrs)
+}
+
+// boolCompare false 排在 true 前面
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
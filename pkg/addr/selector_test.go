@@ -1,8 +1,12 @@
 package addr
 
 import (
+	"errors"
 	"net/netip"
 	"testing"
+	"time"
+
+	"ddns/pkg/provider"
 )
 
 func TestNewSelector(t *testing.T) {
@@ -28,8 +32,68 @@ func TestNewSelector(t *testing.T) {
 	}
 }
 
+func TestChainSelector(t *testing.T) {
+	old := netip.MustParseAddr("2001:db8:1::1")
+	temporary := netip.MustParseAddr("2001:db8:2::abcd")
+	stable := netip.MustParseAddr("2001:db8:2::1")
+	remote := netip.MustParseAddr("2001:db8:3::1")
+	addrs := []netip.Addr{old, temporary, stable, remote}
+	infos := AddrInfos{
+		{Index: 2, Addr: old}:       {Flags: ifaFlagDeprecated, Valid: time.Hour},
+		{Index: 2, Addr: temporary}: {Flags: ifaFlagTemporary, Preferred: 2 * time.Hour, Valid: 4 * time.Hour},
+		{Index: 2, Addr: stable}:    {Preferred: time.Hour, Valid: 2 * time.Hour},
+	}
+	tests := []struct {
+		rule string
+		want netip.Addr
+	}{
+		{rule: "stable", want: old},
+		{rule: "nodeprecated", want: temporary},
+		{rule: "nodeprecated,stable", want: stable},
+		{rule: "lifetime", want: temporary},
+		{rule: "stable,lifetime", want: temporary},
+		{rule: "nodeprecated,stable,index@3", want: temporary},
+		{rule: "lifetime, contain@:3:", want: remote},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			selector, ok := NewSelector(tt.rule).(*Chain)
+			if !ok {
+				t.Fatalf("NewSelector(%q) did not return *Chain", tt.rule)
+			}
+			selector.lookup = func() (AddrInfos, error) { return infos, nil }
+			if got := selector.Select(addrs); got != tt.want {
+				t.Fatalf("Select() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	selector := NewSelector("nodeprecated").(*Chain)
+	selector.lookup = func() (AddrInfos, error) { return nil, errors.New("unsupported") }
+	if got := selector.Select(addrs); got != old {
+		t.Fatalf("Select() without address info = %s, want %s", got, old)
+	}
+	if addrs[0] != old || addrs[1] != temporary {
+		t.Fatalf("Select() modified input: %v", addrs)
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	// 原有的单个选择规则不检查，无效时按原来的方式选择第一个地址
+	for _, rule := range []string{"", "index@2", "splice@1@::10", "contain@2408", "splice@1", "index@1,", "nodeprecated, stable,lifetime", "nodeprecated,stable,index@1"} {
+		if err := ValidateRule(rule); err != nil {
+			t.Errorf("ValidateRule(%q) = %v", rule, err)
+		}
+	}
+	for _, rule := range []string{"nodeprecate,index@1", "index@1,stable", "stable,lifetme,splice@1@::10"} {
+		if err := ValidateRule(rule); err == nil {
+			t.Errorf("ValidateRule(%q) accepted an invalid rule", rule)
+		}
+	}
+}
+
 func TestNewFetcherRejectsUnsupportedType(t *testing.T) {
-	if _, err := NewFetcher("unknown", "value"); err == nil {
+	if _, err := NewFetcher("unknown", "value", Options{Version: provider.IPv4}); err == nil {
 		t.Fatal("NewFetcher() accepted unsupported type")
 	}
 }
@@ -40,13 +104,16 @@ func TestNewFetcherCreatesSupportedTypes(t *testing.T) {
 		getValue string
 	}{
 		{getType: "cmd", getValue: "echo 127.0.0.1"},
-		{getType: "duid", getValue: "duid"},
+		{getType: "duid", getValue: "000300019009d009781d"},
 		{getType: "nic", getValue: "lo"},
 		{getType: "url", getValue: "https://example.com"},
+		{getType: "stun", getValue: "stun.example.com"},
+		{getType: "dns", getValue: "myip.opendns.com @resolver1.opendns.com"},
+		{getType: "router", getValue: "upnp, natpmp"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.getType, func(t *testing.T) {
-			fetcher, err := NewFetcher(tt.getType, tt.getValue)
+			fetcher, err := NewFetcher(tt.getType, tt.getValue, Options{Version: provider.IPv4})
 			if err != nil || fetcher == nil {
 				t.Fatalf("NewFetcher(%q) = %T, %v", tt.getType, fetcher, err)
 			}
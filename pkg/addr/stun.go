@@ -0,0 +1,280 @@
+package addr
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 通过 STUN 服务器获取公网IP地址（RFC 5389 Binding 请求）
+// 每个服务器分别通过 IPv4 和 IPv6 发送请求，读取响应中的 XOR-MAPPED-ADDRESS
+
+const (
+	stunDefaultPort     = "3478"
+	stunMagicCookie     = 0x2112A442
+	stunHeaderSize      = 20
+	stunBindingRequest  = 0x0001
+	stunBindingSuccess  = 0x0101
+	stunAttrMapped      = 0x0001
+	stunAttrXORMapped   = 0x0020
+	stunFamilyIPv4      = 0x01
+	stunFamilyIPv6      = 0x02
+	stunMaxMessageBytes = 1500
+)
+
+// stunRetransmits 请求的重传间隔，收到响应或者全部超时后结束
+var stunRetransmits = []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second}
+
+// Stun 通过 STUN 服务器获取公网IP地址
+type Stun struct {
+	// Servers STUN 服务器，格式 host 或 host:port，多个使用英文逗号分隔，端口默认 3478
+	Servers string
+	dialer  net.Dialer
+}
+
+func NewStun(servers string) *Stun {
+	return &Stun{Servers: servers}
+}
+
+// stunMapping 一个服务器通过某个地址族返回的映射地址
+type stunMapping struct {
+	server  string
+	network string
+	addr    netip.AddrPort
+}
+
+func (s *Stun) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	var servers []string
+	for _, server := range strings.Split(s.Servers, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("STUN Fetcher: 请提供STUN服务器地址")
+	}
+
+	// 并发向所有服务器发送 IPv4 和 IPv6 请求，结果按服务器顺序保存
+	networks := []string{"udp4", "udp6"}
+	mappings := make([]*stunMapping, len(servers)*len(networks))
+	errs := make([]error, len(mappings))
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		for j, network := range networks {
+			wg.Add(1)
+			go func(index int, server, network string) {
+				defer wg.Done()
+				addr, err := s.query(ctx, network, server)
+				if err != nil {
+					errs[index] = fmt.Errorf("%s(%s): %w", server, network, err)
+					return
+				}
+				mappings[index] = &stunMapping{server: server, network: network, addr: addr}
+			}(i*len(networks)+j, server, network)
+		}
+	}
+	wg.Wait()
+
+	var results []stunMapping
+	for _, mapping := range mappings {
+		if mapping != nil {
+			results = append(results, *mapping)
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("STUN Fetcher: 没有获取到映射地址: %w", errors.Join(errs...))
+	}
+	return stunAddrs(results), nil
+}
+
+// stunAddrs 汇总映射地址，多个服务器返回的地址不一致时记录警告，并把多数服务器返回的地址排在前面
+func stunAddrs(results []stunMapping) []netip.Addr {
+	counts := make(map[netip.Addr]int)
+	var addrs []netip.Addr
+	for _, result := range results {
+		addr := result.addr.Addr()
+		if counts[addr] == 0 {
+			addrs = append(addrs, addr)
+		}
+		counts[addr]++
+	}
+
+	for _, is4 := range []bool{true, false} {
+		var family []stunMapping
+		for _, result := range results {
+			if result.addr.Addr().Is4() == is4 {
+				family = append(family, result)
+			}
+		}
+		if len(family) < 2 {
+			continue
+		}
+		sameAddr, samePort := true, true
+		for _, result := range family[1:] {
+			sameAddr = sameAddr && result.addr.Addr() == family[0].addr.Addr()
+			samePort = samePort && result.addr.Port() == family[0].addr.Port()
+		}
+		switch {
+		case !sameAddr:
+			slog.Warn("STUN 服务器返回的映射地址不一致，优先使用多数服务器返回的地址", "mappings", stunMappingsString(family))
+		case !samePort:
+			// 同一个本地端口映射到不同的公网端口，说明 NAT 为对称型
+			slog.Debug("STUN 服务器返回的映射端口不一致，NAT 可能为对称型", "mappings", stunMappingsString(family))
+		}
+	}
+
+	slices.SortStableFunc(addrs, func(a, b netip.Addr) int {
+		return counts[b] - counts[a]
+	})
+	return addrs
+}
+
+func stunMappingsString(mappings []stunMapping) string {
+	parts := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		parts[i] = mapping.server + "=" + mapping.addr.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// query 向一个服务器发送 Binding 请求，返回映射地址
+func (s *Stun) query(ctx context.Context, network, server string) (netip.AddrPort, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), stunDefaultPort)
+	}
+	conn, err := s.dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	defer conn.Close()
+	// 上下文取消时中断阻塞的读取
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	if _, err := rand.Read(request[8:20]); err != nil {
+		return netip.AddrPort{}, err
+	}
+
+	buf := make([]byte, stunMaxMessageBytes)
+	for _, timeout := range stunRetransmits {
+		if _, err := conn.Write(request); err != nil {
+			return netip.AddrPort{}, err
+		}
+		deadline := time.Now().Add(timeout)
+		if err := conn.SetReadDeadline(deadline); err != nil {
+			return netip.AddrPort{}, err
+		}
+		for {
+			n, err := conn.Read(buf)
+			if ctx.Err() != nil {
+				return netip.AddrPort{}, ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			if err != nil {
+				return netip.AddrPort{}, err
+			}
+			addr, err := parseStunResponse(buf[:n], request[8:20])
+			if errors.Is(err, errStunOtherTransaction) {
+				// 忽略迟到的其他请求的响应，继续等待
+				continue
+			}
+			return addr, err
+		}
+	}
+	return netip.AddrPort{}, fmt.Errorf("等待响应超时")
+}
+
+var errStunOtherTransaction = errors.New("事务ID不匹配")
+
+// parseStunResponse 解析 Binding 成功响应，优先使用 XOR-MAPPED-ADDRESS，兼容旧服务器的 MAPPED-ADDRESS
+func parseStunResponse(msg, transactionID []byte) (netip.AddrPort, error) {
+	if len(msg) < stunHeaderSize || binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie {
+		return netip.AddrPort{}, fmt.Errorf("响应不是 STUN 消息")
+	}
+	if !bytes.Equal(msg[8:20], transactionID) {
+		return netip.AddrPort{}, errStunOtherTransaction
+	}
+	if msgType := binary.BigEndian.Uint16(msg[0:2]); msgType != stunBindingSuccess {
+		return netip.AddrPort{}, fmt.Errorf("服务器返回错误响应: 0x%04x", msgType)
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if stunHeaderSize+length > len(msg) {
+		return netip.AddrPort{}, fmt.Errorf("响应长度无效")
+	}
+
+	var mapped netip.AddrPort
+	attrs := msg[stunHeaderSize : stunHeaderSize+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLen > len(attrs) {
+			return netip.AddrPort{}, fmt.Errorf("响应属性长度无效")
+		}
+		value := attrs[4 : 4+attrLen]
+		switch attrType {
+		case stunAttrXORMapped:
+			return parseStunAddress(value, msg[4:20])
+		case stunAttrMapped:
+			if addr, err := parseStunAddress(value, nil); err == nil {
+				mapped = addr
+			}
+		}
+		// 属性按 4 字节对齐，最后一个属性缺少填充时结束解析
+		next := 4 + (attrLen+3)&^3
+		if next > len(attrs) {
+			break
+		}
+		attrs = attrs[next:]
+	}
+	if mapped.IsValid() {
+		return mapped, nil
+	}
+	return netip.AddrPort{}, fmt.Errorf("响应中没有映射地址")
+}
+
+// parseStunAddress 解析地址属性，xorKey 为 magic cookie 加事务ID，为 nil 时表示不做异或
+func parseStunAddress(value, xorKey []byte) (netip.AddrPort, error) {
+	if len(value) < 4 {
+		return netip.AddrPort{}, fmt.Errorf("地址属性长度无效")
+	}
+	port := binary.BigEndian.Uint16(value[2:4])
+	var ip []byte
+	switch value[1] {
+	case stunFamilyIPv4:
+		ip = slices.Clone(value[4:])
+		if len(ip) != net.IPv4len {
+			return netip.AddrPort{}, fmt.Errorf("IPv4 地址长度无效")
+		}
+	case stunFamilyIPv6:
+		ip = slices.Clone(value[4:])
+		if len(ip) != net.IPv6len {
+			return netip.AddrPort{}, fmt.Errorf("IPv6 地址长度无效")
+		}
+	default:
+		return netip.AddrPort{}, fmt.Errorf("未知的地址族: %d", value[1])
+	}
+	if xorKey != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= xorKey[i]
+		}
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr, port), nil
+}
@@ -0,0 +1,101 @@
+package addr
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestStunFetchPrefersMajorityMapping(t *testing.T) {
+	servers := []string{
+		startStunServer(t, netip.MustParseAddrPort("198.51.100.7:4000")),
+		startStunServer(t, netip.MustParseAddrPort("203.0.113.5:4000")),
+		startStunServer(t, netip.MustParseAddrPort("203.0.113.5:4001")),
+	}
+	got, err := NewStun(strings.Join(servers, ", ")).Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].String() != "203.0.113.5" || got[1].String() != "198.51.100.7" {
+		t.Fatalf("Fetch() = %v, want majority address first", got)
+	}
+}
+
+func TestParseStunResponseAddressFamilies(t *testing.T) {
+	transactionID := []byte("0123456789ab")
+	for _, want := range []string{"203.0.113.5:3478", "[2001:db8::1]:54321"} {
+		addr := netip.MustParseAddrPort(want)
+		got, err := parseStunResponse(stunResponse(transactionID, addr), transactionID)
+		if err != nil || got != addr {
+			t.Fatalf("parseStunResponse() = %v, %v, want %v", got, err, addr)
+		}
+	}
+	if _, err := parseStunResponse(stunResponse([]byte("other-txn-id"), netip.MustParseAddrPort("203.0.113.5:1")), transactionID); err != errStunOtherTransaction {
+		t.Fatalf("mismatched transaction error = %v", err)
+	}
+}
+
+func TestParseStunResponseUnpaddedAttribute(t *testing.T) {
+	transactionID := []byte("0123456789ab")
+	msg := stunResponse(transactionID, netip.MustParseAddrPort("203.0.113.5:3478"))[:stunHeaderSize]
+	// MAPPED-ADDRESS 之后是一个长度为 1 且缺少填充的属性
+	msg = append(msg, 0, byte(stunAttrMapped), 0, 8, 0, stunFamilyIPv4, 0x0d, 0x96, 198, 51, 100, 7)
+	msg = append(msg, 0x80, 0x22, 0, 1, 'x')
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)-stunHeaderSize))
+	got, err := parseStunResponse(msg, transactionID)
+	if err != nil || got != netip.MustParseAddrPort("198.51.100.7:3478") {
+		t.Fatalf("parseStunResponse() = %v, %v", got, err)
+	}
+}
+
+// startStunServer 启动一个本地 UDP STUN 服务器，对所有 Binding 请求返回固定的映射地址
+func startStunServer(t *testing.T, mapped netip.AddrPort) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, stunMaxMessageBytes)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < stunHeaderSize || binary.BigEndian.Uint16(buf[0:2]) != stunBindingRequest {
+				continue
+			}
+			_, _ = conn.WriteTo(stunResponse(buf[8:20], mapped), from)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// stunResponse 构造带 XOR-MAPPED-ADDRESS 的 Binding 成功响应
+func stunResponse(transactionID []byte, mapped netip.AddrPort) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint32(key[0:4], stunMagicCookie)
+	copy(key[4:], transactionID)
+	ip := mapped.Addr().AsSlice()
+	family := byte(stunFamilyIPv4)
+	if mapped.Addr().Is6() {
+		family = stunFamilyIPv6
+	}
+	for i := range ip {
+		ip[i] ^= key[i]
+	}
+	value := append([]byte{0, family, 0, 0}, ip...)
+	binary.BigEndian.PutUint16(value[2:4], mapped.Port()^uint16(stunMagicCookie>>16))
+
+	msg := make([]byte, stunHeaderSize, stunHeaderSize+4+len(value))
+	binary.BigEndian.PutUint16(msg[0:2], stunBindingSuccess)
+	binary.BigEndian.PutUint16(msg[2:4], uint16(4+len(value)))
+	copy(msg[4:20], key)
+	msg = binary.BigEndian.AppendUint16(msg, stunAttrXORMapped)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(value)))
+	return append(msg, value...)
+}
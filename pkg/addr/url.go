@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/netip"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,7 +17,13 @@ import (
 
 // Url
 type Url struct {
-	Urls   string
+	Urls string
+	// Extractor 从响应中读取IP地址，为 nil 时扫描全部内容
+	Extractor Extractor
+	// Quorum 大于 0 时启用一致性模式，至少 Quorum 个 URL 返回同一地址才采用
+	Quorum int
+	// Accept 一致性模式只统计满足条件的地址，为 nil 时统计所有地址
+	Accept Filter
 	client http.Client
 }
 
@@ -29,69 +37,30 @@ func NewUrl(urls string) *Url {
 }
 
 func (u *Url) Fetch(ctx context.Context) ([]netip.Addr, error) {
-	if u.Urls == "" {
-		return nil, fmt.Errorf("URL Fetcher: 请提供URL地址")
+	var urls []string
+	for _, url := range strings.Split(u.Urls, ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
 	}
-
-	urls := strings.Split(u.Urls, ",")
 	if len(urls) == 0 {
 		return nil, fmt.Errorf("URL Fetcher: 请提供URL地址")
 	}
 
-	type result struct {
-		ips []netip.Addr
-		err error
-	}
+	// 达到法定数量后取消其余请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// 并发获取所有 URL 的 IP
-	resultCh := make(chan result, len(urls))
+	resultCh := make(chan urlResult, len(urls))
 	var wg sync.WaitGroup
-
 	for _, url := range urls {
-		url := strings.TrimSpace(url)
-		if url == "" {
-			continue
-		}
-
 		wg.Add(1)
-		go func(targetURL string) {
+		go func() {
 			defer wg.Done()
-
-			req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
-			if err != nil {
-				resultCh <- result{nil, err}
-				return
-			}
-
-			resp, err := u.client.Do(req)
-			if err != nil {
-				resultCh <- result{nil, err}
-				return
-			}
-			defer resp.Body.Close()
-			// 检查HTTP响应状态码
-			if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
-				resultCh <- result{nil, fmt.Errorf("URL Fetcher: HTTP请求失败，状态码: %d", resp.StatusCode)}
-				return
-			}
-
-			// 读取响应体，限制最大读取1MB
-			body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20+1))
-			if err != nil {
-				resultCh <- result{nil, err}
-				return
-			}
-			if len(body) > 1<<20 {
-				resultCh <- result{nil, fmt.Errorf("URL Fetcher: 响应内容超过 1 MiB 限制")}
-				return
-			}
-			ips, err := extractFromString(string(body))
-			if err != nil {
-				resultCh <- result{nil, err}
-				return
-			}
-			resultCh <- result{ips, nil}
-		}(url)
+			ips, err := u.fetchOne(ctx, url)
+			resultCh <- urlResult{url: url, ips: ips, err: err}
+		}()
 	}
 
 	// 等待所有请求完成
@@ -100,6 +69,10 @@ func (u *Url) Fetch(ctx context.Context) ([]netip.Addr, error) {
 		close(resultCh)
 	}()
 
+	if u.Quorum > 0 {
+		return u.consensus(resultCh)
+	}
+
 	// 收集结果
 	var ips []netip.Addr
 	for r := range resultCh {
@@ -114,3 +87,80 @@ func (u *Url) Fetch(ctx context.Context) ([]netip.Addr, error) {
 
 	return ips, nil
 }
+
+// urlResult 一个 URL 的获取结果
+type urlResult struct {
+	url string
+	ips []netip.Addr
+	err error
+}
+
+// consensus 统计每个地址的来源数量，第一个达到 Quorum 的地址立即返回
+// 只统计满足 Accept 的地址，同一来源返回的重复地址只计一次
+func (u *Url) consensus(resultCh <-chan urlResult) ([]netip.Addr, error) {
+	votes := make(map[netip.Addr]int)
+	var results []urlResult
+	for r := range resultCh {
+		results = append(results, r)
+		seen := make(map[netip.Addr]bool)
+		for _, addr := range r.ips {
+			addr = addr.Unmap()
+			if seen[addr] || (u.Accept != nil && !u.Accept(addr)) {
+				continue
+			}
+			seen[addr] = true
+			votes[addr]++
+			if votes[addr] >= u.Quorum {
+				if dissent := urlDissent(results, addr); dissent != "" {
+					slog.Warn("URL 来源返回的地址与多数不一致", "addr", addr, "quorum", u.Quorum, "dissent", dissent)
+				}
+				return []netip.Addr{addr}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("URL Fetcher: 没有地址得到 %d 个来源的一致结果: %s", u.Quorum, urlDissent(results, netip.Addr{}))
+}
+
+// urlDissent 列出没有返回 agreed 的来源及其结果
+func urlDissent(results []urlResult, agreed netip.Addr) string {
+	var parts []string
+	for _, r := range results {
+		if slices.Contains(r.ips, agreed) {
+			continue
+		}
+		if r.err != nil {
+			parts = append(parts, fmt.Sprintf("%s=错误(%v)", r.url, r.err))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", r.url, r.ips))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fetchOne 请求一个 URL 并读取响应中的IP地址
+func (u *Url) fetchOne(ctx context.Context, targetURL string) ([]netip.Addr, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// 检查HTTP响应状态码
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("URL Fetcher: HTTP请求失败，状态码: %d", resp.StatusCode)
+	}
+
+	// 读取响应体，限制最大读取1MB
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 1<<20 {
+		return nil, fmt.Errorf("URL Fetcher: 响应内容超过 1 MiB 限制")
+	}
+	return extract(u.Extractor, body)
+}
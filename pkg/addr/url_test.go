@@ -4,8 +4,10 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestURLFetch(t *testing.T) {
@@ -39,3 +41,39 @@ func TestURLFetch(t *testing.T) {
 type roundTripperFunc func(*http.Request) (*http.Response, error)
 
 func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) { return f(request) }
+
+func TestURLFetchQuorum(t *testing.T) {
+	answers := map[string]string{
+		"a.example": "203.0.113.7",
+		"b.example": "198.51.100.66",
+		"c.example": "203.0.113.7",
+	}
+	newFetcher := func(urls string, quorum int) *Url {
+		fetcher := NewUrl(urls)
+		fetcher.Quorum, fetcher.Accept = quorum, func(addr netip.Addr) bool { return IsIPv4(addr) && IsPublic(addr) }
+		fetcher.client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			answer, ok := answers[req.URL.Host]
+			if !ok {
+				// 不响应的来源，一致性达成后应被取消
+				<-req.Context().Done()
+				return nil, req.Context().Err()
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(answer)), Header: make(http.Header)}, nil
+		})
+		return fetcher
+	}
+
+	start := time.Now()
+	got, err := newFetcher("https://a.example, https://b.example, https://c.example, https://slow.example", 2).Fetch(context.Background())
+	if err != nil || len(got) != 1 || got[0].String() != "203.0.113.7" {
+		t.Fatalf("Fetch() = %v, %v, want [203.0.113.7]", got, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Fetch() waited %v for the slow source after quorum", elapsed)
+	}
+
+	_, err = newFetcher("https://a.example, https://b.example", 2).Fetch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "b.example=[198.51.100.66]") {
+		t.Fatalf("Fetch() error = %v, want disagreeing source", err)
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"ddns/pkg/addr"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxCommandArgs command.args、command.env 的最大数量
+	MaxCommandArgs = 64
+	// MaxCommandTimeout command.timeout 的最大值，单位秒
+	MaxCommandTimeout = 300
+)
+
+// CommandSpec cmd 获取方式的结构化命令，零值表示通过 shell 执行 getValue
+type CommandSpec struct {
+	// 程序和参数，第一个为程序名或路径，不经过 shell 执行，填写时 getValue 为空
+	Args []string `yaml:"args,omitempty" mapstructure:"args"`
+	// 工作目录
+	Dir string `yaml:"dir,omitempty" mapstructure:"dir"`
+	// 追加的环境变量，格式 KEY=VALUE
+	Env []string `yaml:"env,omitempty" mapstructure:"env"`
+	// 超时时间，单位秒，为 0 时为 5 秒
+	Timeout int `yaml:"timeout,omitempty" mapstructure:"timeout"`
+}
+
+// IsZero 是否没有配置结构化命令
+func (c CommandSpec) IsZero() bool {
+	return len(c.Args) == 0 && c.Dir == "" && len(c.Env) == 0 && c.Timeout == 0
+}
+
+// Build 返回 addr 包使用的命令参数，没有配置时返回 nil
+func (c CommandSpec) Build() *addr.Execute {
+	if c.IsZero() {
+		return nil
+	}
+	return &addr.Execute{Args: c.Args, Dir: c.Dir, Env: c.Env, Timeout: time.Duration(c.Timeout) * time.Second}
+}
+
+// validateCommand 检查结构化命令，field 为记录的字段路径
+func validateCommand(r Record, field string) []error {
+	c := r.Command
+	if c.IsZero() {
+		return nil
+	}
+	var errs []error
+	if r.GetType != "cmd" {
+		errs = append(errs, fmt.Errorf("%s.command 只支持 cmd 获取方式", field))
+	}
+	if len(c.Args) > 0 && r.GetValue != "" {
+		errs = append(errs, fmt.Errorf("%s.getValue 和 command.args 只能填写一个", field))
+	}
+	if len(c.Args) > MaxCommandArgs || len(c.Env) > MaxCommandArgs {
+		errs = append(errs, fmt.Errorf("%s.command 的 args、env 数量不能超过 %d 个", field, MaxCommandArgs))
+	}
+	if len(c.Args) > 0 && strings.TrimSpace(c.Args[0]) == "" {
+		errs = append(errs, fmt.Errorf("%s.command.args 的第一个参数必须是程序名或路径", field))
+	}
+	if err := validateByteLength(field+".command.args", strings.Join(c.Args, " "), MaxCommandBytes); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateByteLength(field+".command.dir", c.Dir, MaxCommandBytes); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validateByteLength(field+".command.env", strings.Join(c.Env, " "), MaxCommandBytes); err != nil {
+		errs = append(errs, err)
+	}
+	for i, env := range c.Env {
+		if key, _, ok := strings.Cut(env, "="); !ok || strings.TrimSpace(key) == "" {
+			errs = append(errs, fmt.Errorf("%s.command.env[%d] 格式无效，请填写 KEY=VALUE", field, i))
+		}
+	}
+	if c.Timeout != 0 && (c.Timeout < 1 || c.Timeout > MaxCommandTimeout) {
+		errs = append(errs, fmt.Errorf("%s.command.timeout 无效，请填写 1-%d 秒", field, MaxCommandTimeout))
+	}
+	// 配置了备用获取方式时命令还受 sourceTimeout 限制，更长的命令超时时间不会生效
+	if limit := r.FetchTimeout(); limit > 0 && time.Duration(c.Timeout)*time.Second > limit {
+		errs = append(errs, fmt.Errorf("%s.command.timeout 不能超过获取方式的超时时间 sourceTimeout（%d 秒）", field, int(limit/time.Second)))
+	}
+	return errs
+}
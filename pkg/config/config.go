@@ -1,6 +1,7 @@
 package config
 
 import (
+	"ddns/pkg/addr"
 	"ddns/pkg/provider"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"strings"
 
 	"go.yaml.in/yaml/v3"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/net/idna"
 )
 
@@ -16,10 +18,12 @@ const (
 	MaxProviderTypeBytes   = 32
 	MaxRecordNameBytes     = 64
 	MaxAccessKeyBytes      = 256
+	MaxServerBytes         = 256
 	MaxURLBytes            = 2048
 	MaxCommandBytes        = 4096
 	MaxNICBytes            = 256
-	MaxDUIDBytes           = 128
+	MaxDUIDBytes           = 1024
+	MaxMACBytes            = 1024
 	MaxRuleBytes           = 512
 	MaxGetTypeBytes        = 16
 	MaxDomainBytes         = 253
@@ -38,6 +42,8 @@ type Config struct {
 	Providers []Provider `yaml:"providers" mapstructure:"providers"`
 	Webhook   Webhook    `yaml:"webhook" mapstructure:"webhook"`
 	Auth      Auth       `yaml:"auth" mapstructure:"auth"`
+	// 可以调用内置 DynDNS2 接口（/nic/update）推送 IP 地址的客户端
+	DynDNSClients []DynDNSClient `yaml:"dyndnsClients,omitempty" mapstructure:"dyndnsClients"`
 }
 
 type Webhook struct {
@@ -46,6 +52,13 @@ type Webhook struct {
 	Headers []string `yaml:"headers" mapstructure:"headers"`
 }
 
+// DynDNSClient 内置 DynDNS2 接口的客户端凭据，记录的 getType 为 dyndns 时 getValue 填写 username
+// 与 Web 控制台的登录密码一样只保存 bcrypt 哈希
+type DynDNSClient struct {
+	Username     string `yaml:"username" mapstructure:"username"`
+	PasswordHash string `yaml:"passwordHash" mapstructure:"passwordHash"`
+}
+
 type Auth struct {
 	Username     string `yaml:"username" mapstructure:"username"`
 	PasswordHash string `yaml:"passwordHash" mapstructure:"passwordHash"`
@@ -60,23 +73,31 @@ type Provider struct {
 	//DNS服务商密钥
 	KeyID     string `yaml:"keyId" mapstructure:"keyId"`
 	KeySecret string `yaml:"keySecret" mapstructure:"keySecret"`
+	// 服务器地址，rfc2136 为 DNS 服务器 host 或 host:port，dyndns2 为更新地址 URL
+	Server string `yaml:"server,omitempty" mapstructure:"server"`
 	// 记录列表
 	Records []Record `yaml:"records" mapstructure:"records"`
 	// 强制同步时间，单位分钟
 	ForceInterval int64 `yaml:"forceInterval" mapstructure:"forceInterval"`
 }
 
+// Credentials 返回创建服务商实例使用的凭据
+func (p Provider) Credentials() provider.Credentials {
+	return provider.Credentials{KeyID: p.KeyID, KeySecret: p.KeySecret, Server: p.Server}
+}
+
 func (p Provider) MarshalYAML() (any, error) {
 	type providerYAML struct {
 		Name          string   `yaml:"name"`
 		Provider      string   `yaml:"provider"`
 		KeyID         string   `yaml:"keyId"`
 		KeySecret     string   `yaml:"keySecret"`
+		Server        string   `yaml:"server,omitempty"`
 		Records       []Record `yaml:"records"`
 		ForceInterval int64    `yaml:"forceInterval"`
 	}
 	return providerYAML{
-		Name: p.Name, Provider: p.Provider, KeyID: p.KeyID, KeySecret: p.KeySecret,
+		Name: p.Name, Provider: p.Provider, KeyID: p.KeyID, KeySecret: p.KeySecret, Server: p.Server,
 		Records: p.Records, ForceInterval: int64(p.ForceInterval),
 	}, nil
 }
@@ -87,6 +108,7 @@ func (p *Provider) UnmarshalYAML(value *yaml.Node) error {
 		Provider      string   `yaml:"provider"`
 		KeyID         string   `yaml:"keyId"`
 		KeySecret     string   `yaml:"keySecret"`
+		Server        string   `yaml:"server,omitempty"`
 		Records       []Record `yaml:"records"`
 		ForceInterval int64    `yaml:"forceInterval"`
 	}
@@ -95,7 +117,7 @@ func (p *Provider) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 	*p = Provider{
-		Name: raw.Name, Provider: raw.Provider, KeyID: raw.KeyID, KeySecret: raw.KeySecret,
+		Name: raw.Name, Provider: raw.Provider, KeyID: raw.KeyID, KeySecret: raw.KeySecret, Server: raw.Server,
 		Records: raw.Records, ForceInterval: raw.ForceInterval,
 	}
 	return nil
@@ -119,26 +141,96 @@ type Record struct {
 	Interval int64 `yaml:"interval" mapstructure:"interval"`
 	//筛选IP地址的规则
 	Rule string `yaml:"rule" mapstructure:"rule"`
+	// 从 URL 响应或命令输出中提取IP地址的规则，如 json@data.ip，为空时扫描全部内容
+	Extract string `yaml:"extract,omitempty" mapstructure:"extract"`
+	// cmd 获取方式的结构化命令，填写 args 时不经过 shell 执行
+	Command CommandSpec `yaml:"command,omitempty" mapstructure:"command"`
+	// 一致性模式，至少 quorum 个 URL 返回同一地址才采用，仅 url 获取方式使用，0 表示不启用
+	Quorum int `yaml:"quorum,omitempty" mapstructure:"quorum"`
+	// 备用获取方式，主获取方式失败或没有符合条件的地址时依次尝试
+	Fallbacks []Fallback `yaml:"fallbacks,omitempty" mapstructure:"fallbacks"`
+	// 每个获取方式的超时时间，单位秒，为 0 时配置了备用获取方式按 10 秒处理，否则不限制
+	SourceTimeout int `yaml:"sourceTimeout,omitempty" mapstructure:"sourceTimeout"`
+	// 候选地址的健康检查，配置后发布第一个检查通过的地址，publish 为 all 时发布所有检查通过的地址
+	Health HealthCheck `yaml:"health,omitempty" mapstructure:"health"`
+	// 发布方式，为空时发布一个地址，all 发布所有地址，云端按地址集合维护多条同名记录
+	Publish string `yaml:"publish,omitempty" mapstructure:"publish"`
+	// 地址策略，决定哪些地址可以用于记录，零值时只接受公网地址
+	Policy AddrPolicy `yaml:"policy,omitempty" mapstructure:"policy"`
+	// 前缀跟踪的委派前缀长度，48-64，为 0 时按 64 处理
+	PrefixLength int `yaml:"prefixLength,omitempty" mapstructure:"prefixLength"`
+	// 前缀跟踪的主机，子域名使用委派前缀拼接主机的接口标识
+	Hosts []PrefixHost `yaml:"hosts,omitempty" mapstructure:"hosts"`
+	// 是否开启 Cloudflare 代理，为空时保持云端设置，仅 cloudflare 服务商生效
+	Proxied *bool `yaml:"proxied,omitempty" mapstructure:"proxied"`
+	// 记录类型，为空时按 ipVersion 使用 A 或 AAAA
+	Type string `yaml:"type,omitempty" mapstructure:"type"`
+	// 记录值模板，CNAME、TXT、MX、SRV、CAA 记录使用，可引用 {{.IP}} 和 {{.SubDomain}}
+	Value string `yaml:"value,omitempty" mapstructure:"value"`
+	// MX、SRV 优先级
+	Priority int `yaml:"priority,omitempty" mapstructure:"priority"`
+	// SRV 权重
+	Weight int `yaml:"weight,omitempty" mapstructure:"weight"`
+	// SRV 端口
+	Port int `yaml:"port,omitempty" mapstructure:"port"`
 }
 
 func (r *Record) UnmarshalYAML(value *yaml.Node) error {
 	type recordYAML struct {
-		Name       string           `yaml:"name"`
-		SubDomains []string         `yaml:"subDomains"`
-		IPVersion  provider.Version `yaml:"ipVersion"`
-		TTL        int64            `yaml:"ttl"`
-		GetType    string           `yaml:"getType"`
-		GetValue   string           `yaml:"getValue"`
-		Interval   int64            `yaml:"interval"`
-		Rule       string           `yaml:"rule"`
+		Name          string           `yaml:"name"`
+		SubDomains    []string         `yaml:"subDomains"`
+		IPVersion     provider.Version `yaml:"ipVersion"`
+		TTL           int64            `yaml:"ttl"`
+		GetType       string           `yaml:"getType"`
+		GetValue      string           `yaml:"getValue"`
+		Interval      int64            `yaml:"interval"`
+		Rule          string           `yaml:"rule"`
+		Extract       string           `yaml:"extract"`
+		Command       CommandSpec      `yaml:"command"`
+		Quorum        int              `yaml:"quorum"`
+		Fallbacks     []Fallback       `yaml:"fallbacks"`
+		SourceTimeout int              `yaml:"sourceTimeout"`
+		Health        HealthCheck      `yaml:"health"`
+		Publish       string           `yaml:"publish"`
+		Policy        AddrPolicy       `yaml:"policy"`
+		PrefixLength  int              `yaml:"prefixLength"`
+		Hosts         []PrefixHost     `yaml:"hosts"`
+		Proxied       *bool            `yaml:"proxied"`
+		Type          string           `yaml:"type"`
+		Value         string           `yaml:"value"`
+		Priority      int              `yaml:"priority"`
+		Weight        int              `yaml:"weight"`
+		Port          int              `yaml:"port"`
 	}
 	var raw recordYAML
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
 	*r = Record{
-		Name: raw.Name, SubDomains: raw.SubDomains, IPVersion: raw.IPVersion, TTL: raw.TTL,
-		GetType: raw.GetType, GetValue: raw.GetValue, Interval: raw.Interval, Rule: raw.Rule,
+		Name:          raw.Name,
+		SubDomains:    raw.SubDomains,
+		IPVersion:     raw.IPVersion,
+		TTL:           raw.TTL,
+		GetType:       raw.GetType,
+		GetValue:      raw.GetValue,
+		Interval:      raw.Interval,
+		Rule:          raw.Rule,
+		Extract:       raw.Extract,
+		Command:       raw.Command,
+		Quorum:        raw.Quorum,
+		Fallbacks:     raw.Fallbacks,
+		SourceTimeout: raw.SourceTimeout,
+		Health:        raw.Health,
+		Publish:       strings.ToLower(strings.TrimSpace(raw.Publish)),
+		Policy:        raw.Policy,
+		PrefixLength:  raw.PrefixLength,
+		Hosts:         raw.Hosts,
+		Proxied:       raw.Proxied,
+		Type:          strings.ToUpper(strings.TrimSpace(raw.Type)),
+		Value:         raw.Value,
+		Priority:      raw.Priority,
+		Weight:        raw.Weight,
+		Port:          raw.Port,
 	}
 	return nil
 }
@@ -150,6 +242,28 @@ func (c *Config) Validate() error {
 		errs = append(errs, err)
 	}
 
+	// 检查 DynDNS2 客户端
+	dyndnsClients := make(map[string]bool)
+	for i, client := range c.DynDNSClients {
+		if client.Username == "" {
+			errs = append(errs, fmt.Errorf("dyndnsClients[%d].username 不能为空", i))
+		}
+		if err := validateByteLength("dyndnsClients["+strconv.Itoa(i)+"].username", client.Username, MaxUsernameBytes); err != nil {
+			errs = append(errs, err)
+		}
+		if client.PasswordHash == "" {
+			errs = append(errs, fmt.Errorf("dyndnsClients[%d].passwordHash 不能为空", i))
+		} else if err := validateByteLength("dyndnsClients["+strconv.Itoa(i)+"].passwordHash", client.PasswordHash, MaxPasswordHashBytes); err != nil {
+			errs = append(errs, err)
+		} else if _, err := bcrypt.Cost([]byte(client.PasswordHash)); err != nil {
+			errs = append(errs, fmt.Errorf("dyndnsClients[%d].passwordHash 不是有效的 bcrypt 哈希", i))
+		}
+		if dyndnsClients[client.Username] {
+			errs = append(errs, fmt.Errorf("dyndnsClients[%d].username 重复: %s", i, client.Username))
+		}
+		dyndnsClients[client.Username] = true
+	}
+
 	//检查Providers
 	providerNames := make(map[string]bool)
 	for i, p := range c.Providers {
@@ -160,24 +274,27 @@ func (c *Config) Validate() error {
 		if err := validateByteLength("providers["+strconv.Itoa(i)+"].name", p.Name, MaxProviderNameBytes); err != nil {
 			errs = append(errs, err)
 		}
-		if p.KeyID == "" {
-			errs = append(errs, fmt.Errorf("providers[%d].KeyID  不能为空", i))
-		}
 		if err := validateByteLength("providers["+strconv.Itoa(i)+"].keyId", p.KeyID, MaxAccessKeyBytes); err != nil {
 			errs = append(errs, err)
 		}
 		if err := validateByteLength("providers["+strconv.Itoa(i)+"].keySecret", p.KeySecret, MaxAccessKeyBytes); err != nil {
 			errs = append(errs, err)
 		}
-		if p.KeySecret == "" {
-			errs = append(errs, fmt.Errorf("providers[%d].keySecret 不能为空", i))
-
+		if err := validateByteLength("providers["+strconv.Itoa(i)+"].server", p.Server, MaxServerBytes); err != nil {
+			errs = append(errs, err)
 		}
 		if p.Provider == "" {
 			errs = append(errs, fmt.Errorf("providers[%d].provider 不能为空", i))
-		}
-		if !validProviderTypes[p.Provider] {
-			errs = append(errs, fmt.Errorf("providers[%d].provider 无效，请填写 aliyun、baidu、dnsla、tencent、huawei 或 volcengine", i))
+		} else if registration, ok := provider.Lookup(p.Provider); !ok {
+			errs = append(errs, fmt.Errorf("providers[%d].provider 无效，请填写 %s", i, strings.Join(provider.Names(), "、")))
+		} else {
+			// 按服务商注册的凭据字段检查必填项
+			credentials := p.Credentials()
+			for _, field := range registration.Fields {
+				if field.Required && strings.TrimSpace(credentials.Get(field.Key)) == "" {
+					errs = append(errs, fmt.Errorf("providers[%d].%s 不能为空", i, field.Key))
+				}
+			}
 		}
 		if err := validateByteLength("providers["+strconv.Itoa(i)+"].provider", p.Provider, MaxProviderTypeBytes); err != nil {
 			errs = append(errs, err)
@@ -192,60 +309,123 @@ func (c *Config) Validate() error {
 		}
 		providerNames[p.Name] = true
 
+		// 服务商实例声明的能力，与同步时使用的相同；构造函数只保存凭据，不访问网络
+		// 服务商无效时已经报错，按完整能力检查记录
+		caps := provider.CapAll
+		if operator, err := provider.New(p.Provider, p.Credentials()); err == nil {
+			caps = provider.CapabilitiesOf(operator)
+		}
+
 		//检查Records
 		recordNames := make(map[string]bool)
-		domainVersions := make(map[string]bool)
+		domainTypes := make(map[string]map[string]bool)
 		for j, r := range p.Records {
+			field := "providers[" + p.Name + "].records[" + strconv.Itoa(j) + "]"
 			// 检查record空值
 			if r.Name == "" {
 				errs = append(errs, fmt.Errorf("providers[%s].records[%d].name 不能为空", p.Name, j))
 			}
-			if err := validateByteLength("providers["+p.Name+"].records["+strconv.Itoa(j)+"].name", r.Name, MaxRecordNameBytes); err != nil {
+			if err := validateByteLength(field+".name", r.Name, MaxRecordNameBytes); err != nil {
 				errs = append(errs, err)
 			}
-			if r.GetType == "" {
-				errs = append(errs, fmt.Errorf("providers[%s].records[%d].getType 不能为空", p.Name, j))
+			errs = append(errs, validateRecordType(r, field)...)
+			// 静态的非地址记录不需要获取 IP 地址
+			if r.NeedsAddr() {
+				if r.GetType == "" {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].getType 不能为空", p.Name, j))
+				}
+				if !validGetTypes[r.GetType] {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].getType 无效，请填写 cmd、url、nic、openwrt、duid、mac、stun、dns、router 或 dyndns", p.Name, j))
+				}
+				// router 方式的 getValue 可以为空，表示自动发现网关并依次尝试所有协议；结构化命令使用 command.args
+				if r.GetValue == "" && r.GetType != "router" && len(r.Command.Args) == 0 {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].getValue 不能为空", p.Name, j))
+				}
+				if r.IPVersion != provider.IPv4 && r.IPVersion != provider.IPv6 {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].ipVersion 无效，请填写 4 或 6", p.Name, j))
+				}
 			}
-			if !validGetTypes[r.GetType] {
-				errs = append(errs, fmt.Errorf("providers[%s].records[%d].getType 无效，请填写 cmd、url、nic 或 duid", p.Name, j))
+			if r.GetType == "dyndns" && r.GetValue != "" && !dyndnsClients[r.GetValue] {
+				errs = append(errs, fmt.Errorf("providers[%s].records[%d].getValue 不是已配置的 DynDNS 客户端: %s", p.Name, j, r.GetValue))
 			}
-			if err := validateByteLength("providers["+p.Name+"].records["+strconv.Itoa(j)+"].getType", r.GetType, MaxGetTypeBytes); err != nil {
+			if err := validateByteLength(field+".getType", r.GetType, MaxGetTypeBytes); err != nil {
 				errs = append(errs, err)
 			}
-			if r.GetValue == "" {
-				errs = append(errs, fmt.Errorf("providers[%s].records[%d].getValue 不能为空", p.Name, j))
-			}
 			if len(r.SubDomains) == 0 {
 				errs = append(errs, fmt.Errorf("providers[%s].records[%d].subDomains 不能为空", p.Name, j))
 			}
+			recordType := r.RecordType()
 			for _, subDomain := range r.SubDomains {
-				key, err := domainVersionKey(subDomain, r.IPVersion)
-				if err != nil {
+				normalized, err := normalizedDomainName(subDomain)
+				if err != nil || recordType == "" {
 					continue
 				}
-				if domainVersions[key] {
-					errs = append(errs, fmt.Errorf("providers[%s].records[%d].subDomains 与同服务商其他记录重复: %s (IPv%d)", p.Name, j, subDomain, r.IPVersion))
+				types := domainTypes[normalized]
+				if types == nil {
+					types = make(map[string]bool)
+					domainTypes[normalized] = types
 				}
-				domainVersions[key] = true
+				if types[recordType] {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].subDomains 与同服务商其他记录重复: %s (%s)", p.Name, j, subDomain, recordType))
+				} else if len(types) > 0 && (recordType == provider.TypeCNAME || types[provider.TypeCNAME]) {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].subDomains 的 CNAME 记录不能与其他记录共存: %s", p.Name, j, subDomain))
+				}
+				types[recordType] = true
 			}
-			if err := validateByteLength("providers["+p.Name+"].records["+strconv.Itoa(j)+"].getValue", r.GetValue, maxGetValueBytes(r.GetType)); err != nil {
+			if err := validateByteLength(field+".getValue", r.GetValue, maxGetValueBytes(r.GetType)); err != nil {
 				errs = append(errs, err)
 			}
-			if err := validateByteLength("providers["+p.Name+"].records["+strconv.Itoa(j)+"].rule", r.Rule, MaxRuleBytes); err != nil {
+			if err := validateByteLength(field+".rule", r.Rule, MaxRuleBytes); err != nil {
 				errs = append(errs, err)
 			}
-			if r.IPVersion != provider.IPv4 && r.IPVersion != provider.IPv6 {
-				errs = append(errs, fmt.Errorf("providers[%s].records[%d].ipVersion 无效，请填写 4 或 6", p.Name, j))
+			if err := addr.ValidateRule(r.Rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s.rule 无效: %w", field, err))
+			}
+			if r.Extract != "" {
+				if err := validateByteLength(field+".extract", r.Extract, MaxRuleBytes); err != nil {
+					errs = append(errs, err)
+				}
+				if r.GetType != "url" && r.GetType != "cmd" {
+					errs = append(errs, fmt.Errorf("%s.extract 只支持 url 和 cmd 获取方式", field))
+				} else if _, err := addr.NewExtractor(r.Extract); err != nil {
+					errs = append(errs, fmt.Errorf("%s.extract 无效: %w", field, err))
+				}
+			}
+			errs = append(errs, validatePolicy(r.Policy, field)...)
+			errs = append(errs, validatePrefixHosts(r, field)...)
+			errs = append(errs, validateFallbacks(r, field)...)
+			errs = append(errs, validateCommand(r, field)...)
+			errs = append(errs, validateHealth(r, field)...)
+			errs = append(errs, validatePublish(r, field, caps)...)
+			errs = append(errs, validateInterval(r, field)...)
+			if r.Quorum != 0 {
+				if r.GetType != "url" {
+					errs = append(errs, fmt.Errorf("%s.quorum 只支持 url 获取方式", field))
+				} else if urls := countURLs(r.GetValue); r.Quorum < 1 || r.Quorum > urls {
+					errs = append(errs, fmt.Errorf("%s.quorum 无效，请填写 1-%d（URL 数量）", field, urls))
+				}
 			}
 			if r.TTL != 0 && (r.TTL < 1 || r.TTL > 86400) {
 				errs = append(errs, fmt.Errorf("providers[%s].records[%d].ttl 无效，请填写 1-86400 秒", p.Name, j))
 			}
-			if r.Interval != 0 && (r.Interval < 10 || r.Interval > 60) {
-				errs = append(errs, fmt.Errorf("providers[%s].records[%d].interval 无效，请填写 10-60 秒", p.Name, j))
-			}
 			if r.GetType == "duid" && r.IPVersion != provider.IPv6 {
 				errs = append(errs, fmt.Errorf("providers[%s].records[%d].duid 仅支持 IPv6", p.Name, j))
 			}
+			if r.GetType == "duid" && r.GetValue != "" {
+				if _, err := addr.NewDuid(r.GetValue); err != nil {
+					errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", field, err))
+				}
+			}
+			if r.GetType == "mac" && r.GetValue != "" {
+				if _, err := addr.NewMac(r.GetValue); err != nil {
+					errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", field, err))
+				}
+			}
+			if r.GetType == "openwrt" && r.GetValue != "" {
+				if _, err := addr.NewOpenWrt(r.GetValue); err != nil {
+					errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", field, err))
+				}
+			}
 
 			// 检查record是否重名
 			if recordNames[r.Name] {
@@ -297,25 +477,36 @@ func (c *Config) NormalizeDomains() error {
 				}
 				record.SubDomains[k] = normalized
 			}
+			for k, host := range record.Hosts {
+				normalized, err := normalizedDomainName(host.SubDomain)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("providers[%s].records[%d].hosts: %w", c.Providers[i].Name, j, err))
+					continue
+				}
+				record.Hosts[k].SubDomain = normalized
+			}
 		}
 	}
 	return errors.Join(errs...)
 }
 
-var validProviderTypes = map[string]bool{
-	"aliyun":     true,
-	"baidu":      true,
-	"dnsla":      true,
-	"tencent":    true,
-	"huawei":     true,
-	"volcengine": true,
-}
-
 var validGetTypes = map[string]bool{
-	"cmd":  true,
-	"url":  true,
-	"nic":  true,
-	"duid": true,
+	"cmd": true,
+	"url": true,
+	"nic": true,
+	// openwrt 通过 ubus 读取 OpenWrt 逻辑接口的地址，getValue 为接口列表
+	"openwrt": true,
+	"duid":    true,
+	// mac 按 MAC 地址从邻居表、DHCP 租约或 EUI-64 查找局域网主机的 IP 地址
+	"mac": true,
+	// stun 通过 STUN 服务器获取 IP 地址，getValue 为服务器列表
+	"stun": true,
+	// dns 通过 DNS 查询获取 IP 地址，getValue 为查询列表
+	"dns": true,
+	// router 通过路由器的 UPnP IGD、NAT-PMP 或 PCP 获取 WAN 地址，getValue 为协议列表
+	"router": true,
+	// dyndns 接收客户端推送的 IP 地址，getValue 为客户端用户名
+	"dyndns": true,
 }
 
 func validateByteLength(field, value string, max int) error {
@@ -325,16 +516,31 @@ func validateByteLength(field, value string, max int) error {
 	return nil
 }
 
+// countURLs 返回英文逗号分隔的非空 URL 数量
+func countURLs(value string) int {
+	count := 0
+	for _, url := range strings.Split(value, ",") {
+		if strings.TrimSpace(url) != "" {
+			count++
+		}
+	}
+	return count
+}
+
 func maxGetValueBytes(getType string) int {
 	switch getType {
-	case "url":
+	case "url", "stun", "dns", "router":
 		return MaxURLBytes
 	case "cmd":
 		return MaxCommandBytes
-	case "nic":
+	case "nic", "openwrt":
 		return MaxNICBytes
 	case "duid":
 		return MaxDUIDBytes
+	case "mac":
+		return MaxMACBytes
+	case "dyndns":
+		return MaxUsernameBytes
 	default:
 		return MaxCommandBytes
 	}
@@ -345,20 +551,15 @@ func validateDomainName(value string) error {
 	return err
 }
 
-func domainVersionKey(domain string, version provider.Version) (string, error) {
-	normalized, err := normalizedDomainName(domain)
-	if err != nil {
-		return "", err
-	}
-	return normalized + "\x00" + strconv.Itoa(int(version)), nil
-}
+// domainProfile 在 idna.Lookup 的基础上允许下划线，SRV 记录的子域名形如 _sip._udp.example.com
+var domainProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
 
 func normalizedDomainName(value string) (string, error) {
 	value = strings.TrimSuffix(strings.TrimSpace(value), ".")
 	if value == "" {
 		return "", errors.New("域名不能为空")
 	}
-	asciiName, err := idna.Lookup.ToASCII(value)
+	asciiName, err := domainProfile.ToASCII(value)
 	if err != nil {
 		return "", fmt.Errorf("域名格式无效")
 	}
@@ -370,6 +571,14 @@ func normalizedDomainName(value string) (string, error) {
 		if len(label) == 0 || len(label) > MaxDomainLabelBytes {
 			return "", fmt.Errorf("域名标签长度不能超过 %d 字节", MaxDomainLabelBytes)
 		}
+		if strings.IndexFunc(label, invalidLabelRune) >= 0 {
+			return "", fmt.Errorf("域名格式无效")
+		}
 	}
 	return asciiName, nil
 }
+
+// invalidLabelRune 转换为 ASCII 后的域名标签只能包含字母、数字、连字符和下划线
+func invalidLabelRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+}
@@ -1,16 +1,20 @@
 package config
 
 import (
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"ddns/pkg/provider"
+	_ "ddns/pkg/provider/all"
 
 	"go.yaml.in/yaml/v3"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestDurationFieldsUnmarshalAsUnitIntegers(t *testing.T) {
@@ -143,8 +147,68 @@ func TestConfigValidateEnumerationsAndRanges(t *testing.T) {
 		{"get type", func(cfg *Config) { cfg.Providers[0].Records[0].GetType = "unknown" }, ".getType 无效"},
 		{"ttl", func(cfg *Config) { cfg.Providers[0].Records[0].TTL = 86401 }, ".ttl 无效"},
 		{"interval", func(cfg *Config) { cfg.Providers[0].Records[0].Interval = 61 }, ".interval 无效"},
+		{"nic interval", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType = "nic"
+			cfg.Providers[0].Records[0].Interval = MaxNotifyInterval + 1
+		}, ".interval 无效，请填写 10-3600 秒"},
+		{"nic interval with fallback", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType = "nic"
+			cfg.Providers[0].Records[0].Interval = 600
+			cfg.Providers[0].Records[0].Fallbacks = []Fallback{{GetType: "url"}}
+		}, ".interval 无效，请填写 10-60 秒"},
 		{"force interval", func(cfg *Config) { cfg.Providers[0].ForceInterval = 31 }, ".forceInterval 无效"},
 		{"duid ipv4", func(cfg *Config) { cfg.Providers[0].Records[0].GetType = "duid" }, "duid 仅支持 IPv6"},
+		{"quorum", func(cfg *Config) { cfg.Providers[0].Records[0].Quorum = 2 }, ".quorum 无效"},
+		{"policy prefix", func(cfg *Config) { cfg.Providers[0].Records[0].Policy.Exclude = []string{"2002::/129"} }, ".policy.exclude"},
+		{"policy ipv6", func(cfg *Config) { cfg.Providers[0].Records[0].Policy.IPv6 = "privacy" }, ".policy.ipv6 无效"},
+		{"extract rule", func(cfg *Config) { cfg.Providers[0].Records[0].Extract = "xpath@//ip" }, ".extract 无效"},
+		{"hosts ipv4", func(cfg *Config) {
+			cfg.Providers[0].Records[0].Hosts = []PrefixHost{{SubDomain: "nas.example.com", Suffix: "::1"}}
+		}, ".hosts 只支持"},
+		{"duid source", func(cfg *Config) {
+			cfg.Providers[0].Records[0].IPVersion, cfg.Providers[0].Records[0].GetType = provider.IPv6, "duid"
+			cfg.Providers[0].Records[0].GetValue = "000300019009d009781d@dhcpcd"
+		}, "不支持的租约来源"},
+		{"mac address", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType, cfg.Providers[0].Records[0].GetValue = "mac", "00:11:22:33:44@neigh"
+		}, "MAC 地址无效"},
+		{"openwrt interface", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType, cfg.Providers[0].Records[0].GetValue = "openwrt", "wan;reboot"
+		}, "接口名无效"},
+		{"fallback getType", func(cfg *Config) {
+			cfg.Providers[0].Records[0].Fallbacks = []Fallback{{GetType: "url", GetValue: "https://ip.example.com"}, {GetType: "dyndns", GetValue: "camera"}}
+		}, ".fallbacks[1].getType 无效"},
+		{"source timeout", func(cfg *Config) { cfg.Providers[0].Records[0].SourceTimeout = 120 }, ".sourceTimeout 无效"},
+		{"health port", func(cfg *Config) { cfg.Providers[0].Records[0].Health = HealthCheck{Type: HealthTCP} }, ".health.port 不能为空"},
+		{"health http options", func(cfg *Config) {
+			cfg.Providers[0].Records[0].Health = HealthCheck{Type: HealthTCP, Port: 443, Path: "/healthz"}
+		}, "只支持 http 检查"},
+		{"publish rule", func(cfg *Config) {
+			cfg.Providers[0].Records[0].Publish, cfg.Providers[0].Records[0].Rule = PublishAll, "index@1"
+		}, ".rule 不能与 publish: all 同时使用"},
+		{"publish update-only provider", func(cfg *Config) {
+			cfg.Providers[0].Provider, cfg.Providers[0].Server = "dyndns2", "https://dynupdate.example.com/nic/update"
+			cfg.Providers[0].Records[0].Publish = PublishAll
+		}, ".publish: all 需要服务商支持查询、创建和删除记录"},
+		{"publish value", func(cfg *Config) { cfg.Providers[0].Records[0].Publish = "round-robin" }, ".publish 无效"},
+		{"health path", func(cfg *Config) {
+			cfg.Providers[0].Records[0].Health = HealthCheck{Type: HealthHTTP, Path: "healthz", Status: 204}
+		}, ".health.path 必须以 / 开头"},
+		{"command args", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType, cfg.Providers[0].Records[0].GetValue = "cmd", "ip addr"
+			cfg.Providers[0].Records[0].Command = CommandSpec{Args: []string{"ip", "addr"}}
+		}, "getValue 和 command.args 只能填写一个"},
+		{"command timeout over source timeout", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType, cfg.Providers[0].Records[0].GetValue = "cmd", ""
+			cfg.Providers[0].Records[0].Command = CommandSpec{Args: []string{"/usr/bin/get-ip"}, Timeout: 30}
+			cfg.Providers[0].Records[0].Fallbacks = []Fallback{{GetType: "url", GetValue: "https://ip.example.com"}}
+		}, "command.timeout 不能超过获取方式的超时时间 sourceTimeout（10 秒）"},
+		{"command env", func(cfg *Config) {
+			cfg.Providers[0].Records[0].GetType, cfg.Providers[0].Records[0].GetValue = "cmd", ""
+			cfg.Providers[0].Records[0].Command = CommandSpec{Args: []string{"ip", "addr"}, Env: []string{"LANG"}}
+		}, "command.env[0] 格式无效"},
+		{"prefix length", func(cfg *Config) { cfg.Providers[0].Records[0].PrefixLength = 40 }, ".prefixLength 无效"},
+		{"rule typo", func(cfg *Config) { cfg.Providers[0].Records[0].Rule = "nodeprecate,index@1" }, ".rule 无效"},
 	}
 
 	for _, tt := range tests {
@@ -158,6 +222,108 @@ func TestConfigValidateEnumerationsAndRanges(t *testing.T) {
 	}
 }
 
+func TestRecordCheckIntervalAllowsLongIntervalForNotifiers(t *testing.T) {
+	cfg := validConfig()
+	record := &cfg.Providers[0].Records[0]
+	record.GetType, record.GetValue, record.Interval = "nic", "eth0", 600
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := record.CheckInterval(); got != 10*time.Minute {
+		t.Fatalf("CheckInterval() = %v, want 10m", got)
+	}
+	record.GetType = "url"
+	if got := record.CheckInterval(); got != DefaultInterval*time.Second {
+		t.Fatalf("CheckInterval() = %v, want default for polling records", got)
+	}
+}
+
+func TestConfigValidateCloudflareOnlyNeedsToken(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers[0].Provider = "cloudflare"
+	cfg.Providers[0].KeyID = ""
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cfg.Providers[0].KeySecret = ""
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "keySecret") {
+		t.Fatalf("Validate() error = %v, want keySecret error", err)
+	}
+}
+
+func TestConfigValidateRFC2136RequiresServer(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers[0].Provider = "rfc2136"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server") {
+		t.Fatalf("Validate() error = %v, want server error", err)
+	}
+	cfg.Providers[0].Server = "ns1.example.com:53"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	data, err := yaml.Marshal(cfg.Providers[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Provider
+	if err := yaml.Unmarshal(data, &decoded); err != nil || decoded.Server != "ns1.example.com:53" {
+		t.Fatalf("server round trip = %q, %v", decoded.Server, err)
+	}
+}
+
+func TestConfigValidateDynDNSRecordRequiresClient(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers[0].Records[0].GetType = "dyndns"
+	cfg.Providers[0].Records[0].GetValue = "camera"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "DynDNS 客户端") {
+		t.Fatalf("Validate() error = %v, want unknown client error", err)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DynDNSClients = []DynDNSClient{{Username: "camera", PasswordHash: string(hash)}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cfg.DynDNSClients = append(cfg.DynDNSClients, DynDNSClient{Username: "camera", PasswordHash: string(hash)})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "重复") {
+		t.Fatalf("Validate() error = %v, want duplicate client error", err)
+	}
+	// 明文密码不是有效的 bcrypt 哈希
+	cfg.DynDNSClients = []DynDNSClient{{Username: "camera", PasswordHash: "secret"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "dyndnsClients[0].passwordHash 不是有效的 bcrypt 哈希") {
+		t.Fatalf("Validate() error = %v, want invalid hash error", err)
+	}
+}
+
+func TestRecordProxiedRoundTrip(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(`
+providers:
+  - name: cf
+    provider: cloudflare
+    keySecret: token
+    records:
+      - name: nas
+        proxied: false
+      - name: web
+`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	records := cfg.Providers[0].Records
+	if records[0].Proxied == nil || *records[0].Proxied || records[1].Proxied != nil {
+		t.Fatalf("proxied = %v, %v", records[0].Proxied, records[1].Proxied)
+	}
+	data, err := yaml.Marshal(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "proxied:") != 1 {
+		t.Fatalf("unset proxied should be omitted:\n%s", data)
+	}
+}
+
 func validConfig() Config {
 	return Config{
 		Providers: []Provider{{
@@ -168,6 +334,48 @@ func validConfig() Config {
 	}
 }
 
+func TestConfigValidatePrefixHosts(t *testing.T) {
+	cfg := validConfig()
+	record := &cfg.Providers[0].Records[0]
+	record.IPVersion = provider.IPv6
+	record.SubDomains = []string{"router.example.com", "nas.example.com", "printer.example.com"}
+	record.PrefixLength = 56
+	record.Hosts = []PrefixHost{
+		{SubDomain: "NAS.example.com.", Suffix: "::10"},
+		{SubDomain: "printer.example.com", MAC: "00:11:22:33:44:55", Subnet: 1},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := record.Hosts[0].SubDomain; got != "nas.example.com" {
+		t.Fatalf("host subdomain = %q, want normalized", got)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Record)
+		want   string
+	}{
+		{"unknown subdomain", func(r *Record) { r.Hosts[0].SubDomain = "tv.example.com" }, "不在 subDomains 中"},
+		{"duplicate", func(r *Record) { r.Hosts[1].SubDomain = "nas.example.com" }, "重复"},
+		{"suffix and mac", func(r *Record) { r.Hosts[0].MAC = "00:11:22:33:44:66" }, "只能填写一个"},
+		{"subnet range", func(r *Record) { r.Hosts[1].Subnet = 256 }, "超出 /56"},
+		{"subnet without delegation", func(r *Record) { r.PrefixLength = 64 }, "超出 /64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			clone := *record
+			clone.Hosts = slices.Clone(record.Hosts)
+			tt.mutate(&clone)
+			cfg.Providers[0].Records[0] = clone
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
 func TestValidateDomainNameRejectsOverlongName(t *testing.T) {
 	value := strings.Repeat("a.", 126) + "com"
 	if err := validateDomainName(value); err == nil {
@@ -231,14 +439,79 @@ func TestConfigValidateRejectsDuplicateDomainAndVersion(t *testing.T) {
 	}
 }
 
+func TestConfigValidateRecordTypes(t *testing.T) {
+	static := func(recordType, value string) Record {
+		return Record{Name: "static-" + recordType, SubDomains: []string{"static.example.com"}, Type: recordType, Value: value, TTL: 600}
+	}
+	tests := []struct {
+		name   string
+		record Record
+		want   string
+	}{
+		{name: "static txt", record: static("TXT", "v=spf1 -all")},
+		{name: "templated txt", record: Record{Name: "ip", SubDomains: []string{"ip.example.com"}, Type: "TXT", Value: "ip={{.IP}}", IPVersion: provider.IPv4, GetType: "url", GetValue: "https://example.com"}},
+		{name: "static txt uses ip", record: static("TXT", "ip={{.IP}}"), want: "请配置 getType"},
+		{name: "bad template", record: static("TXT", "{{.IP"), want: "模板无效"},
+		{name: "mx", record: Record{Name: "mx", SubDomains: []string{"example.com"}, Type: "MX", Value: "mail.example.com", Priority: 10}},
+		{name: "srv without port", record: static("SRV", "mc.example.com"), want: "port 无效"},
+		{name: "srv service name", record: Record{Name: "sip", SubDomains: []string{"_sip._udp.example.com"}, Type: "SRV", Value: "sip.example.com", Priority: 10, Weight: 5, Port: 5060}},
+		{name: "invalid domain characters", record: Record{Name: "space", SubDomains: []string{"bad name.example.com"}, Type: "TXT", Value: "text"}, want: "域名格式无效"},
+		{name: "caa format", record: static("CAA", "issue letsencrypt.org"), want: "CAA"},
+		{name: "unknown type", record: static("NS", "ns1.example.com"), want: "type 无效"},
+		{name: "missing value", record: static("CNAME", ""), want: "value 不能为空"},
+		{name: "cname conflicts", record: Record{Name: "alias", SubDomains: []string{"nas.example.com"}, Type: "CNAME", Value: "target.example.com"}, want: "CNAME"},
+		{name: "address type mismatch", record: Record{Name: "v6", SubDomains: []string{"v6.example.com"}, Type: "AAAA", IPVersion: provider.IPv4, GetType: "url", GetValue: "https://example.com"}, want: "不一致"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.Providers[0].Records = append(cfg.Providers[0].Records, tt.record)
+			err := cfg.Validate()
+			if tt.want == "" && err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordRenderValue(t *testing.T) {
+	record := Record{Type: "txt", Value: "host={{.SubDomain}} ip={{.IP}}"}
+	value, err := record.RenderValue("nas.example.com", netip.MustParseAddr("1.2.3.4"))
+	if err != nil || value != "host=nas.example.com ip=1.2.3.4" {
+		t.Fatalf("RenderValue() = %q, %v", value, err)
+	}
+	if record.RecordType() != "TXT" || record.IsAddress() {
+		t.Fatalf("RecordType() = %q, IsAddress() = %v", record.RecordType(), record.IsAddress())
+	}
+	address := Record{IPVersion: provider.IPv6}
+	if value, _ := address.RenderValue("nas.example.com", netip.MustParseAddr("2001:db8::1")); value != "2001:db8::1" || address.RecordType() != "AAAA" {
+		t.Fatalf("address RenderValue() = %q, type %q", value, address.RecordType())
+	}
+}
+
 func TestCloneConfigDeepCopiesSubDomains(t *testing.T) {
 	cfg := validConfig()
+	cfg.Providers[0].Records[0].Policy = AddrPolicy{Include: []string{"2001:db8::/32"}, Exclude: []string{"2001:db8:ff::/48"}}
+	proxied := true
+	cfg.Providers[0].Records[0].Proxied = &proxied
 	clone := cloneConfig(&cfg)
+	*clone.Providers[0].Records[0].Proxied = false
 	clone.Providers[0].Records[0].SubDomains[0] = "changed.example.com"
+	clone.Providers[0].Records[0].Policy.Include[0] = "0.0.0.0/0"
+	clone.Providers[0].Records[0].Policy.Exclude[0] = "0.0.0.0/0"
 
 	if cfg.Providers[0].Records[0].SubDomains[0] != "nas.example.com" {
 		t.Fatalf("source subdomain was mutated: %q", cfg.Providers[0].Records[0].SubDomains[0])
 	}
+	if policy := cfg.Providers[0].Records[0].Policy; policy.Include[0] != "2001:db8::/32" || policy.Exclude[0] != "2001:db8:ff::/48" {
+		t.Fatalf("source policy was mutated: %#v", policy)
+	}
+	if !*cfg.Providers[0].Records[0].Proxied {
+		t.Fatal("source proxied was mutated")
+	}
 }
 
 func TestManagerCallbacksAllowReentry(t *testing.T) {
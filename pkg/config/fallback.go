@@ -0,0 +1,110 @@
+package config
+
+import (
+	"ddns/pkg/addr"
+	"ddns/pkg/provider"
+	"fmt"
+	"time"
+)
+
+// 备用获取方式
+// 记录按 getType、fallbacks 的顺序依次获取IP地址，某个获取方式失败、超时或没有符合地址策略的地址时尝试下一个，
+// 全部失败才算一次获取失败。提取规则、一致性数量和结构化命令只对主获取方式生效
+
+const (
+	// MaxFallbacks 备用获取方式的最大数量
+	MaxFallbacks = 8
+	// DefaultSourceTimeout 配置了备用获取方式时，每个获取方式的默认超时时间
+	DefaultSourceTimeout = 10 * time.Second
+)
+
+// Fallback 备用获取方式
+type Fallback struct {
+	// 获取IP地址的类型，不支持 dyndns
+	GetType string `yaml:"getType" mapstructure:"getType"`
+	// 对应的值，router 方式可以为空
+	GetValue string `yaml:"getValue" mapstructure:"getValue"`
+}
+
+// Sources 返回主获取方式和备用获取方式，按尝试顺序排列
+func (r Record) Sources() []Fallback {
+	if r.GetType == "" {
+		return nil
+	}
+	return append([]Fallback{{GetType: r.GetType, GetValue: r.GetValue}}, r.Fallbacks...)
+}
+
+// NicInterfaces 返回 nic 获取方式读取的网卡名，按尝试顺序排列，读取地址属性时优先使用这些网卡上的属性
+func (r Record) NicInterfaces() []string {
+	var ifaces []string
+	for _, source := range r.Sources() {
+		if source.GetType == "nic" && source.GetValue != "" {
+			ifaces = append(ifaces, source.GetValue)
+		}
+	}
+	return ifaces
+}
+
+// FetchTimeout 返回每个获取方式的超时时间，为 0 时不限制
+func (r Record) FetchTimeout() time.Duration {
+	if r.SourceTimeout > 0 {
+		return time.Duration(r.SourceTimeout) * time.Second
+	}
+	if len(r.Fallbacks) > 0 {
+		return DefaultSourceTimeout
+	}
+	return 0
+}
+
+// validateFallbacks 检查备用获取方式，field 为记录的字段路径
+func validateFallbacks(r Record, field string) []error {
+	var errs []error
+	if r.SourceTimeout != 0 && (r.SourceTimeout < 1 || r.SourceTimeout > 60) {
+		errs = append(errs, fmt.Errorf("%s.sourceTimeout 无效，请填写 1-60 秒", field))
+	}
+	if len(r.Fallbacks) == 0 {
+		return errs
+	}
+	if !r.NeedsAddr() || r.GetType == "" {
+		errs = append(errs, fmt.Errorf("%s.fallbacks 只支持获取IP地址的记录", field))
+		return errs
+	}
+	// 推送方式在客户端推送前没有地址，不能判断是否需要切换
+	if r.GetType == "dyndns" {
+		errs = append(errs, fmt.Errorf("%s.fallbacks 不支持 dyndns 获取方式", field))
+	}
+	if len(r.Fallbacks) > MaxFallbacks {
+		errs = append(errs, fmt.Errorf("%s.fallbacks 数量不能超过 %d 个", field, MaxFallbacks))
+	}
+	for i, fb := range r.Fallbacks {
+		prefix := fmt.Sprintf("%s.fallbacks[%d]", field, i)
+		if !validGetTypes[fb.GetType] || fb.GetType == "dyndns" {
+			errs = append(errs, fmt.Errorf("%s.getType 无效，请填写 cmd、url、nic、openwrt、duid、mac、stun、dns 或 router", prefix))
+			continue
+		}
+		if fb.GetValue == "" && fb.GetType != "router" {
+			errs = append(errs, fmt.Errorf("%s.getValue 不能为空", prefix))
+		}
+		if err := validateByteLength(prefix+".getValue", fb.GetValue, maxGetValueBytes(fb.GetType)); err != nil {
+			errs = append(errs, err)
+		}
+		switch fb.GetType {
+		case "duid":
+			if r.IPVersion != provider.IPv6 {
+				errs = append(errs, fmt.Errorf("%s.duid 仅支持 IPv6", prefix))
+			}
+			if _, err := addr.NewDuid(fb.GetValue); fb.GetValue != "" && err != nil {
+				errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", prefix, err))
+			}
+		case "mac":
+			if _, err := addr.NewMac(fb.GetValue); fb.GetValue != "" && err != nil {
+				errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", prefix, err))
+			}
+		case "openwrt":
+			if _, err := addr.NewOpenWrt(fb.GetValue); fb.GetValue != "" && err != nil {
+				errs = append(errs, fmt.Errorf("%s.getValue 无效: %w", prefix, err))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 健康检查
+// 配置后记录把各个获取方式得到的地址作为候选地址，按顺序检查，发布第一个检查通过的地址；
+// 正在使用的地址检查失败时自动切换到下一个可用的候选地址。publish 为 all 时发布所有检查通过的地址
+
+const (
+	// HealthTCP 连接候选地址的端口
+	HealthTCP = "tcp"
+	// HealthHTTP 向候选地址发送 HTTP GET 请求并检查状态码
+	HealthHTTP = "http"
+	// DefaultHealthTimeout 健康检查的默认超时时间
+	DefaultHealthTimeout = 3 * time.Second
+	// MaxHealthTimeout health.timeout 的最大值，单位秒
+	MaxHealthTimeout = 30
+)
+
+// HealthCheck 候选地址的健康检查，零值表示不检查
+type HealthCheck struct {
+	// 检查方式，tcp 或 http
+	Type string `yaml:"type,omitempty" mapstructure:"type"`
+	// 端口，tcp 必填，http 为 0 时使用 80
+	Port int `yaml:"port,omitempty" mapstructure:"port"`
+	// HTTP 请求路径，为空时为 /
+	Path string `yaml:"path,omitempty" mapstructure:"path"`
+	// HTTP 请求的 Host 头，为空时使用候选地址
+	Host string `yaml:"host,omitempty" mapstructure:"host"`
+	// 期望的 HTTP 状态码，为 0 时为 200
+	Status int `yaml:"status,omitempty" mapstructure:"status"`
+	// 每个候选地址的超时时间，单位秒，为 0 时为 3 秒
+	Timeout int `yaml:"timeout,omitempty" mapstructure:"timeout"`
+}
+
+// IsZero 是否没有配置健康检查
+func (h HealthCheck) IsZero() bool {
+	return h == HealthCheck{}
+}
+
+// CheckTimeout 返回每个候选地址的检查超时时间
+func (h HealthCheck) CheckTimeout() time.Duration {
+	if h.Timeout > 0 {
+		return time.Duration(h.Timeout) * time.Second
+	}
+	return DefaultHealthTimeout
+}
+
+// validateHealth 检查健康检查配置，field 为记录的字段路径
+func validateHealth(r Record, field string) []error {
+	h := r.Health
+	if h.IsZero() {
+		return nil
+	}
+	var errs []error
+	if !r.NeedsAddr() || r.GetType == "" {
+		errs = append(errs, fmt.Errorf("%s.health 只支持获取IP地址的记录", field))
+	}
+	switch h.Type {
+	case HealthTCP:
+		if h.Port == 0 {
+			errs = append(errs, fmt.Errorf("%s.health.port 不能为空", field))
+		}
+		if h.Path != "" || h.Host != "" || h.Status != 0 {
+			errs = append(errs, fmt.Errorf("%s.health 的 path、host、status 只支持 http 检查", field))
+		}
+	case HealthHTTP:
+		if h.Path != "" && !strings.HasPrefix(h.Path, "/") {
+			errs = append(errs, fmt.Errorf("%s.health.path 必须以 / 开头", field))
+		}
+		if err := validateByteLength(field+".health.path", h.Path, MaxURLBytes); err != nil {
+			errs = append(errs, err)
+		}
+		if err := validateByteLength(field+".health.host", h.Host, MaxDomainBytes); err != nil {
+			errs = append(errs, err)
+		}
+		if strings.ContainsAny(h.Path+h.Host, " \t\r\n") {
+			errs = append(errs, fmt.Errorf("%s.health 的 path、host 不能包含空白字符", field))
+		}
+		if h.Status != 0 && (h.Status < 100 || h.Status > 599) {
+			errs = append(errs, fmt.Errorf("%s.health.status 无效，请填写 100-599", field))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.health.type 无效，请填写 tcp 或 http", field))
+	}
+	if h.Port < 0 || h.Port > 65535 {
+		errs = append(errs, fmt.Errorf("%s.health.port 无效，请填写 1-65535", field))
+	}
+	if h.Timeout != 0 && (h.Timeout < 1 || h.Timeout > MaxHealthTimeout) {
+		errs = append(errs, fmt.Errorf("%s.health.timeout 无效，请填写 1-%d 秒", field, MaxHealthTimeout))
+	}
+	return errs
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// 检测周期
+// 网卡、DynDNS 推送方式可以主动通知地址变化，定时检测只作为兜底，允许配置更长的检测周期
+
+const (
+	// DefaultInterval 默认检测周期，单位秒
+	DefaultInterval = 30
+	// MinInterval 检测周期的最小值，单位秒
+	MinInterval = 10
+	// MaxPollInterval 需要定时检测地址变化的记录的检测周期最大值，单位秒
+	MaxPollInterval = 60
+	// MaxNotifyInterval 主获取方式可以主动通知地址变化的记录的检测周期最大值，单位秒
+	MaxNotifyInterval = 3600
+)
+
+// NotifiesChanges 主获取方式是否可以主动通知地址变化
+// 备用获取方式和健康检查都需要定时检测，配置后按普通记录处理
+func (r Record) NotifiesChanges() bool {
+	return (r.GetType == "nic" || r.GetType == "dyndns") && len(r.Fallbacks) == 0 && r.Health.IsZero()
+}
+
+// MaxInterval 返回记录允许的检测周期最大值，单位秒
+func (r Record) MaxInterval() int64 {
+	if r.NotifiesChanges() {
+		return MaxNotifyInterval
+	}
+	return MaxPollInterval
+}
+
+// CheckInterval 返回检测周期，没有配置或超出范围时为 30 秒
+func (r Record) CheckInterval() time.Duration {
+	if r.Interval < MinInterval || r.Interval > r.MaxInterval() {
+		return DefaultInterval * time.Second
+	}
+	return time.Duration(r.Interval) * time.Second
+}
+
+// validateInterval 检查检测周期，field 为记录的字段路径
+func validateInterval(r Record, field string) []error {
+	if r.Interval == 0 || (r.Interval >= MinInterval && r.Interval <= r.MaxInterval()) {
+		return nil
+	}
+	if r.NotifiesChanges() {
+		return []error{fmt.Errorf("%s.interval 无效，请填写 %d-%d 秒", field, MinInterval, MaxNotifyInterval)}
+	}
+	return []error{fmt.Errorf("%s.interval 无效，请填写 %d-%d 秒，只有没有备用获取方式和健康检查的 nic、dyndns 记录可以超过 %d 秒", field, MinInterval, MaxPollInterval, MaxPollInterval)}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"ddns/pkg/addr"
+	"fmt"
+)
+
+// MaxPolicyPrefixes include、exclude 列表的最大数量
+const MaxPolicyPrefixes = 64
+
+// AddrPolicy 记录的地址策略，零值表示只接受公网地址
+type AddrPolicy struct {
+	// 只接受这些网段内的地址，为空时不限制
+	Include []string `yaml:"include,omitempty" mapstructure:"include"`
+	// 排除这些网段内的地址
+	Exclude []string `yaml:"exclude,omitempty" mapstructure:"exclude"`
+	// 允许 IPv4 私网地址
+	AllowPrivate bool `yaml:"allowPrivate,omitempty" mapstructure:"allowPrivate"`
+	// 允许 IPv6 唯一本地地址（fc00::/7）
+	AllowULA bool `yaml:"allowULA,omitempty" mapstructure:"allowULA"`
+	// 允许运营商级 NAT 地址（100.64.0.0/10）
+	AllowCGNAT bool `yaml:"allowCGNAT,omitempty" mapstructure:"allowCGNAT"`
+	// IPv6 地址偏好，stable 排除临时地址，temporary 只使用临时地址，为空时不区分
+	IPv6 string `yaml:"ipv6,omitempty" mapstructure:"ipv6"`
+}
+
+// Build 解析网段列表，返回 addr 包使用的地址策略
+func (p AddrPolicy) Build() (addr.Policy, error) {
+	include, err := addr.ParsePrefixes(p.Include)
+	if err != nil {
+		return addr.Policy{}, fmt.Errorf("include %w", err)
+	}
+	exclude, err := addr.ParsePrefixes(p.Exclude)
+	if err != nil {
+		return addr.Policy{}, fmt.Errorf("exclude %w", err)
+	}
+	switch p.IPv6 {
+	case addr.IPv6Any, addr.IPv6Stable, addr.IPv6Temporary:
+	default:
+		return addr.Policy{}, fmt.Errorf("ipv6 无效，请填写 stable 或 temporary")
+	}
+	return addr.Policy{
+		Include: include, Exclude: exclude,
+		AllowPrivate: p.AllowPrivate, AllowULA: p.AllowULA, AllowCGNAT: p.AllowCGNAT,
+		IPv6: p.IPv6,
+	}, nil
+}
+
+// validatePolicy 检查地址策略，field 为记录的字段路径
+func validatePolicy(p AddrPolicy, field string) []error {
+	var errs []error
+	if len(p.Include) > MaxPolicyPrefixes || len(p.Exclude) > MaxPolicyPrefixes {
+		errs = append(errs, fmt.Errorf("%s.policy 的网段数量不能超过 %d 个", field, MaxPolicyPrefixes))
+	}
+	if _, err := p.Build(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.policy.%w", field, err))
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"ddns/pkg/addr"
+	"ddns/pkg/provider"
+	"fmt"
+	"slices"
+)
+
+// 前缀跟踪
+// 配置了 hosts 的 AAAA 记录只获取一次地址，取前 prefixLength 位作为委派前缀，
+// hosts 中的子域名使用委派前缀拼接各自的接口标识，其他子域名仍使用获取到的地址
+
+// MaxPrefixHosts hosts 的最大数量
+const MaxPrefixHosts = 256
+
+// PrefixHost 前缀跟踪的主机，suffix 和 mac 填写一个
+type PrefixHost struct {
+	// 子域名，必须在记录的 subDomains 中
+	SubDomain string `yaml:"subDomain" mapstructure:"subDomain"`
+	// 静态接口标识，如 ::10、::9209:d0ff:fe09:781d
+	Suffix string `yaml:"suffix,omitempty" mapstructure:"suffix"`
+	// MAC 地址，按 EUI-64 生成接口标识
+	MAC string `yaml:"mac,omitempty" mapstructure:"mac"`
+	// 子网序号，委派前缀短于 /64 时选择第几个 /64 子网，从0开始计数
+	Subnet int `yaml:"subnet,omitempty" mapstructure:"subnet"`
+}
+
+// PrefixBits 返回委派前缀长度，没有配置时为 64
+func (r Record) PrefixBits() int {
+	if r.PrefixLength == 0 {
+		return addr.MaxPrefixLength
+	}
+	return r.PrefixLength
+}
+
+// BuildHosts 返回子域名对应的前缀跟踪主机，没有配置 hosts 时返回 nil
+func (r Record) BuildHosts() (map[string]addr.Host, error) {
+	if len(r.Hosts) == 0 {
+		return nil, nil
+	}
+	hosts := make(map[string]addr.Host, len(r.Hosts))
+	for i, h := range r.Hosts {
+		host, err := addr.NewHost(h.Suffix, h.MAC, h.Subnet)
+		if err == nil {
+			err = host.CheckSubnet(r.PrefixBits())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("hosts[%d] %w", i, err)
+		}
+		hosts[h.SubDomain] = host
+	}
+	return hosts, nil
+}
+
+// validatePrefixHosts 检查前缀跟踪配置，field 为记录的字段路径
+func validatePrefixHosts(r Record, field string) []error {
+	if r.PrefixLength == 0 && len(r.Hosts) == 0 {
+		return nil
+	}
+	var errs []error
+	if r.RecordType() != provider.TypeAAAA || r.GetType == "" {
+		errs = append(errs, fmt.Errorf("%s.hosts 只支持获取 IPv6 地址的 AAAA 记录", field))
+	}
+	if r.PrefixLength != 0 && (r.PrefixLength < addr.MinPrefixLength || r.PrefixLength > addr.MaxPrefixLength) {
+		errs = append(errs, fmt.Errorf("%s.prefixLength 无效，请填写 %d-%d", field, addr.MinPrefixLength, addr.MaxPrefixLength))
+		return errs
+	}
+	if len(r.Hosts) > MaxPrefixHosts {
+		errs = append(errs, fmt.Errorf("%s.hosts 数量不能超过 %d 个", field, MaxPrefixHosts))
+	}
+	seen := make(map[string]bool, len(r.Hosts))
+	for i, h := range r.Hosts {
+		if !slices.Contains(r.SubDomains, h.SubDomain) {
+			errs = append(errs, fmt.Errorf("%s.hosts[%d].subDomain 不在 subDomains 中: %s", field, i, h.SubDomain))
+		}
+		if seen[h.SubDomain] {
+			errs = append(errs, fmt.Errorf("%s.hosts[%d].subDomain 重复: %s", field, i, h.SubDomain))
+		}
+		seen[h.SubDomain] = true
+	}
+	if _, err := r.BuildHosts(); err != nil {
+		errs = append(errs, fmt.Errorf("%s.%w", field, err))
+	}
+	return errs
+}
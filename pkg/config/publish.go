@@ -0,0 +1,48 @@
+package config
+
+import (
+	"ddns/pkg/provider"
+	"fmt"
+)
+
+// 多值记录
+// publish 为 all 时记录发布获取到的所有地址，云端的同名 A、AAAA 记录按地址集合维护：
+// 创建缺少的地址，删除多余的地址，已有的地址保持不变
+
+const (
+	// PublishAll 发布获取到的所有地址
+	PublishAll = "all"
+	// MaxPublishAddrs 多值记录每个子域名最多发布的地址数量
+	MaxPublishAddrs = 16
+)
+
+// PublishesSet 是否按地址集合发布多值记录
+func (r Record) PublishesSet() bool {
+	return r.Publish == PublishAll
+}
+
+// validatePublish 检查发布方式，field 为记录的字段路径，caps 为服务商实例声明的能力
+func validatePublish(r Record, field string, caps provider.Capability) []error {
+	if r.Publish == "" {
+		return nil
+	}
+	if r.Publish != PublishAll {
+		return []error{fmt.Errorf("%s.publish 无效，请填写 all 或留空", field)}
+	}
+	var errs []error
+	if !r.IsAddress() || r.GetType == "" {
+		errs = append(errs, fmt.Errorf("%s.publish 只支持获取IP地址的 A、AAAA 记录", field))
+	}
+	// 规则只选出一个地址，发布所有地址时不使用
+	if r.Rule != "" {
+		errs = append(errs, fmt.Errorf("%s.rule 不能与 publish: all 同时使用，所有符合地址策略的地址都会发布", field))
+	}
+	if len(r.Hosts) > 0 {
+		errs = append(errs, fmt.Errorf("%s.hosts 不能与 publish: all 同时使用", field))
+	}
+	// 按地址集合维护记录需要查询、创建和删除记录，只能推送更新的服务商（如 dyndns2）不支持
+	if !caps.Has(provider.CapQuery | provider.CapCreate | provider.CapDelete) {
+		errs = append(errs, fmt.Errorf("%s.publish: all 需要服务商支持查询、创建和删除记录，当前服务商只能更新记录", field))
+	}
+	return errs
+}
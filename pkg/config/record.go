@@ -0,0 +1,139 @@
+package config
+
+import (
+	"ddns/pkg/provider"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// 记录类型和记录值模板
+// A、AAAA 记录的值是获取到的 IP 地址；CNAME、TXT、MX、SRV、CAA 记录的值由 value 模板生成，
+// 模板可以引用 {{.IP}}（getType 获取到的 IP 地址）和 {{.SubDomain}}（当前子域名），
+// 没有配置 getType 的记录为静态记录，模板不能引用 {{.IP}}
+
+const MaxRecordValueBytes = 1024
+
+// ValueData 记录值模板可以引用的数据
+type ValueData struct {
+	// IP 获取到的 IP 地址，静态记录为空
+	IP string
+	// SubDomain 当前同步的子域名
+	SubDomain string
+}
+
+// RecordType 返回记录类型，没有配置 type 时按 ipVersion 返回 A 或 AAAA
+func (r Record) RecordType() string {
+	if r.Type != "" {
+		return strings.ToUpper(r.Type)
+	}
+	return r.IPVersion.RecordType()
+}
+
+// IsAddress 是否为 A 或 AAAA 记录，没有配置 type 的记录都是地址记录
+func (r Record) IsAddress() bool {
+	switch strings.ToUpper(r.Type) {
+	case "", provider.TypeA, provider.TypeAAAA:
+		return true
+	default:
+		return false
+	}
+}
+
+// NeedsAddr 是否需要获取 IP 地址：地址记录，或者配置了 getType 的其他类型记录
+func (r Record) NeedsAddr() bool {
+	return r.IsAddress() || r.GetType != ""
+}
+
+// RenderValue 生成子域名的记录值，地址记录直接返回 IP 地址
+func (r Record) RenderValue(subDomain string, addr netip.Addr) (string, error) {
+	if r.IsAddress() {
+		return addr.String(), nil
+	}
+	tmpl, err := parseValueTemplate(r.Value)
+	if err != nil {
+		return "", err
+	}
+	data := ValueData{SubDomain: subDomain}
+	if addr.IsValid() {
+		data.IP = addr.String()
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("生成记录值失败: %w", err)
+	}
+	value := strings.TrimSpace(b.String())
+	if value == "" {
+		return "", fmt.Errorf("生成的记录值为空")
+	}
+	return value, nil
+}
+
+func parseValueTemplate(value string) (*template.Template, error) {
+	tmpl, err := template.New("value").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("记录值模板无效: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateUsesIP 模板的输出是否依赖 {{.IP}}
+func templateUsesIP(tmpl *template.Template) bool {
+	var withoutIP, withIP strings.Builder
+	if tmpl.Execute(&withoutIP, ValueData{SubDomain: "example.com"}) != nil {
+		return false
+	}
+	if tmpl.Execute(&withIP, ValueData{IP: "192.0.2.1", SubDomain: "example.com"}) != nil {
+		return true
+	}
+	return withoutIP.String() != withIP.String()
+}
+
+// validateRecordType 检查记录类型、记录值模板以及 MX、SRV 的附加字段
+func validateRecordType(r Record, field string) []error {
+	var errs []error
+	if r.Type != "" && !slices.Contains(provider.RecordTypes, strings.ToUpper(r.Type)) {
+		return []error{fmt.Errorf("%s.type 无效，请填写 %s", field, strings.Join(provider.RecordTypes, "、"))}
+	}
+	recordType := r.RecordType()
+	if r.IsAddress() {
+		if r.Type != "" && provider.VersionOf(r.Type) != r.IPVersion {
+			errs = append(errs, fmt.Errorf("%s.ipVersion 与 type 不一致", field))
+		}
+		if r.Value != "" {
+			errs = append(errs, fmt.Errorf("%s.value 仅用于 CNAME、TXT、MX、SRV、CAA 记录", field))
+		}
+		return errs
+	}
+
+	if strings.TrimSpace(r.Value) == "" {
+		errs = append(errs, fmt.Errorf("%s.value 不能为空", field))
+	}
+	if err := validateByteLength(field+".value", r.Value, MaxRecordValueBytes); err != nil {
+		errs = append(errs, err)
+	}
+	tmpl, err := parseValueTemplate(r.Value)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s.value: %w", field, err))
+	} else if r.GetType == "" && templateUsesIP(tmpl) {
+		errs = append(errs, fmt.Errorf("%s.value 引用了 {{.IP}}，请配置 getType", field))
+	}
+	// 不含模板的 CAA 记录值可以提前检查格式
+	if recordType == provider.TypeCAA && !strings.Contains(r.Value, "{{") {
+		if _, _, _, err := provider.ParseCAA(r.Value); err != nil {
+			errs = append(errs, fmt.Errorf("%s.value: %w", field, err))
+		}
+	}
+	if r.Priority < 0 || r.Priority > 65535 {
+		errs = append(errs, fmt.Errorf("%s.priority 无效，请填写 0-65535", field))
+	}
+	if r.Weight < 0 || r.Weight > 65535 {
+		errs = append(errs, fmt.Errorf("%s.weight 无效，请填写 0-65535", field))
+	}
+	if recordType == provider.TypeSRV && (r.Port < 1 || r.Port > 65535) {
+		errs = append(errs, fmt.Errorf("%s.port 无效，请填写 1-65535", field))
+	}
+	return errs
+}
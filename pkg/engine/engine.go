@@ -4,60 +4,53 @@ import (
 	"context"
 	"ddns/pkg/config"
 	"ddns/pkg/provider"
-	"ddns/pkg/provider/aliyun"
-	"ddns/pkg/provider/baidu"
-	"ddns/pkg/provider/dnsla"
-	"ddns/pkg/provider/huawei"
-	"ddns/pkg/provider/tencent"
-	"ddns/pkg/provider/volcengine"
+	_ "ddns/pkg/provider/all"
 	"ddns/pkg/webhook"
-	"fmt"
 	"log/slog"
-	"sync"
+	"reflect"
+	"sync/atomic"
 )
 
 // Operator 域名解析记录操作接口，组合了 CRUD 所有操作
-type Operator interface {
-	provider.Getter
-	provider.Creator
-	provider.Updater
-	provider.Deleter
-}
+type Operator = provider.Operator
 
-// NewOperator 根据服务商类型创建对应的 Operator 实例
-func NewOperator(provider, accessKeyId, accessKeySecret string) (Operator, error) {
-	switch provider {
-	case "aliyun":
-		return aliyun.NewAliyun(accessKeyId, accessKeySecret), nil
-	case "baidu":
-		return baidu.NewBaidu(accessKeyId, accessKeySecret), nil
-	case "dnsla":
-		return dnsla.NewDNSLA(accessKeyId, accessKeySecret), nil
-	case "tencent":
-		return tencent.NewTencent(accessKeyId, accessKeySecret), nil
-	case "huawei":
-		return huawei.NewHuawei(accessKeyId, accessKeySecret), nil
-	case "volcengine":
-		return volcengine.NewVolcengine(accessKeyId, accessKeySecret), nil
-	default:
-		return nil, fmt.Errorf("不支持的DNS运营商：%v", provider)
-	}
+// NewOperator 根据服务商类型创建对应的 Operator 实例，服务商需要先在 provider 注册表中注册
+func NewOperator(p config.Provider) (Operator, error) {
+	return provider.New(p.Provider, p.Credentials())
 }
 
 // Engine 代表整个动态域名解析引擎，负责管理配置和启动各个服务商的同步任务
 type Engine struct {
 	// 配置管理器
 	cfgManager *config.Manager
+	// 同步状态存储，为 nil 时同步状态只保存在内存中
+	stateStore StateStore
 }
 
-// NewEngine 创建一个新的 Engine 实例
-func NewEngine(cfgManager *config.Manager) *Engine {
+// NewEngine 创建一个新的 Engine 实例，stateStore 可以为 nil
+func NewEngine(cfgManager *config.Manager, stateStore StateStore) *Engine {
 	return &Engine{
 		cfgManager: cfgManager,
+		stateStore: stateStore,
 	}
 }
 
-// Start 启动整个动态域名解析引擎，监听配置文件变化并热重载
+// runningProvider 正在运行的服务商及其配置
+type runningProvider struct {
+	config   config.Provider
+	provider *Provider
+	cancel   context.CancelFunc
+	done     chan struct{}
+}
+
+// stop 停止服务商并等待所有记录的监听退出
+func (r *runningProvider) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// Start 启动整个动态域名解析引擎，监听配置文件变化并增量热重载
+// 热重载时只重启新增、删除或修改过的服务商和记录，未修改的记录继续运行并保留同步缓存
 func (e *Engine) Start(ctx context.Context) {
 	// 声明热加载通道
 	reloadChan := make(chan struct{}, 1)
@@ -70,9 +63,15 @@ func (e *Engine) Start(ctx context.Context) {
 		}
 	})
 
+	// 同步状态在热重载之间保留，避免每次修改配置都重新查询所有子域名
+	states := newStateBook(e.stateStore)
+	// 相同的获取方式在所有服务商之间合并，热重载时保留
+	fetches := newFetchCoordinator(sharedFetchTTL)
+	// Webhook 配置修改后原地替换，不需要重启服务商
+	notifier := &webhookSwitch{}
+	running := make(map[string]*runningProvider)
+
 	for {
-		pctx, cancel := context.WithCancel(ctx)
-		var wg sync.WaitGroup
 		cfg, err := e.cfgManager.Get()
 		if err != nil {
 			slog.Error("Engine启动失败！在获取配置文件时报错！err：", "err", err)
@@ -81,53 +80,112 @@ func (e *Engine) Start(ctx context.Context) {
 			//否则程序将组赛在此select中，直到任意通道有信号
 			select {
 			case <-ctx.Done():
-				cancel()
+				stopProviders(running)
 				return
 			case <-reloadChan:
-				cancel()
 				continue
 			}
 		}
 
-		//初始化 Webhook 实例
-		var notifier *webhook.Webhook
-		if cfg.Webhook.URL != "" {
-			notifier = webhook.NewWebhook(&cfg.Webhook)
-		}
-
-		//依次启动Provider
-		for _, provider := range cfg.Providers {
-			p, err := NewProvider(&provider, notifier)
-			if err != nil {
-				slog.Error("初始化服务商失败，跳过该服务商", "provider", provider.Name, "err", err)
-				continue
-			}
-			wg.Add(1)
-			go func(provider *Provider) {
-				defer wg.Done()
-				provider.Start(pctx)
-
-			}(p)
-			slog.Info("provider 已启动", "provider", provider.Name)
-		}
+		notifier.set(cfg.Webhook)
+		// 丢弃已删除或已修改记录的旧状态
+		states.retain(stateKeys(cfg))
+		e.applyProviders(ctx, cfg.Providers, running, notifier, states, fetches)
 
 		//没有defaut 会堵塞在select里面，直到任意分支有信号
 		select {
 		case <-ctx.Done(): //处理上级ctx关闭信号
-			//发送关闭信号
-			cancel()
-			//等待所有协程关闭
-			wg.Wait()
+			//关闭并等待所有服务商退出
+			stopProviders(running)
 			slog.Info("Engine 已退出")
 			//退出循环，退出本函数
 			return
 		case <-reloadChan: //处理重置信号
-			slog.Info("检测到配置变更，开始热重载")
-			//发送关闭信号
-			cancel()
-			//等待所有协程关闭
-			wg.Wait()
+			slog.Info("检测到配置变更，开始增量热重载")
 			//不return，因为是死循环，进入下一个循环。
 		}
 	}
 }
+
+// applyProviders 对比运行中的服务商和新配置，只启动、停止或更新有变化的服务商
+func (e *Engine) applyProviders(ctx context.Context, providers []config.Provider, running map[string]*runningProvider, notifier *webhookSwitch, states *stateBook, fetches *fetchCoordinator) {
+	seen := make(map[string]struct{}, len(providers))
+	for _, provider := range providers {
+		seen[provider.Name] = struct{}{}
+		if current, ok := running[provider.Name]; ok {
+			if sameProviderSettings(current.config, provider) {
+				// 服务商设置没变，只把记录的变化交给服务商处理
+				if !reflect.DeepEqual(current.config.Records, provider.Records) {
+					current.provider.UpdateRecords(provider.Records)
+					current.config = provider
+					slog.Info("provider 记录已更新", "provider", provider.Name)
+				}
+				continue
+			}
+			current.stop()
+			delete(running, provider.Name)
+			slog.Info("provider 配置已修改，重新启动", "provider", provider.Name)
+		}
+
+		p, err := NewProvider(&provider, notifier, states, fetches)
+		if err != nil {
+			slog.Error("初始化服务商失败，跳过该服务商", "provider", provider.Name, "err", err)
+			continue
+		}
+		pctx, cancel := context.WithCancel(ctx)
+		current := &runningProvider{config: provider, provider: p, cancel: cancel, done: make(chan struct{})}
+		go func() {
+			defer close(current.done)
+			p.Start(pctx)
+		}()
+		running[provider.Name] = current
+		slog.Info("provider 已启动", "provider", provider.Name)
+	}
+
+	for name, current := range running {
+		if _, ok := seen[name]; !ok {
+			current.stop()
+			delete(running, name)
+			slog.Info("provider 已删除，停止同步", "provider", name)
+		}
+	}
+}
+
+// stopProviders 通知所有服务商退出并等待
+func stopProviders(running map[string]*runningProvider) {
+	for _, current := range running {
+		current.cancel()
+	}
+	for _, current := range running {
+		<-current.done
+	}
+}
+
+// sameProviderSettings 比较除记录以外的服务商设置是否相同
+func sameProviderSettings(a, b config.Provider) bool {
+	a.Records, b.Records = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// webhookSwitch 可以在运行中替换配置的 Webhook 通知器
+type webhookSwitch struct {
+	current atomic.Pointer[webhook.Webhook]
+}
+
+// set 替换 Webhook 配置，URL 为空时不发送通知
+func (s *webhookSwitch) set(cfg config.Webhook) {
+	if cfg.URL == "" {
+		s.current.Store(nil)
+		return
+	}
+	s.current.Store(webhook.NewWebhook(&cfg))
+}
+
+// Send 使用当前的 Webhook 配置发送通知
+func (s *webhookSwitch) Send(ctx context.Context, data *webhook.WebhookData) error {
+	current := s.current.Load()
+	if current == nil {
+		return nil
+	}
+	return current.Send(ctx, data)
+}
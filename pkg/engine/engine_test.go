@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -13,7 +14,7 @@ func TestEngineStartStopsWhenConfigurationIsUnavailable(t *testing.T) {
 	cancel()
 	done := make(chan struct{})
 	go func() {
-		NewEngine(config.NewManager()).Start(ctx)
+		NewEngine(config.NewManager(), nil).Start(ctx)
 		close(done)
 	}()
 	select {
@@ -38,3 +39,68 @@ func TestProviderStartStopsWithoutRecords(t *testing.T) {
 		t.Fatal("Provider.Start() did not wait for shutdown")
 	}
 }
+
+func TestApplyProvidersRestartsOnlyChangedProviders(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine := NewEngine(config.NewManager(), nil)
+	running := make(map[string]*runningProvider)
+	notifier := &webhookSwitch{}
+	states := newStateBook(nil)
+	kept := config.Provider{Name: "kept", Provider: "aliyun", KeyID: "id", KeySecret: "secret"}
+	changed := config.Provider{Name: "changed", Provider: "aliyun", KeyID: "id", KeySecret: "secret"}
+
+	engine.applyProviders(ctx, []config.Provider{kept, changed}, running, notifier, states, nil)
+	keptBefore, changedBefore := running["kept"], running["changed"]
+
+	changed.KeyID = "new-id"
+	added := config.Provider{Name: "added", Provider: "aliyun", KeyID: "id", KeySecret: "secret"}
+	engine.applyProviders(ctx, []config.Provider{kept, changed, added}, running, notifier, states, nil)
+	if running["kept"] != keptBefore {
+		t.Fatal("unchanged provider was restarted")
+	}
+	if running["changed"] == changedBefore || running["added"] == nil {
+		t.Fatal("changed or added provider was not started")
+	}
+	select {
+	case <-changedBefore.done:
+	default:
+		t.Fatal("old changed provider is still running")
+	}
+
+	engine.applyProviders(ctx, []config.Provider{kept}, running, notifier, states, nil)
+	if len(running) != 1 || running["kept"] != keptBefore {
+		t.Fatalf("running providers = %v, want only kept", running)
+	}
+	stopProviders(running)
+}
+
+func TestApplyRecordsRestartsOnlyChangedRecords(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	instance := &Provider{
+		provider: &config.Provider{Name: "home", Provider: "aliyun"},
+		operator: &fakeOperator{getErr: errors.New("offline")},
+	}
+	static := func(name, value string) config.Record {
+		return config.Record{Name: name, SubDomains: []string{name + ".example.com"}, Type: "TXT", Value: value, Interval: 60}
+	}
+	runners := make(map[string]*recordRunner)
+	instance.applyRecords(ctx, runners, []config.Record{static("kept", "a"), static("changed", "a")})
+	kept, changed := runners["kept"], runners["changed"]
+
+	instance.applyRecords(ctx, runners, []config.Record{static("kept", "a"), static("changed", "b"), static("added", "a")})
+	if runners["kept"] != kept {
+		t.Fatal("unchanged record was restarted")
+	}
+	if runners["changed"] == changed || runners["added"] == nil {
+		t.Fatal("changed or added record was not started")
+	}
+
+	instance.applyRecords(ctx, runners, []config.Record{static("kept", "a")})
+	if len(runners) != 1 || runners["kept"] != kept {
+		t.Fatalf("running records = %v, want only kept", runners)
+	}
+	cancel()
+	<-kept.done
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"context"
+	"ddns/pkg/addr"
+	"ddns/pkg/config"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// sharedFetchTTL 共享获取结果的最短缓存时间，窗口内其他记录直接使用同一次获取的结果
+	// 实际缓存时间为使用该获取方式的记录中最短检测周期的一半，检测时间错开的记录也能使用同一次获取的结果
+	sharedFetchTTL = 5 * time.Second
+	// sharedFetchTimeout 共享获取的默认最长时间，发起获取的记录退出或超时后，其他记录仍然可以等待结果
+	// 获取方式自己的超时时间更长时（如 command.timeout）使用获取方式的超时时间
+	sharedFetchTimeout = time.Minute
+)
+
+// fetchCoordinator 合并所有服务商中获取方式相同的记录，同一时间只发起一次获取，结果分发给所有记录
+// 减少对查询网站的请求，也避免不同记录在短时间内得到不同的地址；Engine 热重载时保留
+type fetchCoordinator struct {
+	mu sync.Mutex
+	// ttl 最短缓存时间
+	ttl     time.Duration
+	fetches map[string]*sharedFetcher
+}
+
+func newFetchCoordinator(ttl time.Duration) *fetchCoordinator {
+	return &fetchCoordinator{ttl: ttl, fetches: make(map[string]*sharedFetcher)}
+}
+
+// acquire 返回获取方式对应的共享 Fetcher，第一次使用时包装获取方式，之后去重键相同的记录复用同一个
+// interval 为记录的检测周期，用于计算缓存时间
+func (c *fetchCoordinator) acquire(source fetchSource, interval time.Duration) *sharedFetcher {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	shared, ok := c.fetches[source.key]
+	if !ok {
+		shared = &sharedFetcher{fetcher: source.fetcher, timeout: max(sharedFetchTimeout, source.timeout), intervals: make(map[time.Duration]int)}
+		c.fetches[source.key] = shared
+	}
+	shared.refs++
+	shared.intervals[interval]++
+	shared.setTTL(c.ttl)
+	return shared
+}
+
+// release 记录停止后释放共享 Fetcher，没有记录使用时删除
+func (c *fetchCoordinator) release(key string, interval time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	shared, ok := c.fetches[key]
+	if !ok {
+		return
+	}
+	shared.refs--
+	if shared.intervals[interval]--; shared.intervals[interval] <= 0 {
+		delete(shared.intervals, interval)
+	}
+	if shared.refs <= 0 {
+		delete(c.fetches, key)
+		return
+	}
+	shared.setTTL(c.ttl)
+}
+
+// fetchKey 返回获取方式的去重键，键中包含影响获取结果的所有参数
+// 地址策略只在一致性模式中参与统计，其他情况下不影响获取结果
+func fetchKey(source config.Fallback, opts addr.Options, extract string, policy config.AddrPolicy) string {
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%d", source.GetType, source.GetValue, opts.Version, extract, opts.Quorum)
+	if opts.Quorum > 0 {
+		key += fmt.Sprintf("\x00%v", policy)
+	}
+	if opts.Command != nil {
+		key += fmt.Sprintf("\x00%q", *opts.Command)
+	}
+	return key
+}
+
+// sharedFetcher 多个记录共享的 Fetcher
+type sharedFetcher struct {
+	fetcher addr.Fetcher
+	// timeout 一次获取的最长时间，去重键包含结构化命令，共享的记录超时时间相同
+	timeout time.Duration
+	// refs 使用该 Fetcher 的记录数量，intervals 这些记录的检测周期和数量，由 fetchCoordinator.mu 保护
+	refs      int
+	intervals map[time.Duration]int
+
+	mu   sync.Mutex
+	ttl  time.Duration
+	call *fetchCall
+}
+
+// setTTL 按最短检测周期的一半更新缓存时间，不短于 minTTL，调用方需持有 fetchCoordinator.mu
+// 每个记录检测时拿到的结果最多是半个检测周期前的，检测时间错开的记录也只需要一次获取
+func (f *sharedFetcher) setTTL(minTTL time.Duration) {
+	ttl := minTTL
+	if len(f.intervals) > 0 {
+		ttl = max(ttl, slices.Min(slices.Collect(maps.Keys(f.intervals)))/2)
+	}
+	f.mu.Lock()
+	f.ttl = ttl
+	f.mu.Unlock()
+}
+
+// fetchCall 一次获取，done 关闭后结果可读
+type fetchCall struct {
+	done  chan struct{}
+	at    time.Time
+	addrs []netip.Addr
+	err   error
+}
+
+// Fetch 缓存窗口内直接返回上次的结果，正在获取时等待同一次获取，否则发起新的获取
+// 获取在独立的协程中进行，调用方的 ctx 取消时只停止等待，不影响其他记录
+func (f *sharedFetcher) Fetch(ctx context.Context) ([]netip.Addr, error) {
+	f.mu.Lock()
+	call := f.call
+	if call == nil || call.expired(f.ttl) {
+		call = &fetchCall{done: make(chan struct{})}
+		f.call = call
+		go call.run(context.WithoutCancel(ctx), f.fetcher, f.timeout)
+	}
+	f.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return slices.Clone(call.addrs), call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *fetchCall) run(ctx context.Context, fetcher addr.Fetcher, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c.addrs, c.err = fetcher.Fetch(ctx)
+	c.at = time.Now()
+	close(c.done)
+}
+
+// expired 获取已经完成并且超过缓存时间
+func (c *fetchCall) expired(ttl time.Duration) bool {
+	select {
+	case <-c.done:
+		return time.Since(c.at) >= ttl
+	default:
+		return false
+	}
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
+)
+
+func TestSharedFetcherMergesConcurrentFetches(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetcher := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		calls.Add(1)
+		<-release
+		return []netip.Addr{netip.MustParseAddr("8.8.8.8")}, nil
+	})
+	fetches := newFetchCoordinator(time.Hour)
+	first := fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, 30*time.Second)
+	second := fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, 30*time.Second)
+	if first != second {
+		t.Fatal("identical sources were not merged")
+	}
+
+	// 调用方超时只停止等待，不取消其他记录正在等待的获取
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := first.Fetch(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Fetch() with expired context error = %v", err)
+	}
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			addrs, err := second.Fetch(context.Background())
+			if err != nil || len(addrs) != 1 {
+				t.Errorf("Fetch() = %v, %v", addrs, err)
+			}
+		})
+	}
+	close(release)
+	wg.Wait()
+	if _, err := first.Fetch(context.Background()); err != nil || calls.Load() != 1 {
+		t.Fatalf("fetch calls = %d, err %v, want 1 cached call", calls.Load(), err)
+	}
+
+	fetches.release("url", 30*time.Second)
+	fetches.release("url", 30*time.Second)
+	if len(fetches.fetches) != 0 {
+		t.Fatal("released source was not removed")
+	}
+}
+
+func TestSharedFetcherRefreshesAfterTTL(t *testing.T) {
+	var calls atomic.Int32
+	fetches := newFetchCoordinator(0)
+	shared := fetches.acquire(fetchSource{key: "cmd", fetcher: fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		calls.Add(1)
+		return nil, errors.New("failed")
+	})}, 0)
+	for range 2 {
+		if _, err := shared.Fetch(context.Background()); err == nil {
+			t.Fatal("Fetch() error was not returned")
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("fetch calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestSharedFetcherTTLFollowsShortestInterval(t *testing.T) {
+	var calls atomic.Int32
+	fetcher := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		calls.Add(1)
+		return []netip.Addr{netip.MustParseAddr("8.8.8.8")}, nil
+	})
+	fetches := newFetchCoordinator(sharedFetchTTL)
+	shared := fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, time.Minute)
+	fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, 30*time.Second)
+
+	// 两个记录的检测时间错开 12 秒，后检测的记录使用先检测的记录的结果
+	if _, err := shared.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	shared.call.at = time.Now().Add(-12 * time.Second)
+	if _, err := shared.Fetch(context.Background()); err != nil || calls.Load() != 1 {
+		t.Fatalf("fetch calls = %d, err %v, want offset ticker to reuse the result", calls.Load(), err)
+	}
+	// 超过最短检测周期的一半后重新获取
+	shared.call.at = time.Now().Add(-16 * time.Second)
+	if _, err := shared.Fetch(context.Background()); err != nil || calls.Load() != 2 {
+		t.Fatalf("fetch calls = %d, err %v, want refresh after half interval", calls.Load(), err)
+	}
+
+	// 检测周期短的记录停止后按剩下的记录计算
+	fetches.release("url", 30*time.Second)
+	if shared.ttl != 30*time.Second {
+		t.Fatalf("ttl = %v, want 30s", shared.ttl)
+	}
+	fetches.release("url", time.Minute)
+	fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, 10*time.Second)
+	if got := fetches.fetches["url"].ttl; got != sharedFetchTTL {
+		t.Fatalf("ttl = %v, want minimum %v", got, sharedFetchTTL)
+	}
+}
+
+func TestRecordStateShareUsesFetchKey(t *testing.T) {
+	record := config.Record{Name: "a", SubDomains: []string{"a.example.com"}, IPVersion: provider.IPv6, GetType: "url", GetValue: "https://6.ipw.cn"}
+	other := record
+	other.Name, other.SubDomains = "b", []string{"b.example.com"}
+	withQuorum := record
+	withQuorum.GetValue, withQuorum.Quorum = "https://6.ipw.cn,https://v6.ident.me", 2
+	nic := record
+	nic.GetType, nic.GetValue = "nic", "br-lan"
+
+	fetches := newFetchCoordinator(time.Hour)
+	var states []*RecordState
+	for _, rec := range []config.Record{record, other, withQuorum, nic} {
+		state, err := NewRecordState(&rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		state.share(fetches, 30*time.Second)
+		states = append(states, state)
+	}
+	if states[0].sources[0].fetcher != states[1].sources[0].fetcher {
+		t.Fatal("records with identical sources do not share a fetcher")
+	}
+	// 网卡方式可以主动通知地址变化，不共享
+	if len(fetches.fetches) != 2 || len(states[3].sharedKey) != 0 {
+		t.Fatalf("shared fetchers = %d, nic keys %v", len(fetches.fetches), states[3].sharedKey)
+	}
+	for _, state := range states {
+		state.release()
+	}
+	if len(fetches.fetches) != 0 {
+		t.Fatal("released records left shared fetchers")
+	}
+}
+
+func TestSharedFetcherUsesSourceTimeout(t *testing.T) {
+	fetcher := fetcherFunc(func(context.Context) ([]netip.Addr, error) { return nil, nil })
+	fetches := newFetchCoordinator(sharedFetchTTL)
+	// 命令超时时间超过默认的共享获取时间时使用命令的超时时间
+	if shared := fetches.acquire(fetchSource{key: "cmd", fetcher: fetcher, timeout: 5 * time.Minute}, time.Minute); shared.timeout != 5*time.Minute {
+		t.Fatalf("timeout = %v, want 5m", shared.timeout)
+	}
+	if shared := fetches.acquire(fetchSource{key: "url", fetcher: fetcher}, time.Minute); shared.timeout != sharedFetchTimeout {
+		t.Fatalf("timeout = %v, want %v", shared.timeout, sharedFetchTimeout)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"ddns/pkg/config"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+)
+
+// healthChecker 检查候选地址是否可用
+type healthChecker struct {
+	check  config.HealthCheck
+	dialer *net.Dialer
+	client *http.Client
+}
+
+// newHealthChecker 创建健康检查，没有配置时返回 nil
+func newHealthChecker(check config.HealthCheck) *healthChecker {
+	if check.IsZero() {
+		return nil
+	}
+	dialer := &net.Dialer{}
+	return &healthChecker{
+		check:  check,
+		dialer: dialer,
+		client: &http.Client{
+			// 直接连接候选地址，不使用代理和连接池
+			Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true},
+			// 跳转也是服务的响应，按状态码判断
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Check 检查候选地址，返回 nil 表示可用
+func (h *healthChecker) Check(ctx context.Context, candidate netip.Addr) error {
+	ctx, cancel := context.WithTimeout(ctx, h.check.CheckTimeout())
+	defer cancel()
+
+	if h.check.Type == config.HealthTCP {
+		conn, err := h.dialer.DialContext(ctx, "tcp", netip.AddrPortFrom(candidate, uint16(h.check.Port)).String())
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	port := h.check.Port
+	if port == 0 {
+		port = 80
+	}
+	path := h.check.Path
+	if path == "" {
+		path = "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+net.JoinHostPort(candidate.String(), strconv.Itoa(port))+path, nil)
+	if err != nil {
+		return err
+	}
+	if h.check.Host != "" {
+		req.Host = h.check.Host
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	want := h.check.Status
+	if want == 0 {
+		want = http.StatusOK
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("HTTP 状态码 %d，期望 %d", resp.StatusCode, want)
+	}
+	return nil
+}
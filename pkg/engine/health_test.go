@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"ddns/pkg/config"
+)
+
+func TestHealthCheckerHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "home.example.com" {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			return
+		}
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/healthz", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	candidate := netip.MustParseAddr(u.Hostname())
+
+	tests := []struct {
+		name  string
+		check config.HealthCheck
+		ok    bool
+	}{
+		{"status", config.HealthCheck{Type: config.HealthHTTP, Port: port, Path: "/healthz", Host: "home.example.com", Status: 204}, true},
+		{"default status", config.HealthCheck{Type: config.HealthHTTP, Port: port, Host: "home.example.com"}, false},
+		{"redirect is not followed", config.HealthCheck{Type: config.HealthHTTP, Port: port, Path: "/moved", Host: "home.example.com", Status: 302}, true},
+		{"host", config.HealthCheck{Type: config.HealthHTTP, Port: port, Status: 204}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newHealthChecker(tt.check).Check(context.Background(), candidate)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Check() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
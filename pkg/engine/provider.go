@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"ddns/pkg/addr"
 	"ddns/pkg/config"
 	"ddns/pkg/provider"
 	"ddns/pkg/utils"
@@ -10,6 +11,8 @@ import (
 	"fmt"
 	"log/slog"
 	"net/netip"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 )
@@ -24,6 +27,25 @@ type Provider struct {
 	notifier          notificationSender
 	notificationQueue chan webhook.WebhookData
 	notificationWG    sync.WaitGroup
+	// 子域名同步状态，为 nil 时每次启动都重新同步
+	states *stateBook
+	// 所有服务商共享的获取协调器，为 nil 时每个记录单独获取
+	fetches *fetchCoordinator
+	// 热重载时新的记录配置，只保留最新的一份
+	updates chan []config.Record
+}
+
+// recordRunner 正在监听的记录及其配置
+type recordRunner struct {
+	config config.Record
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop 停止记录监听并等待退出
+func (r *recordRunner) stop() {
+	r.cancel()
+	<-r.done
 }
 
 type notificationSender interface {
@@ -31,8 +53,8 @@ type notificationSender interface {
 }
 
 // NewProvider 创建一个新的 Provider 实例
-func NewProvider(provider *config.Provider, notifier *webhook.Webhook) (*Provider, error) {
-	operator, err := NewOperator(provider.Provider, provider.KeyID, provider.KeySecret)
+func NewProvider(provider *config.Provider, notifier notificationSender, states *stateBook, fetches *fetchCoordinator) (*Provider, error) {
+	operator, err := NewOperator(*provider)
 	if err != nil {
 		return nil, err
 	}
@@ -41,30 +63,85 @@ func NewProvider(provider *config.Provider, notifier *webhook.Webhook) (*Provide
 		provider: provider,
 		operator: operator,
 		notifier: notifier,
+		states:   states,
+		fetches:  fetches,
+		updates:  make(chan []config.Record, 1),
 	}, nil
 }
 
 // Start 启动 Provider，监听所有记录的IP地址变化，并同步到DNS服务商
+// 运行中通过 UpdateRecords 更新记录配置，只重启有变化的记录
 func (p *Provider) Start(ctx context.Context) {
-	var wg sync.WaitGroup
 	p.startNotificationWorker(ctx)
 	//启动所有记录的获取IP地址
-	for _, record := range p.provider.Records {
-		wg.Add(1)
-		r := record
-		go func(record *config.Record) {
-			defer wg.Done()
-			p.watchRecord(ctx, record)
-		}(&r)
-		slog.Info("record 监听已启动", "provider", p.provider.Name, "record", record.Name)
+	runners := make(map[string]*recordRunner)
+	p.applyRecords(ctx, runners, p.provider.Records)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Warn("Provider 正在退出", "provider", p.provider.Name)
+			for _, runner := range runners {
+				<-runner.done
+			}
+			p.notificationWG.Wait()
+			slog.Warn("Provider 已退出", "provider", p.provider.Name)
+			return
+		case records := <-p.updates:
+			p.applyRecords(ctx, runners, records)
+		}
+	}
+}
+
+// UpdateRecords 提交新的记录配置，由 Start 所在协程对比后生效，未处理的旧配置会被替换
+func (p *Provider) UpdateRecords(records []config.Record) {
+	for {
+		select {
+		case p.updates <- records:
+			return
+		default:
+			select {
+			case <-p.updates:
+			default:
+			}
+		}
 	}
+}
 
-	<-ctx.Done()
-	slog.Warn("Provider 正在退出", "provider", p.provider.Name)
+// applyRecords 对比正在监听的记录和新配置，未修改的记录继续运行并保留同步缓存
+func (p *Provider) applyRecords(ctx context.Context, runners map[string]*recordRunner, records []config.Record) {
+	seen := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		seen[record.Name] = struct{}{}
+		if runner, ok := runners[record.Name]; ok {
+			if reflect.DeepEqual(runner.config, record) {
+				continue
+			}
+			runner.stop()
+			slog.Info("record 配置已修改，重新监听", "provider", p.provider.Name, "record", record.Name)
+		}
+		runners[record.Name] = p.startRecord(ctx, record)
+		slog.Info("record 监听已启动", "provider", p.provider.Name, "record", record.Name)
+	}
+	for name, runner := range runners {
+		if _, ok := seen[name]; !ok {
+			runner.stop()
+			delete(runners, name)
+			slog.Info("record 已删除，停止监听", "provider", p.provider.Name, "record", name)
+		}
+	}
+}
 
-	wg.Wait()
-	p.notificationWG.Wait()
-	slog.Warn("Provider 已退出", "provider", p.provider.Name)
+// startRecord 在独立的协程中监听记录，watchRecord 会修改记录配置，这里传入副本
+func (p *Provider) startRecord(ctx context.Context, record config.Record) *recordRunner {
+	rctx, cancel := context.WithCancel(ctx)
+	runner := &recordRunner{config: record, cancel: cancel, done: make(chan struct{})}
+	watched := record
+	go func() {
+		defer close(runner.done)
+		p.watchRecord(rctx, &watched)
+	}()
+	return runner
 }
 
 func (p *Provider) startNotificationWorker(ctx context.Context) {
@@ -95,17 +172,33 @@ func (p *Provider) watchRecord(ctx context.Context, record *config.Record) {
 		slog.Error("初始化 RecordState 失败", "err", err)
 		return
 	}
-
-	p.syncRecord(ctx, record, recordState)
+	if p.states != nil {
+		keys := make(map[string]string, len(record.SubDomains))
+		for _, subDomain := range record.SubDomains {
+			keys[subDomain] = stateKey(p.provider, record, subDomain)
+		}
+		recordState.restore(p.states, keys)
+	}
+	// 先取通知通道再同步，避免漏掉同步期间推送的地址
+	changed := recordState.Changed()
 
 	//设置定时器
-	//允许范围是5-60秒
-	if record.Interval < 10 || record.Interval > 60 {
-		record.Interval = 30
+	//可以主动通知地址变化的记录允许更长的检测周期，系统不支持通知时按普通记录的最大值处理
+	interval := record.CheckInterval()
+	if changed == nil && interval > config.MaxPollInterval*time.Second {
+		slog.Warn("获取方式不支持通知地址变化，检测周期按最大值处理", "record", record.Name, "interval", config.MaxPollInterval)
+		interval = config.MaxPollInterval * time.Second
 	}
+	record.Interval = int64(interval / time.Second)
+
+	// 共享获取的缓存时间与检测周期相关
+	recordState.share(p.fetches, interval)
+	defer recordState.release()
+
+	p.syncRecord(ctx, record, recordState)
 
 	//新建定时器
-	ticker := time.NewTicker(time.Duration(record.Interval) * time.Second)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	//死循环监听ctx和定时器
@@ -115,17 +208,23 @@ func (p *Provider) watchRecord(ctx context.Context, record *config.Record) {
 			slog.Warn("record 监听已停止", "record", record.Name)
 			return
 		case <-ticker.C:
-			p.syncRecord(ctx, record, recordState)
+		case <-changed:
+			// 客户端推送了新地址或网卡地址发生变化，立即同步
 		}
+		changed = recordState.Changed()
+		p.syncRecord(ctx, record, recordState)
 	}
 }
 
-// syncRecord 同步单个记录的IP地址变化到DNS服务商
-func (p *Provider) syncRecord(ctx context.Context, record *config.Record, recordState *RecordState) {
+// resolved 处理获取地址的结果，返回是否获取成功，current 为获取到的地址，只用于日志
+// 获取失败时累计失败次数并发送通知，推送方式等待客户端首次推送时不算失败
+func (p *Provider) resolved(ctx context.Context, record *config.Record, recordState *RecordState, previousSource string, current any, err error) bool {
 	logger := p.logger(record.Name)
-
-	// 获取当前IP地址
-	currentAddr, err := recordState.Resolve(ctx)
+	if errors.Is(err, addr.ErrNotReady) {
+		// 推送方式在客户端首次推送前没有地址，属于正常情况
+		logger.Debug("等待客户端推送 IP 地址", "err", err)
+		return false
+	}
 	if err != nil {
 		recordState.GetAddrFailCount++
 		msg := fmt.Sprintf("record: %v 第%d次获取 IP 失败 err: %v", record.Name, recordState.GetAddrFailCount, err)
@@ -138,38 +237,108 @@ func (p *Provider) syncRecord(ctx context.Context, record *config.Record, record
 				Date:     time.Now().Format("2006-01-02 15:04:05"),
 			})
 		}
-		return
+		return false
 	}
 	recordState.GetAddrFailCount = 0
+	if source := recordState.Source(); previousSource != "" && previousSource != source {
+		logger.Warn("获取方式已切换", "from", previousSource, "to", source, "IP", current)
+	}
+	if skipped := recordState.Skipped(); skipped != nil {
+		logger.Warn("已跳过不可用的候选地址", "IP", current, "err", skipped)
+	}
+	return true
+}
 
-	//强制同步时间，单位分钟
+// rejected 服务商永久拒绝同步时停止同步子域名并发送通知，返回 err 是否为永久错误
+// 认证失败、主机名不存在等错误重试也不会成功，反复重试还可能被服务商封禁
+func (p *Provider) rejected(ctx context.Context, record *config.Record, recordState *RecordState, subDomain, oldAddr, newAddr string, err error) bool {
+	var permanent *provider.PermanentError
+	if !errors.As(err, &permanent) {
+		return false
+	}
+	recordState.Reject(subDomain, err)
+	msg := "服务商拒绝同步，已停止同步该子域名，修改配置后恢复"
+	p.logger(record.Name).Error(msg, "subDomain", subDomain, "err", err)
+	p.sendNotification(ctx, &webhook.WebhookData{
+		Domain:   subDomain,
+		OldAddr:  oldAddr,
+		NewAddr:  newAddr,
+		Provider: p.provider.Provider,
+		State:    fmt.Sprintf("%s err: %v", msg, err),
+		Date:     time.Now().Format("2006-01-02 15:04:05"),
+	})
+	return true
+}
+
+// forceInterval 返回强制同步时间，单位分钟
+func (p *Provider) forceInterval() int64 {
 	//允许范围在1-30分钟
 	forceInterval := p.provider.ForceInterval
 	if forceInterval < 5 || forceInterval > 30 {
 		forceInterval = 15
 	}
+	return forceInterval
+}
+
+// syncRecord 同步单个记录的IP地址变化到DNS服务商
+func (p *Provider) syncRecord(ctx context.Context, record *config.Record, recordState *RecordState) {
+	// 多值记录按地址集合同步
+	if record.PublishesSet() {
+		p.syncRecordSet(ctx, record, recordState)
+		return
+	}
+	logger := p.logger(record.Name)
+
+	// 获取当前IP地址，配置了备用获取方式时依次尝试
+	previousSource := recordState.Source()
+	currentAddr, err := recordState.Resolve(ctx)
+	if !p.resolved(ctx, record, recordState, previousSource, currentAddr, err) {
+		return
+	}
+	source := recordState.Source()
+	forceInterval := p.forceInterval()
+	canQuery := p.canQuery()
 
 	// 遍历所有子域名
 	for _, subDomain := range record.SubDomains {
+		// 前缀跟踪主机的子域名按委派前缀生成地址，同一次获取供所有子域名使用
+		hostAddr, err := recordState.HostAddr(subDomain, currentAddr)
+		if err != nil {
+			logger.Error("生成前缀跟踪地址失败", "subDomain", subDomain, "IP", currentAddr, "err", err)
+			continue
+		}
+
+		if err := recordState.Rejected(subDomain); err != nil {
+			logger.Debug("服务商已拒绝同步，修改配置后恢复", "subDomain", subDomain, "err", err)
+			continue
+		}
+
 		//判断是否需要更新和计算剩余强制和DNS服务商对齐时间
-		needSync, nextForceSyncIn := recordState.ShouldSync(subDomain, currentAddr)
+		// 只支持更新的服务商无法和云端对齐，只在IP变化时推送
+		shouldSync := recordState.ShouldSync
+		if !canQuery {
+			shouldSync = recordState.ShouldPush
+		}
+		needSync, nextForceSyncIn := shouldSync(subDomain, hostAddr)
 		if !needSync {
 			msg := fmt.Sprintf("IP 未变，将%v秒后重获IP", record.Interval)
 			logger.Info(msg,
 				"subDomain", subDomain,
-				"IP", currentAddr,
+				"IP", hostAddr,
 				"nextForceSyncIn", nextForceSyncIn.Truncate(time.Second))
 			continue
 		}
 
-		//获取缓存的IP地址
-		oldAddr := netip.Addr{}
-		if cache, exists := recordState.GetCache(subDomain); exists {
-			oldAddr = cache.Addr
-		}
+		//获取缓存的IP地址和上次写入的记录
+		previous, _ := recordState.GetCache(subDomain)
+		oldAddr := previous.Addr
 
 		// 执行DNS服务商操作
-		if err := p.syncToProvider(ctx, subDomain, record, currentAddr); err != nil {
+		recordID, err := p.syncToProvider(ctx, subDomain, record, previous, hostAddr)
+		if err != nil {
+			if p.rejected(ctx, record, recordState, subDomain, addrString(oldAddr), hostAddr.String(), err) {
+				continue
+			}
 			// 获取失败计数
 			failCount, nextRetryGap := recordState.IncFailCount(subDomain, forceInterval)
 			msg := fmt.Sprintf("第%d次同步失败!", failCount)
@@ -183,7 +352,7 @@ func (p *Provider) syncRecord(ctx context.Context, record *config.Record, record
 				p.sendNotification(ctx, &webhook.WebhookData{
 					Domain:   subDomain,
 					OldAddr:  oldAddr.String(),
-					NewAddr:  currentAddr.String(),
+					NewAddr:  hostAddr.String(),
 					Provider: p.provider.Provider,
 					State:    fmt.Sprintf("第%d次同步失败 err: %v nextRetryGap:%v", failCount, err, nextRetryGap.Truncate(time.Second)),
 					Date:     time.Now().Format("2006-01-02 15:04:05"),
@@ -193,66 +362,93 @@ func (p *Provider) syncRecord(ctx context.Context, record *config.Record, record
 			continue // 当前子域名操作失败，不更新缓存，下一轮重试
 		}
 
+		// 只支持更新的服务商无法查询云端旧值，按本地缓存判断 IP 是否变化后发送 webhook 通知
+		if !canQuery && oldAddr != hostAddr {
+			p.sendNotification(ctx, &webhook.WebhookData{
+				Domain:   subDomain,
+				OldAddr:  addrString(oldAddr),
+				NewAddr:  hostAddr.String(),
+				Provider: p.provider.Provider,
+				State:    "更新记录成功",
+				Date:     time.Now().Format("2006-01-02 15:04:05"),
+			})
+		}
+
 		// 同步成功，更新缓存和重置失败计数器
-		nextForceSyncIn = recordState.UpdateCache(subDomain, currentAddr, forceInterval)
-		logger.Info("子域名记录同步完成", "subDomain", subDomain, "IP", currentAddr, "nextForceSyncIn", nextForceSyncIn.Truncate(time.Second))
+		nextForceSyncIn = recordState.UpdateCache(subDomain, hostAddr, recordID, forceInterval)
+		logger.Info("子域名记录同步完成", "subDomain", subDomain, "IP", hostAddr, "source", source, "nextForceSyncIn", nextForceSyncIn.Truncate(time.Second))
 	}
 }
 
-// syncToProvider 同步子域名记录到DNS服务商
-func (p *Provider) syncToProvider(ctx context.Context, subDomain string, record *config.Record, currentAddr netip.Addr) error {
+// syncToProvider 同步子域名记录到DNS服务商，返回 TXT、MX 等可以有多条同名记录的类型写入的记录ID
+// previous 为上次同步的缓存，按其中的记录ID找到这些类型上次写入的记录，没有记录ID时按上次的IP地址渲染的记录值查找
+func (p *Provider) syncToProvider(ctx context.Context, subDomain string, record *config.Record, previous SubDomainInfo, currentAddr netip.Addr) (string, error) {
 	logger := p.logger(record.Name)
 
-	ttl := record.TTL
-	if record.TTL > 86400 || record.TTL < 1 {
-		ttl = 600
+	ttl := recordTTL(record)
+
+	// 切割rr domain
+	rr, domain, err := utils.ParseDomain(subDomain)
+	if err != nil {
+		return "", err
+	}
+	value, err := record.RenderValue(subDomain, currentAddr)
+	if err != nil {
+		return "", err
+	}
+	desired := provider.Record{
+		Type:       record.RecordType(),
+		RR:         rr,
+		DomainName: domain,
+		Value:      value,
+		TTL:        ttl,
+		Proxied:    record.Proxied,
+		Priority:   record.Priority,
+		Weight:     record.Weight,
+		Port:       record.Port,
+	}
+
+	// 只支持更新的服务商（如 DynDNS2）不能查询和创建记录，直接推送当前IP地址
+	if !p.canQuery() {
+		return "", p.pushToProvider(ctx, subDomain, record, desired)
 	}
 
 	// 调用dns api 获取记录信息
 	var resRecords []provider.Record
-	err := utils.DoWithDefaultRetry(ctx, func() error {
+	err = utils.DoWithDefaultRetry(ctx, func() error {
 		var err error
-		//调用DNS运营商
-		resRecords, err = p.operator.GetSub(ctx, subDomain, record.IPVersion)
-		// if errors.Is(err, provider.ErrRecordNotFound) {
-		// 	return err
-		// }
+		//调用DNS运营商，非地址记录查询全部类型后按类型过滤
+		resRecords, err = p.operator.GetSub(ctx, subDomain, provider.VersionOf(desired.Type))
 		return err
 	})
 
-	createRecord := func() error {
-		// 切割rr domain
-		rr, domain, err := utils.ParseDomain(subDomain)
-		if err != nil {
-			return err
-		}
-
+	createRecord := func() (string, error) {
 		//创建记录
-		err = utils.DoWithDefaultRetry(ctx, func() error {
-			_, createErr := p.operator.Create(ctx, &provider.Record{
-				Type:       record.IPVersion.RecordType(),
-				RR:         rr,
-				DomainName: domain,
-				Value:      currentAddr.String(),
-				TTL:        ttl,
-			})
+		var created *provider.Record
+		err := utils.DoWithDefaultRetry(ctx, func() error {
+			reqRecord := desired
+			var createErr error
+			created, createErr = p.operator.Create(ctx, &reqRecord)
 			return createErr
 		})
 
 		if err == nil {
 
-			logger.Info("创建记录成功", "subDomain", subDomain, "IP", currentAddr)
+			logger.Info("创建记录成功", "subDomain", subDomain, "type", desired.Type, "value", value)
 			// 创建新记录成功发送 webhook 通知
 			p.sendNotification(ctx, &webhook.WebhookData{
 				Domain: subDomain,
 				// OldAddr:  oldAddr.String(),
-				NewAddr:  currentAddr.String(),
+				NewAddr:  value,
 				Provider: p.provider.Provider,
 				State:    "创建记录成功",
 				Date:     time.Now().Format("2006-01-02 15:04:05"),
 			})
 		}
-		return err
+		if err != nil || created == nil {
+			return "", err
+		}
+		return created.RecordId, nil
 	}
 
 	// 记录不存在，创建
@@ -262,64 +458,165 @@ func (p *Provider) syncToProvider(ctx context.Context, subDomain string, record
 
 	// 其他错误
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	// 同一 RR 可能同时存在多种类型的记录，只处理目标类型
+	var targets []provider.Record
+	for _, resRecord := range resRecords {
+		if strings.EqualFold(resRecord.Type, desired.Type) {
+			targets = append(targets, resRecord)
+		}
+	}
+	if len(targets) == 0 {
+		return createRecord()
+	}
+
+	// TXT、MX、SRV、CAA 同名记录可以有多条，只更新上次写入的那条，找不到时新建，避免覆盖其他记录
+	if !replacesAll(desired.Type) {
+		var written *provider.Record
+		for i, target := range targets {
+			if provider.SameData(target, desired) {
+				logger.Debug("当前记录值与云端一致", "subDomain", subDomain, "type", desired.Type, "value", value)
+				return target.RecordId, nil
+			}
+			if written != nil {
+				continue
+			}
+			// 优先按上次写入的记录ID匹配，记录值引用了IP地址时无法按值找到
+			if previous.RecordId != "" {
+				if target.RecordId == previous.RecordId {
+					written = &targets[i]
+				}
+				continue
+			}
+			if oldValue, err := record.RenderValue(subDomain, previous.Addr); err == nil {
+				// 只按记录值匹配，优先级、权重、端口修改后仍然更新原记录
+				old := target
+				old.Value = oldValue
+				if provider.SameData(target, old) {
+					written = &targets[i]
+				}
+			}
+		}
+		if written == nil {
+			return createRecord()
+		}
+		targets = []provider.Record{*written}
 	}
+
 	//全部都更新成功才发送webhook
 	hasUpdated := false
-	hasTargetRecord := false
-	// 记录dns api返回的IP地址
-	resOldAddr := ""
+	// 只维护自己写入的记录时返回记录ID，部分服务商更新后记录ID会变化
+	recordID := ""
+	// 记录dns api返回的记录值，用于发送webhook
+	resOldValue := ""
 	//记录存在，更新
-	for _, resRecord := range resRecords {
-		// 同一 RR 可能同时存在 A 和 AAAA 记录，不能用一种地址更新另一种记录。
-		if resRecord.Type != record.IPVersion.RecordType() {
-			continue
+	for _, resRecord := range targets {
+		//DNS服务商返回的和本地当前记录值相同，且代理设置无需修改，跳过更新
+		if !replacesAll(desired.Type) {
+			recordID = resRecord.RecordId
 		}
-		hasTargetRecord = true
-		//DNS服务商返回的和本地当前IP地址相同，跳过更新
-		if resRecord.Value == currentAddr.String() {
-			logger.Debug("当前IP地址与云端一致", "subDomain", subDomain, "IP", currentAddr)
+		if provider.SameData(resRecord, desired) && sameProxied(resRecord.Proxied, record.Proxied) {
+			logger.Debug("当前记录值与云端一致", "subDomain", subDomain, "type", desired.Type, "value", value)
 			continue
 		}
-		// 记录旧IP地址，用于发送webhook
-		resOldAddr = resRecord.Value
-		//拷贝dns服务商返回的记录
+		resOldValue = resRecord.Value
+		//拷贝dns服务商返回的记录，赋值新记录值
 		reqRecord := resRecord
-		//赋值新IP地址
-		reqRecord.Value = currentAddr.String()
+		reqRecord.Type = desired.Type
+		reqRecord.Value = desired.Value
+		reqRecord.Priority = desired.Priority
+		reqRecord.Weight = desired.Weight
+		reqRecord.Port = desired.Port
 		reqRecord.TTL = ttl
+		if record.Proxied != nil {
+			reqRecord.Proxied = record.Proxied
+		}
 
 		// 带重试的dns更新请求
 		err := utils.DoWithDefaultRetry(ctx, func() error {
 			return p.operator.Update(ctx, &reqRecord)
 		})
 		if err != nil {
-			return fmt.Errorf("更新记录失败: %w", err)
+			return "", fmt.Errorf("更新记录失败: %w", err)
+		}
+		if !replacesAll(desired.Type) {
+			recordID = reqRecord.RecordId
 		}
-		logger.Info("更新记录成功", "subDomain", subDomain, "old_IP", resRecord.Value, "new_IP", currentAddr)
+		logger.Info("更新记录成功", "subDomain", subDomain, "type", desired.Type, "old_value", resRecord.Value, "new_value", value)
 
 		//所以记录都更新成功才发送webhook
 		// 有些dns服务商相同记录可以有多条，比如：阿里云
 		hasUpdated = true
 
 	}
-	if !hasTargetRecord {
-		return createRecord()
-	}
 	if hasUpdated {
-		//更新 IP 成功发送 webhook 通知
+		//更新成功发送 webhook 通知
 		p.sendNotification(ctx, &webhook.WebhookData{
 			Domain:   subDomain,
-			OldAddr:  resOldAddr,
-			NewAddr:  currentAddr.String(),
+			OldAddr:  resOldValue,
+			NewAddr:  value,
 			Provider: p.provider.Provider,
 			State:    "更新记录成功",
 			Date:     time.Now().Format("2006-01-02 15:04:05"),
 		})
 	}
+	return recordID, nil
+}
+
+// pushToProvider 把记录直接推送到只支持更新的DNS服务商，不需要 RecordId
+func (p *Provider) pushToProvider(ctx context.Context, subDomain string, record *config.Record, desired provider.Record) error {
+	err := utils.DoWithDefaultRetry(ctx, func() error {
+		reqRecord := desired
+		return p.operator.Update(ctx, &reqRecord)
+	})
+	if err != nil {
+		return fmt.Errorf("推送记录失败: %w", err)
+	}
+	p.logger(record.Name).Info("推送记录成功", "subDomain", subDomain, "type", desired.Type, "value", desired.Value)
 	return nil
 }
 
+// replacesAll 同名记录是否全部更新为同一个值，A、AAAA、CNAME 记录全部更新，其他类型只维护自己写入的记录
+func replacesAll(recordType string) bool {
+	switch recordType {
+	case provider.TypeA, provider.TypeAAAA, provider.TypeCNAME:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordTTL 返回记录的生存时间，超出范围时为600秒
+func recordTTL(record *config.Record) int64 {
+	if record.TTL > 86400 || record.TTL < 1 {
+		return 600
+	}
+	return record.TTL
+}
+
+// canQuery DNS服务商是否支持查询记录，不支持时只能直接推送更新
+func (p *Provider) canQuery() bool {
+	return provider.CapabilitiesOf(p.operator).Has(provider.CapQuery | provider.CapCreate)
+}
+
+// addrString 未缓存的IP地址返回空字符串
+func addrString(addr netip.Addr) string {
+	if !addr.IsValid() {
+		return ""
+	}
+	return addr.String()
+}
+
+// sameProxied 云端代理设置是否满足配置，配置为空表示保持云端设置
+func sameProxied(current, desired *bool) bool {
+	if desired == nil {
+		return true
+	}
+	return current != nil && *current == *desired
+}
+
 // sendNotification 将通知交给受 Provider 生命周期约束的有界队列。
 func (p *Provider) sendNotification(ctx context.Context, data *webhook.WebhookData) {
 	if p.notifier == nil || p.notificationQueue == nil || data == nil {
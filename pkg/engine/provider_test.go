@@ -2,10 +2,12 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"net/netip"
 	"testing"
 	"time"
 
+	"ddns/pkg/addr"
 	"ddns/pkg/config"
 	"ddns/pkg/provider"
 	"ddns/pkg/webhook"
@@ -14,8 +16,11 @@ import (
 type fakeOperator struct {
 	getRecords []provider.Record
 	getErr     error
+	updateErr  error
+	deleteErr  error
 	created    []provider.Record
 	updated    []provider.Record
+	deleted    []string
 }
 
 func (f *fakeOperator) GetAll(context.Context, string, provider.Version) ([]provider.Record, error) {
@@ -33,29 +38,36 @@ func (f *fakeOperator) Create(_ context.Context, record *provider.Record) (*prov
 
 func (f *fakeOperator) Update(_ context.Context, record *provider.Record) error {
 	f.updated = append(f.updated, *record)
-	return nil
+	return f.updateErr
 }
 
-func (f *fakeOperator) Delete(context.Context, string, string) error { return nil }
+func (f *fakeOperator) Delete(_ context.Context, recordID, _ string) error {
+	f.deleted = append(f.deleted, recordID)
+	return f.deleteErr
+}
 
 func TestSyncToProviderCreatesAndUpdates(t *testing.T) {
 	tests := []struct {
 		name       string
 		getErr     error
 		getRecords []provider.Record
+		proxied    *bool
 		wantCreate int
 		wantUpdate int
 	}{
 		{name: "create missing record", getErr: provider.ErrRecordNotFound, wantCreate: 1},
 		{name: "update matching type", getRecords: []provider.Record{{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "1.1.1.1"}}, wantUpdate: 1},
+		{name: "skip unchanged address", getRecords: []provider.Record{{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8"}}},
+		{name: "update proxied setting", getRecords: []provider.Record{{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8", Proxied: new(bool)}}, proxied: boolPtr(true), wantUpdate: 1},
+		{name: "keep cloud proxied setting", getRecords: []provider.Record{{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8", Proxied: boolPtr(true)}}},
 		{name: "create target type when only other type exists", getRecords: []provider.Record{{RecordId: "aaaa", DomainName: "example.com", RR: "nas", Type: "AAAA", Value: "::1"}}, wantCreate: 1},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			operator := &fakeOperator{getErr: tt.getErr, getRecords: tt.getRecords}
 			instance := &Provider{provider: &config.Provider{Name: "home", Provider: "aliyun"}, operator: operator}
-			record := &config.Record{Name: "nas", IPVersion: provider.IPv4, TTL: 600}
-			if err := instance.syncToProvider(context.Background(), "nas.example.com", record, netip.MustParseAddr("8.8.8.8")); err != nil {
+			record := &config.Record{Name: "nas", IPVersion: provider.IPv4, TTL: 600, Proxied: tt.proxied}
+			if _, err := instance.syncToProvider(context.Background(), "nas.example.com", record, SubDomainInfo{}, netip.MustParseAddr("8.8.8.8")); err != nil {
 				t.Fatal(err)
 			}
 			if len(operator.created) != tt.wantCreate || len(operator.updated) != tt.wantUpdate {
@@ -65,6 +77,165 @@ func TestSyncToProviderCreatesAndUpdates(t *testing.T) {
 	}
 }
 
+func boolPtr(value bool) *bool { return &value }
+
+func TestSyncToProviderKeepsOtherMultiValueRecords(t *testing.T) {
+	others := []provider.Record{
+		{RecordId: "spf", DomainName: "example.com", RR: "nas", Type: "TXT", Value: `"v=spf1 -all"`},
+		{RecordId: "cname", DomainName: "example.com", RR: "nas", Type: "CNAME", Value: "old.example.com."},
+	}
+	tests := []struct {
+		name       string
+		record     config.Record
+		getRecords []provider.Record
+		oldAddr    netip.Addr
+		recordID   string
+		wantCreate int
+		wantUpdate string
+		wantValue  string
+	}{
+		{
+			name:       "create txt next to other txt",
+			record:     config.Record{Name: "nas", Type: "TXT", Value: "ip={{.IP}}", GetType: "url"},
+			getRecords: others,
+			wantCreate: 1,
+		},
+		{
+			name:       "skip txt already present",
+			record:     config.Record{Name: "nas", Type: "TXT", Value: "ip={{.IP}}", GetType: "url"},
+			getRecords: append([]provider.Record{{RecordId: "ip", Type: "TXT", Value: `"ip=8.8.8.8"`}}, others...),
+		},
+		{
+			name:       "update txt written last time",
+			record:     config.Record{Name: "nas", Type: "TXT", Value: "ip={{.IP}}", GetType: "url"},
+			getRecords: append([]provider.Record{{RecordId: "ip", Type: "TXT", Value: `"ip=1.1.1.1"`}}, others...),
+			oldAddr:    netip.MustParseAddr("1.1.1.1"),
+			wantUpdate: "ip",
+			wantValue:  "ip=8.8.8.8",
+		},
+		{
+			name:       "update txt by written record id",
+			record:     config.Record{Name: "nas", Type: "TXT", Value: "ip={{.IP}}", GetType: "url"},
+			getRecords: append([]provider.Record{{RecordId: "other-ip", Type: "TXT", Value: `"ip=1.1.1.1"`}, {RecordId: "ip", Type: "TXT", Value: `"ip=2.2.2.2"`}}, others...),
+			oldAddr:    netip.MustParseAddr("1.1.1.1"),
+			recordID:   "ip",
+			wantUpdate: "ip",
+			wantValue:  "ip=8.8.8.8",
+		},
+		{
+			name:       "create txt when written record was removed",
+			record:     config.Record{Name: "nas", Type: "TXT", Value: "ip={{.IP}}", GetType: "url"},
+			getRecords: append([]provider.Record{{RecordId: "other-ip", Type: "TXT", Value: `"ip=1.1.1.1"`}}, others...),
+			oldAddr:    netip.MustParseAddr("1.1.1.1"),
+			recordID:   "ip",
+			wantCreate: 1,
+		},
+		{
+			name:       "update mx priority",
+			record:     config.Record{Name: "nas", Type: "MX", Value: "mail.example.com", Priority: 10},
+			getRecords: []provider.Record{{RecordId: "mx", Type: "MX", Value: "mail.example.com.", Priority: 20}},
+			wantUpdate: "mx",
+			wantValue:  "mail.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operator := &fakeOperator{getRecords: tt.getRecords}
+			instance := &Provider{provider: &config.Provider{Name: "home", Provider: "aliyun"}, operator: operator}
+			if _, err := instance.syncToProvider(context.Background(), "nas.example.com", &tt.record, SubDomainInfo{Addr: tt.oldAddr, RecordId: tt.recordID}, netip.MustParseAddr("8.8.8.8")); err != nil {
+				t.Fatal(err)
+			}
+			if len(operator.created) != tt.wantCreate {
+				t.Fatalf("created=%d, want %d", len(operator.created), tt.wantCreate)
+			}
+			if tt.wantUpdate == "" {
+				if len(operator.updated) != 0 {
+					t.Fatalf("updated = %#v, want none", operator.updated)
+				}
+				return
+			}
+			if len(operator.updated) != 1 || operator.updated[0].RecordId != tt.wantUpdate || operator.updated[0].Value != tt.wantValue {
+				t.Fatalf("updated = %#v, want record %s", operator.updated, tt.wantUpdate)
+			}
+		})
+	}
+}
+
+type updateOnlyOperator struct {
+	fakeOperator
+}
+
+func (updateOnlyOperator) Capabilities() provider.Capability { return provider.CapUpdate }
+
+func TestSyncToProviderPushesToUpdateOnlyProvider(t *testing.T) {
+	operator := &updateOnlyOperator{fakeOperator{getErr: provider.ErrNotSupported}}
+	instance := &Provider{provider: &config.Provider{Name: "home", Provider: "dyndns2"}, operator: operator}
+	record := &config.Record{Name: "nas", IPVersion: provider.IPv6, TTL: 600}
+	if _, err := instance.syncToProvider(context.Background(), "nas.example.com", record, SubDomainInfo{}, netip.MustParseAddr("2001:db8::1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(operator.created) != 0 || len(operator.updated) != 1 {
+		t.Fatalf("created=%d updated=%d, want 0 1", len(operator.created), len(operator.updated))
+	}
+	updated := operator.updated[0]
+	if updated.RecordId != "" || updated.RR != "nas" || updated.DomainName != "example.com" || updated.Type != "AAAA" || updated.Value != "2001:db8::1" {
+		t.Fatalf("updated record = %#v", updated)
+	}
+}
+
+func TestSyncRecordPushesOnlyChangedAddress(t *testing.T) {
+	operator := &updateOnlyOperator{fakeOperator{getErr: provider.ErrNotSupported}}
+	instance := &Provider{provider: &config.Provider{Name: "home", Provider: "dyndns2"}, operator: operator}
+	current := netip.MustParseAddr("203.0.113.7")
+	record := &config.Record{Name: "nas", SubDomains: []string{"nas.example.com"}, IPVersion: provider.IPv4, TTL: 600}
+	state := &RecordState{
+		sources:        []fetchSource{{fetcher: fetcherFunc(func(context.Context) ([]netip.Addr, error) { return []netip.Addr{current}, nil })}},
+		filter:         addr.Policy{}.Filter(),
+		selector:       addr.NewSelector(""),
+		cacheSubDomain: map[string]SubDomainInfo{},
+	}
+	instance.syncRecord(context.Background(), record, state)
+	// 超过强制同步时间后地址不变，不再推送
+	cache := state.cacheSubDomain["nas.example.com"]
+	cache.LastSyncAt = time.Now().Add(-time.Hour)
+	state.cacheSubDomain["nas.example.com"] = cache
+	instance.syncRecord(context.Background(), record, state)
+	if len(operator.updated) != 1 {
+		t.Fatalf("updated=%d after unchanged address, want 1", len(operator.updated))
+	}
+	current = netip.MustParseAddr("203.0.113.8")
+	instance.syncRecord(context.Background(), record, state)
+	if len(operator.updated) != 2 || operator.updated[1].Value != "203.0.113.8" {
+		t.Fatalf("updated = %#v, want changed address pushed", operator.updated)
+	}
+}
+
+func TestSyncRecordStopsAfterPermanentError(t *testing.T) {
+	operator := &updateOnlyOperator{fakeOperator{updateErr: &provider.PermanentError{Err: errors.New("badauth")}}}
+	instance := &Provider{provider: &config.Provider{Name: "home", Provider: "dyndns2"}, operator: operator, notifier: &fakeNotificationSender{}, notificationQueue: make(chan webhook.WebhookData, 1)}
+	current := netip.MustParseAddr("203.0.113.7")
+	record := &config.Record{Name: "nas", SubDomains: []string{"nas.example.com"}, IPVersion: provider.IPv4, TTL: 600}
+	state := &RecordState{
+		sources:        []fetchSource{{fetcher: fetcherFunc(func(context.Context) ([]netip.Addr, error) { return []netip.Addr{current}, nil })}},
+		filter:         addr.Policy{}.Filter(),
+		selector:       addr.NewSelector(""),
+		cacheSubDomain: map[string]SubDomainInfo{},
+	}
+	instance.syncRecord(context.Background(), record, state)
+	if state.Rejected("nas.example.com") == nil || len(instance.notificationQueue) != 1 {
+		t.Fatal("permanent error did not stop the subdomain")
+	}
+	// 地址变化后也不再推送，也不计入失败退避
+	current = netip.MustParseAddr("203.0.113.8")
+	instance.syncRecord(context.Background(), record, state)
+	if len(operator.updated) != 1 {
+		t.Fatalf("updated=%d after permanent error, want 1", len(operator.updated))
+	}
+	if cache := state.cacheSubDomain["nas.example.com"]; cache.FailCount != 0 {
+		t.Fatalf("FailCount = %d, want 0", cache.FailCount)
+	}
+}
+
 func TestNotificationWorkerStopsWithContext(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	instance := &Provider{
@@ -4,8 +4,11 @@ import (
 	"context"
 	"ddns/pkg/addr"
 	"ddns/pkg/config"
+	"errors"
 	"fmt"
 	"net/netip"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,69 +16,408 @@ import (
 // SubDomainInfo 子域名同步缓存，以子域名为最新缓存对象。
 type SubDomainInfo struct {
 	//IP地址缓存，即上传同步的
-	Addr netip.Addr
+	Addr netip.Addr `json:"addr"`
+	// 多值记录上传同步的地址集合，Addr 为其中第一个地址
+	Addrs []netip.Addr `json:"addrs,omitempty"`
 	//上次同步的时间
-	LastSyncAt time.Time
+	LastSyncAt time.Time `json:"lastSyncAt"`
 	// API失败次数
-	FailCount int
+	FailCount int `json:"failCount"`
 	//下次重试等待间隔
-	NextRetryGap time.Duration
+	NextRetryGap time.Duration `json:"nextRetryGap"`
 	//下一次强制同步时间
-	NextForceInterval time.Duration
+	NextForceInterval time.Duration `json:"nextForceInterval"`
+	// TXT、MX 等可以有多条同名记录的类型上次写入的记录ID
+	RecordId string `json:"recordId,omitempty"`
+}
+
+// fetchSource 记录的一个获取方式，name 用于日志中显示采用了哪个获取方式，key 用于合并相同的获取方式
+type fetchSource struct {
+	name    string
+	key     string
+	fetcher addr.Fetcher
+	// timeout 获取方式自己的超时时间，如结构化命令的 command.timeout，为 0 时没有单独的超时时间
+	timeout time.Duration
 }
 
 // RecordState 管理单个 Record 的 IP 解析器与同步缓存状态
 type RecordState struct {
-	mu             sync.RWMutex
-	fetcher        addr.Fetcher
-	filter         addr.Filter
-	selector       addr.Selector
+	mu sync.RWMutex
+	// 主获取方式和备用获取方式，按顺序尝试
+	sources  []fetchSource
+	timeout  time.Duration
+	filter   addr.Filter
+	selector addr.Selector
+	// 候选地址的健康检查，为 nil 时不检查；没有筛选规则时获取到的所有地址都是候选地址
+	health        *healthChecker
+	allCandidates bool
+	// 最近一次成功获取地址的获取方式
+	source string
+	// 最近一次获取时跳过的获取方式和候选地址
+	skipped error
+	// 前缀跟踪的主机和委派前缀长度，key是子域名
+	hosts          map[string]addr.Host
+	prefixBits     int
 	cacheSubDomain map[string]SubDomainInfo
+	// 服务商永久拒绝同步的子域名和错误，不持久化，修改配置重新监听后恢复同步
+	rejected map[string]error
 	// 获取IP失败次数
 	GetAddrFailCount int
+	// 持久化的同步状态和子域名对应的状态键，为 nil 时只在内存中缓存
+	states    *stateBook
+	stateKeys map[string]string
+	// 共享获取的协调器、已经共享的获取方式和记录的检测周期，记录停止时释放
+	fetches        *fetchCoordinator
+	sharedKey      []string
+	sharedInterval time.Duration
 }
 
 func NewRecordState(config *config.Record) (*RecordState, error) {
-	fetcher, err := addr.NewFetcher(config.GetType, config.GetValue)
+	// 静态的非地址记录不需要获取IP地址
+	if !config.NeedsAddr() {
+		return &RecordState{cacheSubDomain: make(map[string]SubDomainInfo)}, nil
+	}
+	extractor, err := addr.NewExtractor(config.Extract)
+	if err != nil {
+		return nil, err
+	}
+	version, err := addr.NewFilter(config.IPVersion)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := config.Policy.Build()
 	if err != nil {
 		return nil, err
 	}
-	filter, err := addr.NewFilter(config.IPVersion)
+	// 同一地址可以出现在多个网卡上，地址属性优先使用 nic 获取方式读取的网卡
+	ifaces := config.NicInterfaces()
+	policy.Ifaces = ifaces
+	// 版本和地址策略合并为一个过滤函数，URL 一致性模式也只统计满足条件的地址
+	accept := policy.Filter()
+	filter := func(a netip.Addr) bool { return version(a) && accept(a) }
+	var sources []fetchSource
+	for i, source := range config.Sources() {
+		opts := addr.Options{Version: config.IPVersion, Accept: filter}
+		name, extract := source.GetType, ""
+		if i == 0 {
+			// 提取规则、一致性数量和结构化命令只对主获取方式生效
+			opts.Extractor, opts.Quorum, opts.Command = extractor, config.Quorum, config.Command.Build()
+			extract = config.Extract
+		} else {
+			name = fmt.Sprintf("fallbacks[%d].%s", i-1, source.GetType)
+		}
+		fetcher, err := addr.NewFetcher(source.GetType, source.GetValue, opts)
+		if err != nil {
+			return nil, err
+		}
+		var timeout time.Duration
+		if opts.Command != nil {
+			timeout = opts.Command.Timeout
+		}
+		sources = append(sources, fetchSource{name: name, key: fetchKey(source, opts, extract, config.Policy), fetcher: fetcher, timeout: timeout})
+	}
+	selector := addr.NewSelector(config.Rule, ifaces...)
+	hosts, err := config.BuildHosts()
 	if err != nil {
 		return nil, err
 	}
-	selector := addr.NewSelector(config.Rule)
 
 	return &RecordState{
-		fetcher:  fetcher,
-		filter:   filter,
-		selector: selector,
+		sources:       sources,
+		timeout:       config.FetchTimeout(),
+		filter:        filter,
+		selector:      selector,
+		health:        newHealthChecker(config.Health),
+		allCandidates: config.Rule == "",
+		hosts:         hosts,
+		prefixBits:    config.PrefixBits(),
 		//子域名缓存，key是子域名
 		cacheSubDomain: make(map[string]SubDomainInfo),
 	}, nil
 
 }
 
-// Resolve 执行 IP 获取和过滤
+// restore 从持久化状态恢复子域名缓存，之后同步状态的变化都会写回 states
+func (r *RecordState) restore(states *stateBook, keys map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = states
+	r.stateKeys = keys
+	for subDomain, key := range keys {
+		if info, ok := states.get(key); ok {
+			r.cacheSubDomain[subDomain] = info
+		}
+	}
+}
+
+// share 把获取方式替换为协调器中的共享 Fetcher，与其他记录合并相同的获取，interval 为记录的检测周期
+// 可以主动通知地址变化的获取方式（网卡、推送）读取本地状态，收到通知后需要立即获取，不共享
+func (r *RecordState) share(fetches *fetchCoordinator, interval time.Duration) {
+	if fetches == nil {
+		return
+	}
+	r.fetches = fetches
+	r.sharedInterval = interval
+	for i, source := range r.sources {
+		if _, ok := source.fetcher.(addr.Notifier); ok {
+			continue
+		}
+		r.sources[i].fetcher = fetches.acquire(source, interval)
+		r.sharedKey = append(r.sharedKey, source.key)
+	}
+}
+
+// release 释放共享的获取方式，记录停止时调用
+func (r *RecordState) release() {
+	for _, key := range r.sharedKey {
+		r.fetches.release(key, r.sharedInterval)
+	}
+	r.sharedKey = nil
+}
+
+// persistLocked 把子域名缓存写回持久化状态，调用方需持有写锁
+func (r *RecordState) persistLocked(subDomain string, info SubDomainInfo) {
+	if r.states == nil {
+		return
+	}
+	if key, ok := r.stateKeys[subDomain]; ok {
+		r.states.put(key, info)
+	}
+}
+
+// Resolve 按顺序尝试各个获取方式，返回第一个获取成功并筛选出的地址，静态记录返回无效地址
 func (r *RecordState) Resolve(ctx context.Context) (netip.Addr, error) {
-	addrs, err := r.fetcher.Fetch(ctx)
+	if len(r.sources) == 0 {
+		return netip.Addr{}, nil
+	}
+	if r.health != nil {
+		return r.resolveHealthy(ctx)
+	}
+	// 只有一个获取方式时直接返回原始错误，推送方式依赖 ErrNotReady 判断是否在等待推送
+	if len(r.sources) == 1 {
+		return r.resolveSource(ctx, r.sources[0])
+	}
+	var errs []error
+	for _, source := range r.sources {
+		current, err := r.resolveSource(ctx, source)
+		if err == nil {
+			return current, nil
+		}
+		if ctx.Err() != nil {
+			return netip.Addr{}, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+	}
+	return netip.Addr{}, errors.Join(errs...)
+}
+
+// ResolveSet 返回多值记录需要发布的地址集合，按地址排序，最多 config.MaxPublishAddrs 个
+// 没有健康检查时使用第一个获取成功的获取方式的所有地址，配置了健康检查时返回所有获取方式中检查通过的地址
+func (r *RecordState) ResolveSet(ctx context.Context) ([]netip.Addr, error) {
+	var (
+		addrs []netip.Addr
+		names []string
+		errs  []error
+	)
+	for _, source := range r.sources {
+		candidates, err := r.candidates(ctx, source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			// 推送方式依赖 ErrNotReady 判断是否在等待推送
+			if len(r.sources) == 1 {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+			continue
+		}
+		if r.health == nil {
+			addrs, names = candidates, []string{source.name}
+			break
+		}
+		healthy := 0
+		for _, candidate := range candidates {
+			if err := r.health.Check(ctx, candidate); err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("%s: %s 健康检查失败: %w", source.name, candidate, err))
+				continue
+			}
+			addrs = append(addrs, candidate)
+			healthy++
+		}
+		if healthy > 0 {
+			names = append(names, source.name)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	slices.SortFunc(addrs, netip.Addr.Compare)
+	addrs = slices.Compact(addrs)
+	if len(addrs) > config.MaxPublishAddrs {
+		addrs = addrs[:config.MaxPublishAddrs]
+	}
+
+	r.mu.Lock()
+	r.source, r.skipped = strings.Join(names, ","), errors.Join(errs...)
+	r.mu.Unlock()
+	return addrs, nil
+}
+
+// resolveSource 使用一个获取方式获取并筛选地址，成功时记录采用的获取方式
+func (r *RecordState) resolveSource(ctx context.Context, source fetchSource) (netip.Addr, error) {
+	addrs, err := r.fetchSource(ctx, source)
 	if err != nil {
 		return netip.Addr{}, err
 	}
-	addrs = addr.FilterAddrs(addrs, r.filter, addr.IsPublic)
 
 	addr := r.selector.Select(addrs)
 	if !addr.IsValid() {
-		return netip.Addr{}, fmt.Errorf("未筛选出有效的公网 IP")
+		return netip.Addr{}, fmt.Errorf("未筛选出符合地址策略的 IP")
 	}
 
+	r.mu.Lock()
+	r.source = source.name
+	r.mu.Unlock()
 	return addr, nil
 }
 
+// fetchSource 使用一个获取方式获取地址，返回符合版本和地址策略的地址
+func (r *RecordState) fetchSource(ctx context.Context, source fetchSource) ([]netip.Addr, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+	addrs, err := source.fetcher.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return addr.FilterAddrs(addrs, r.filter), nil
+}
+
+// candidates 返回一个获取方式的候选地址，配置了筛选规则时只有规则选出的地址
+func (r *RecordState) candidates(ctx context.Context, source fetchSource) ([]netip.Addr, error) {
+	addrs, err := r.fetchSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if !r.allCandidates {
+		addrs = []netip.Addr{r.selector.Select(addrs)}
+	}
+	addrs = slices.DeleteFunc(addrs, func(a netip.Addr) bool { return !a.IsValid() })
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("未筛选出符合地址策略的 IP")
+	}
+	return addrs, nil
+}
+
+// resolveHealthy 按获取方式的顺序检查候选地址，返回第一个健康检查通过的地址
+// 每次获取都从第一个候选地址开始检查，之前的地址恢复后自动切回
+func (r *RecordState) resolveHealthy(ctx context.Context) (netip.Addr, error) {
+	var errs []error
+	for _, source := range r.sources {
+		candidates, err := r.candidates(ctx, source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return netip.Addr{}, ctx.Err()
+			}
+			// 推送方式依赖 ErrNotReady 判断是否在等待推送
+			if len(r.sources) == 1 {
+				return netip.Addr{}, err
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", source.name, err))
+			continue
+		}
+		for _, candidate := range candidates {
+			if err := r.health.Check(ctx, candidate); err != nil {
+				if ctx.Err() != nil {
+					return netip.Addr{}, ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("%s: %s 健康检查失败: %w", source.name, candidate, err))
+				continue
+			}
+			r.mu.Lock()
+			r.source, r.skipped = source.name, errors.Join(errs...)
+			r.mu.Unlock()
+			return candidate, nil
+		}
+	}
+	return netip.Addr{}, errors.Join(errs...)
+}
+
+// Source 返回最近一次成功获取地址的获取方式，还没有获取成功时为空
+func (r *RecordState) Source() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.source
+}
+
+// Skipped 返回最近一次获取时因获取失败或健康检查失败而跳过的获取方式和候选地址，没有跳过时为 nil
+func (r *RecordState) Skipped() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.skipped
+}
+
+// HostAddr 返回子域名的记录地址
+// 前缀跟踪主机的子域名使用获取到的地址所在的委派前缀拼接主机的接口标识，其他子域名直接使用获取到的地址
+func (r *RecordState) HostAddr(subDomain string, current netip.Addr) (netip.Addr, error) {
+	host, ok := r.hosts[subDomain]
+	if !ok || !current.IsValid() {
+		return current, nil
+	}
+	return host.Addr(current, r.prefixBits)
+}
+
+// Changed 返回主获取方式的 IP 地址变化通知通道，获取方式不支持主动通知时返回 nil
+func (r *RecordState) Changed() <-chan struct{} {
+	if len(r.sources) == 0 {
+		return nil
+	}
+	if notifier, ok := r.sources[0].fetcher.(addr.Notifier); ok {
+		return notifier.Changed()
+	}
+	return nil
+}
+
 // ShouldSync 子域名是否需要同步处理
 // 参数：子域名，当前IP地址，最大与DNS API同步时间
 // 返回值：是否同步，剩余同步时间
 func (r *RecordState) ShouldSync(subDomain string, currentAddr netip.Addr) (bool, time.Duration) {
+	return r.shouldSync(subDomain, SubDomainInfo{Addr: currentAddr}, true)
+}
+
+// ShouldPush 只支持更新的服务商的子域名是否需要推送，无法查询云端记录，只在地址变化或重试时推送
+// 重复推送相同的地址会被 DynDNS2 等服务商视为滥用
+func (r *RecordState) ShouldPush(subDomain string, currentAddr netip.Addr) (bool, time.Duration) {
+	return r.shouldSync(subDomain, SubDomainInfo{Addr: currentAddr}, false)
+}
+
+// ShouldSyncSet 多值记录的子域名是否需要同步处理，地址集合变化时同步
+func (r *RecordState) ShouldSyncSet(subDomain string, addrs []netip.Addr) (bool, time.Duration) {
+	return r.shouldSync(subDomain, setInfo(addrs), true)
+}
+
+// setInfo 返回多值记录地址集合对应的缓存地址
+func setInfo(addrs []netip.Addr) SubDomainInfo {
+	info := SubDomainInfo{Addrs: addrs}
+	if len(addrs) > 0 {
+		info.Addr = addrs[0]
+	}
+	return info
+}
+
+// sameAddrs 缓存的地址和当前地址是否相同
+func sameAddrs(cache, current SubDomainInfo) bool {
+	return cache.Addr == current.Addr && slices.Equal(cache.Addrs, current.Addrs)
+}
+
+// shouldSync 按 current 中的地址判断子域名是否需要同步处理，force 为 false 时地址不变不强制同步
+func (r *RecordState) shouldSync(subDomain string, current SubDomainInfo, force bool) (bool, time.Duration) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -96,10 +438,15 @@ func (r *RecordState) ShouldSync(subDomain string, currentAddr netip.Addr) (bool
 	}
 
 	// IP地址变了，触发同步
-	if cache.Addr != currentAddr {
+	if !sameAddrs(cache, current) {
 		return true, 0
 	}
 
+	// 不强制同步时IP地址没变就不再处理
+	if !force {
+		return false, 0
+	}
+
 	// IP地址没变，时间到了最大同步时间，防止其他方式改变了云端记录
 	forceInterval := cache.NextForceInterval
 	if elapsed >= forceInterval {
@@ -115,6 +462,23 @@ func (r *RecordState) ShouldSync(subDomain string, currentAddr netip.Addr) (bool
 	return false, forceInterval - elapsed
 }
 
+// Reject 服务商永久拒绝同步时停止同步子域名
+func (r *RecordState) Reject(subDomain string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rejected == nil {
+		r.rejected = make(map[string]error)
+	}
+	r.rejected[subDomain] = err
+}
+
+// Rejected 返回子域名被服务商永久拒绝的错误，没有被拒绝时返回 nil
+func (r *RecordState) Rejected(subDomain string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.rejected[subDomain]
+}
+
 // GetCache 获取子域名缓存
 func (r *RecordState) GetCache(subDomain string) (SubDomainInfo, bool) {
 	r.mu.RLock()
@@ -146,11 +510,22 @@ func (r *RecordState) IncFailCount(SubDomain string, maxIntervalMinutes int64) (
 
 	//写入缓存
 	r.cacheSubDomain[SubDomain] = info
+	r.persistLocked(SubDomain, info)
 	return info.FailCount, info.NextRetryGap
 }
 
-// UpdateCache 记录同步成功后的更新缓存
-func (r *RecordState) UpdateCache(subDomain string, currentAddr netip.Addr, maxForceMinutes int64) time.Duration {
+// UpdateCache 记录同步成功后的更新缓存，recordID 为写入的记录ID，A、AAAA 等同名记录全部更新的类型为空
+func (r *RecordState) UpdateCache(subDomain string, currentAddr netip.Addr, recordID string, maxForceMinutes int64) time.Duration {
+	return r.updateCache(subDomain, SubDomainInfo{Addr: currentAddr, RecordId: recordID}, maxForceMinutes)
+}
+
+// UpdateCacheSet 多值记录同步成功后的更新缓存
+func (r *RecordState) UpdateCacheSet(subDomain string, addrs []netip.Addr, maxForceMinutes int64) time.Duration {
+	return r.updateCache(subDomain, setInfo(addrs), maxForceMinutes)
+}
+
+// updateCache 按 current 中的地址更新同步成功后的缓存
+func (r *RecordState) updateCache(subDomain string, current SubDomainInfo, maxForceMinutes int64) time.Duration {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -159,21 +534,25 @@ func (r *RecordState) UpdateCache(subDomain string, currentAddr netip.Addr, maxF
 	maxInterval := time.Duration(maxForceMinutes) * time.Minute
 	var nextInterval time.Duration
 
-	if !exists || oldCache.Addr != currentAddr {
+	if !exists || !sameAddrs(oldCache, current) {
 		nextInterval = baseInterval
 	} else {
 		nextInterval = oldCache.NextForceInterval + 1*time.Minute
 		nextInterval = min(nextInterval, maxInterval)
 	}
 
-	r.cacheSubDomain[subDomain] = SubDomainInfo{
-		Addr:       currentAddr,
+	info := SubDomainInfo{
+		Addr:       current.Addr,
+		Addrs:      current.Addrs,
 		LastSyncAt: time.Now(),
 		//成功后重置失败计数
 		FailCount:         0,
 		NextRetryGap:      0,
 		NextForceInterval: nextInterval,
+		RecordId:          current.RecordId,
 	}
+	r.cacheSubDomain[subDomain] = info
+	r.persistLocked(subDomain, info)
 
 	return nextInterval
 }
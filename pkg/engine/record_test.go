@@ -1,9 +1,17 @@
 package engine
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/netip"
+	"strings"
 	"testing"
 	"time"
+
+	"ddns/pkg/addr"
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
 )
 
 func TestRecordStateCacheAndRetry(t *testing.T) {
@@ -12,7 +20,7 @@ func TestRecordStateCacheAndRetry(t *testing.T) {
 	if need, _ := state.ShouldSync("nas.example.com", address); !need {
 		t.Fatal("first sync was not requested")
 	}
-	state.UpdateCache("nas.example.com", address, 5)
+	state.UpdateCache("nas.example.com", address, "", 5)
 	if need, _ := state.ShouldSync("nas.example.com", address); need {
 		t.Fatal("unchanged address was unexpectedly synced")
 	}
@@ -24,3 +32,92 @@ func TestRecordStateCacheAndRetry(t *testing.T) {
 		t.Fatal("retry backoff was ignored")
 	}
 }
+
+func TestRecordStateHostAddr(t *testing.T) {
+	state, err := NewRecordState(&config.Record{
+		Name: "lan", SubDomains: []string{"router.example.com", "nas.example.com"}, IPVersion: provider.IPv6,
+		GetType: "nic", GetValue: "br-lan", PrefixLength: 56,
+		Hosts: []config.PrefixHost{{SubDomain: "nas.example.com", Suffix: "::10", Subnet: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := netip.MustParseAddr("2001:db8:aa00:1::1")
+	if got, err := state.HostAddr("router.example.com", current); err != nil || got != current {
+		t.Fatalf("HostAddr(router) = %s, %v", got, err)
+	}
+	if got, err := state.HostAddr("nas.example.com", current); err != nil || got != netip.MustParseAddr("2001:db8:aa00:3::10") {
+		t.Fatalf("HostAddr(nas) = %s, %v", got, err)
+	}
+}
+
+// fetcherFunc 把函数转换为 addr.Fetcher
+type fetcherFunc func(context.Context) ([]netip.Addr, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context) ([]netip.Addr, error) { return f(ctx) }
+
+func TestRecordStateResolveFallbacks(t *testing.T) {
+	hang := fetcherFunc(func(ctx context.Context) ([]netip.Addr, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	private := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("192.168.1.2")}, nil
+	})
+	public := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("8.8.8.8")}, nil
+	})
+	state := &RecordState{
+		sources:  []fetchSource{{name: "nic", fetcher: hang}, {name: "fallbacks[0].cmd", fetcher: private}, {name: "fallbacks[1].url", fetcher: public}},
+		timeout:  20 * time.Millisecond,
+		filter:   addr.Policy{}.Filter(),
+		selector: addr.NewSelector(""),
+	}
+	got, err := state.Resolve(context.Background())
+	if err != nil || got != netip.MustParseAddr("8.8.8.8") || state.Source() != "fallbacks[1].url" {
+		t.Fatalf("Resolve() = %s, %v, source %q", got, err, state.Source())
+	}
+
+	state.sources = state.sources[:2]
+	_, err = state.Resolve(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "fallbacks[0].cmd: 未筛选出") {
+		t.Fatalf("Resolve() all failed error = %v", err)
+	}
+}
+
+func TestRecordStateResolveHealthy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	// 主线路的地址不可用，备用线路的第二个地址可用
+	wan := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("127.0.0.2")}, nil
+	})
+	wanb := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("127.0.0.3"), netip.MustParseAddr("127.0.0.1")}, nil
+	})
+	state := &RecordState{
+		sources:       []fetchSource{{name: "openwrt", fetcher: wan}, {name: "fallbacks[0].openwrt", fetcher: wanb}},
+		filter:        func(netip.Addr) bool { return true },
+		selector:      addr.NewSelector(""),
+		health:        newHealthChecker(config.HealthCheck{Type: config.HealthTCP, Port: port, Timeout: 1}),
+		allCandidates: true,
+	}
+	got, err := state.Resolve(context.Background())
+	if err != nil || got != netip.MustParseAddr("127.0.0.1") || state.Source() != "fallbacks[0].openwrt" {
+		t.Fatalf("Resolve() = %s, %v, source %q", got, err, state.Source())
+	}
+	if skipped := state.Skipped(); skipped == nil || !strings.Contains(skipped.Error(), "openwrt: 127.0.0.2 健康检查失败") {
+		t.Fatalf("Skipped() = %v", skipped)
+	}
+
+	// 配置了筛选规则时每个获取方式只有规则选出的地址
+	state.allCandidates, state.selector = false, addr.NewSelector("index@1")
+	if _, err := state.Resolve(context.Background()); err == nil || !strings.Contains(err.Error(), "127.0.0.3 健康检查失败") {
+		t.Fatalf("Resolve() all unhealthy error = %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"context"
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
+	"ddns/pkg/utils"
+	"ddns/pkg/webhook"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// syncRecordSet 同步多值记录的地址集合到DNS服务商
+func (p *Provider) syncRecordSet(ctx context.Context, record *config.Record, recordState *RecordState) {
+	logger := p.logger(record.Name)
+
+	previousSource := recordState.Source()
+	addrs, err := recordState.ResolveSet(ctx)
+	if !p.resolved(ctx, record, recordState, previousSource, addrs, err) {
+		return
+	}
+	source := recordState.Source()
+	forceInterval := p.forceInterval()
+
+	for _, subDomain := range record.SubDomains {
+		if err := recordState.Rejected(subDomain); err != nil {
+			logger.Debug("服务商已拒绝同步，修改配置后恢复", "subDomain", subDomain, "err", err)
+			continue
+		}
+		needSync, nextForceSyncIn := recordState.ShouldSyncSet(subDomain, addrs)
+		if !needSync {
+			msg := fmt.Sprintf("IP 未变，将%v秒后重获IP", record.Interval)
+			logger.Info(msg,
+				"subDomain", subDomain,
+				"IP", addrs,
+				"nextForceSyncIn", nextForceSyncIn.Truncate(time.Second))
+			continue
+		}
+
+		if err := p.syncSetToProvider(ctx, subDomain, record, addrs); err != nil {
+			var oldAddrs []netip.Addr
+			if cache, exists := recordState.GetCache(subDomain); exists {
+				oldAddrs = cache.Addrs
+			}
+			if p.rejected(ctx, record, recordState, subDomain, joinAddrs(oldAddrs), joinAddrs(addrs), err) {
+				continue
+			}
+			failCount, nextRetryGap := recordState.IncFailCount(subDomain, forceInterval)
+			msg := fmt.Sprintf("第%d次同步失败!", failCount)
+			logger.Error(msg,
+				"subDomain", subDomain,
+				"err", err,
+				"nextRetryGap", nextRetryGap.Truncate(time.Second))
+
+			//连续同步多次失败发送 webhook 通知，约每三次发送一次
+			if failCount == 1 || failCount%3 == 0 {
+				p.sendNotification(ctx, &webhook.WebhookData{
+					Domain:   subDomain,
+					OldAddr:  joinAddrs(oldAddrs),
+					NewAddr:  joinAddrs(addrs),
+					Provider: p.provider.Provider,
+					State:    fmt.Sprintf("第%d次同步失败 err: %v nextRetryGap:%v", failCount, err, nextRetryGap.Truncate(time.Second)),
+					Date:     time.Now().Format("2006-01-02 15:04:05"),
+				})
+			}
+			continue
+		}
+
+		nextForceSyncIn = recordState.UpdateCacheSet(subDomain, addrs, forceInterval)
+		logger.Info("子域名记录同步完成", "subDomain", subDomain, "IP", addrs, "source", source, "nextForceSyncIn", nextForceSyncIn.Truncate(time.Second))
+	}
+}
+
+// syncSetToProvider 按地址集合同步子域名的 A、AAAA 记录
+// 创建缺少的地址，删除多余的地址和重复的记录，已有的地址保持不变；先创建再删除，同步过程中子域名始终有记录
+// 中途失败时已经创建、删除的记录不会回滚，返回错误前仍然发送通知
+func (p *Provider) syncSetToProvider(ctx context.Context, subDomain string, record *config.Record, addrs []netip.Addr) (err error) {
+	logger := p.logger(record.Name)
+	if !provider.CapabilitiesOf(p.operator).Has(provider.CapQuery | provider.CapCreate | provider.CapDelete) {
+		return fmt.Errorf("服务商不支持多值记录，publish: all 需要查询、创建和删除记录")
+	}
+
+	ttl := recordTTL(record)
+	rr, domain, err := utils.ParseDomain(subDomain)
+	if err != nil {
+		return err
+	}
+	recordType := record.RecordType()
+
+	var resRecords []provider.Record
+	err = utils.DoWithDefaultRetry(ctx, func() error {
+		var err error
+		resRecords, err = p.operator.GetSub(ctx, subDomain, provider.VersionOf(recordType))
+		return err
+	})
+	if err != nil && !errors.Is(err, provider.ErrRecordNotFound) {
+		return err
+	}
+
+	// 云端已有的地址，值为 true 表示已经有对应的记录
+	existing := make(map[netip.Addr]bool, len(addrs))
+	for _, a := range addrs {
+		existing[a] = false
+	}
+	var oldValues []string
+	var stale []provider.Record
+	for _, resRecord := range resRecords {
+		if !strings.EqualFold(resRecord.Type, recordType) {
+			continue
+		}
+		oldValues = append(oldValues, resRecord.Value)
+		ip, err := netip.ParseAddr(resRecord.Value)
+		if found, ok := existing[ip]; err != nil || !ok || found {
+			stale = append(stale, resRecord)
+			continue
+		}
+		existing[ip] = true
+		// 已有的地址不修改记录值，只修正 TTL 和代理设置，服务商没有返回 TTL 时不修改
+		if (resRecord.TTL != 0 && resRecord.TTL != ttl) || !sameProxied(resRecord.Proxied, record.Proxied) {
+			reqRecord := resRecord
+			reqRecord.TTL = ttl
+			if record.Proxied != nil {
+				reqRecord.Proxied = record.Proxied
+			}
+			if err := utils.DoWithDefaultRetry(ctx, func() error { return p.operator.Update(ctx, &reqRecord) }); err != nil {
+				return fmt.Errorf("更新记录失败: %w", err)
+			}
+		}
+	}
+
+	var added, removed []string
+	defer func() {
+		if len(added) == 0 && len(removed) == 0 {
+			return
+		}
+		state := "更新记录集合成功"
+		if err != nil {
+			state = fmt.Sprintf("部分更新记录集合成功 err: %v", err)
+		}
+		p.sendNotification(ctx, &webhook.WebhookData{
+			Domain:   subDomain,
+			OldAddr:  strings.Join(oldValues, ","),
+			NewAddr:  joinAddrs(addrs),
+			Added:    strings.Join(added, ","),
+			Removed:  strings.Join(removed, ","),
+			Provider: p.provider.Provider,
+			State:    state,
+			Date:     time.Now().Format("2006-01-02 15:04:05"),
+		})
+	}()
+	for _, a := range addrs {
+		if existing[a] {
+			continue
+		}
+		desired := provider.Record{Type: recordType, RR: rr, DomainName: domain, Value: a.String(), TTL: ttl, Proxied: record.Proxied}
+		err := utils.DoWithDefaultRetry(ctx, func() error {
+			reqRecord := desired
+			_, createErr := p.operator.Create(ctx, &reqRecord)
+			return createErr
+		})
+		if err != nil {
+			return fmt.Errorf("创建记录失败: %w", err)
+		}
+		logger.Info("创建记录成功", "subDomain", subDomain, "type", recordType, "value", desired.Value)
+		added = append(added, desired.Value)
+	}
+	for _, resRecord := range stale {
+		recordDomain := resRecord.DomainName
+		if recordDomain == "" {
+			recordDomain = domain
+		}
+		err := utils.DoWithDefaultRetry(ctx, func() error {
+			return p.operator.Delete(ctx, resRecord.RecordId, recordDomain)
+		})
+		if err != nil {
+			return fmt.Errorf("删除记录失败: %w", err)
+		}
+		logger.Info("删除记录成功", "subDomain", subDomain, "type", recordType, "value", resRecord.Value)
+		removed = append(removed, resRecord.Value)
+	}
+	return nil
+}
+
+// joinAddrs 把地址集合转换为英文逗号分隔的字符串
+func joinAddrs(addrs []netip.Addr) string {
+	values := make([]string, len(addrs))
+	for i, a := range addrs {
+		values[i] = a.String()
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	"ddns/pkg/addr"
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
+	"ddns/pkg/webhook"
+)
+
+func TestSyncSetToProviderReconcilesRecords(t *testing.T) {
+	operator := &fakeOperator{getRecords: []provider.Record{
+		{RecordId: "stale", DomainName: "example.com", RR: "nas", Type: "A", Value: "1.1.1.1"},
+		{RecordId: "keep", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8"},
+		{RecordId: "duplicate", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8"},
+		{RecordId: "other-type", DomainName: "example.com", RR: "nas", Type: "AAAA", Value: "2001:db8::1"},
+	}}
+	instance := &Provider{provider: &config.Provider{Name: "home", Provider: "aliyun"}, operator: operator, notifier: &fakeNotificationSender{}, notificationQueue: make(chan webhook.WebhookData, 1)}
+	record := &config.Record{Name: "nas", IPVersion: provider.IPv4, Publish: config.PublishAll}
+	addrs := []netip.Addr{netip.MustParseAddr("8.8.8.8"), netip.MustParseAddr("9.9.9.9")}
+	if err := instance.syncSetToProvider(context.Background(), "nas.example.com", record, addrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(operator.created) != 1 || operator.created[0].Value != "9.9.9.9" || len(operator.updated) != 0 {
+		t.Fatalf("created=%v updated=%v", operator.created, operator.updated)
+	}
+	if !slices.Equal(operator.deleted, []string{"stale", "duplicate"}) {
+		t.Fatalf("deleted = %v", operator.deleted)
+	}
+	data := <-instance.notificationQueue
+	if data.Added != "9.9.9.9" || data.Removed != "1.1.1.1,8.8.8.8" || data.NewAddr != "8.8.8.8,9.9.9.9" {
+		t.Fatalf("webhook data = %#v", data)
+	}
+
+	// 云端已经是同一个地址集合时不修改记录，也不发送通知
+	operator = &fakeOperator{getRecords: []provider.Record{
+		{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "9.9.9.9"},
+		{RecordId: "b", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8"},
+	}}
+	instance.operator = operator
+	if err := instance.syncSetToProvider(context.Background(), "nas.example.com", record, addrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(operator.created)+len(operator.updated)+len(operator.deleted) != 0 || len(instance.notificationQueue) != 0 {
+		t.Fatalf("unchanged set was modified: %#v", operator)
+	}
+
+	// 已有地址的 TTL 与配置不同时只修改 TTL
+	operator = &fakeOperator{getRecords: []provider.Record{
+		{RecordId: "a", DomainName: "example.com", RR: "nas", Type: "A", Value: "9.9.9.9", TTL: 600},
+		{RecordId: "b", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8", TTL: 60},
+	}}
+	instance.operator = operator
+	if err := instance.syncSetToProvider(context.Background(), "nas.example.com", record, addrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(operator.updated) != 1 || operator.updated[0].RecordId != "b" || operator.updated[0].TTL != 600 || operator.updated[0].Value != "8.8.8.8" {
+		t.Fatalf("updated = %#v, want TTL of record b updated", operator.updated)
+	}
+	if len(operator.created)+len(operator.deleted) != 0 || len(instance.notificationQueue) != 0 {
+		t.Fatalf("TTL update changed the set: %#v", operator)
+	}
+
+	// 创建成功后删除失败时，返回错误前仍然通知已经创建的地址
+	operator = &fakeOperator{deleteErr: errors.New("forbidden"), getRecords: []provider.Record{
+		{RecordId: "stale", DomainName: "example.com", RR: "nas", Type: "A", Value: "1.1.1.1"},
+		{RecordId: "b", DomainName: "example.com", RR: "nas", Type: "A", Value: "8.8.8.8"},
+	}}
+	instance.operator = operator
+	if err := instance.syncSetToProvider(context.Background(), "nas.example.com", record, addrs); err == nil {
+		t.Fatal("failed delete was not reported")
+	}
+	if len(instance.notificationQueue) != 1 {
+		t.Fatal("applied changes were not notified after a failed delete")
+	}
+	data = <-instance.notificationQueue
+	if data.Added != "9.9.9.9" || data.Removed != "" || !strings.Contains(data.State, "forbidden") {
+		t.Fatalf("webhook data = %#v", data)
+	}
+
+	// 只能推送更新的服务商不能维护地址集合
+	instance.operator = &updateOnlyOperator{fakeOperator{}}
+	if err := instance.syncSetToProvider(context.Background(), "nas.example.com", record, addrs); err == nil {
+		t.Fatal("update-only provider accepted a record set")
+	}
+}
+
+func TestRecordStateResolveSet(t *testing.T) {
+	nic := fetcherFunc(func(context.Context) ([]netip.Addr, error) {
+		return []netip.Addr{netip.MustParseAddr("2001:db8::2"), netip.MustParseAddr("fd00::2"), netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::2")}, nil
+	})
+	state := &RecordState{
+		sources:        []fetchSource{{name: "nic", fetcher: nic}, {name: "fallbacks[0].url", fetcher: nic}},
+		filter:         addr.Policy{}.Filter(),
+		selector:       addr.NewSelector(""),
+		allCandidates:  true,
+		cacheSubDomain: map[string]SubDomainInfo{},
+	}
+	addrs, err := state.ResolveSet(context.Background())
+	want := []netip.Addr{netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("2001:db8::2")}
+	if err != nil || !slices.Equal(addrs, want) || state.Source() != "nic" {
+		t.Fatalf("ResolveSet() = %v, %v, source %q", addrs, err, state.Source())
+	}
+
+	state.UpdateCacheSet("nas.example.com", addrs, 15)
+	if need, _ := state.ShouldSyncSet("nas.example.com", slices.Clone(addrs)); need {
+		t.Fatal("unchanged set was synced")
+	}
+	if need, _ := state.ShouldSyncSet("nas.example.com", addrs[:1]); !need {
+		t.Fatal("changed set was not synced")
+	}
+	if need, _ := state.ShouldSync("nas.example.com", addrs[0]); !need {
+		t.Fatal("single address matched a cached set")
+	}
+}
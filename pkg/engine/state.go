@@ -0,0 +1,179 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"ddns/pkg/config"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// stateFileVersion 状态文件格式版本，格式不兼容时丢弃旧状态
+const stateFileVersion = 1
+
+// StateStore 子域名同步状态的持久化接口
+// Engine 启动时加载一次，之后每次同步状态变化时保存全部状态
+type StateStore interface {
+	Load() (map[string]SubDomainInfo, error)
+	Save(states map[string]SubDomainInfo) error
+}
+
+// StatePath 返回配置文件旁边的状态文件路径，如 config.yaml 对应 config.state.json
+func StatePath(configPath string) string {
+	return strings.TrimSuffix(configPath, filepath.Ext(configPath)) + ".state.json"
+}
+
+// FileStateStore 把同步状态保存为 JSON 文件
+type FileStateStore struct {
+	path string
+}
+
+// NewFileStateStore 创建一个 JSON 文件状态存储
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{path: path}
+}
+
+// stateFile 状态文件的 JSON 格式
+type stateFile struct {
+	Version    int                      `json:"version"`
+	SubDomains map[string]SubDomainInfo `json:"subDomains"`
+}
+
+// Load 读取状态文件，文件不存在时返回空状态
+func (s *FileStateStore) Load() (map[string]SubDomainInfo, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]SubDomainInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取状态文件失败: %w", err)
+	}
+	var file stateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %w", err)
+	}
+	if file.Version != stateFileVersion || file.SubDomains == nil {
+		return map[string]SubDomainInfo{}, nil
+	}
+	return file.SubDomains, nil
+}
+
+// Save 先写入临时文件再重命名，避免程序中途退出留下不完整的状态文件
+func (s *FileStateStore) Save(states map[string]SubDomainInfo) error {
+	data, err := json.MarshalIndent(stateFile{Version: stateFileVersion, SubDomains: states}, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return fmt.Errorf("写入状态文件失败: %w", err)
+	}
+	return nil
+}
+
+// stateBook 所有 RecordState 共享的同步状态，Engine 热重载时保留，并在变化时写入 StateStore
+type stateBook struct {
+	mu     sync.Mutex
+	store  StateStore
+	states map[string]SubDomainInfo
+}
+
+// newStateBook 从 store 加载已保存的状态，store 为 nil 时只在内存中保存
+func newStateBook(store StateStore) *stateBook {
+	book := &stateBook{store: store, states: make(map[string]SubDomainInfo)}
+	if store == nil {
+		return book
+	}
+	states, err := store.Load()
+	if err != nil {
+		slog.Warn("加载同步状态失败，所有子域名将重新同步", "err", err)
+		return book
+	}
+	book.states = states
+	slog.Info("已加载同步状态", "count", len(states))
+	return book
+}
+
+func (b *stateBook) get(key string) (SubDomainInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	info, ok := b.states[key]
+	return info, ok
+}
+
+func (b *stateBook) put(key string, info SubDomainInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.states[key] = info
+	b.saveLocked()
+}
+
+// retain 只保留当前配置仍在使用的状态，删除的记录和修改过的记录不再保留旧状态
+func (b *stateBook) retain(keys map[string]struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	removed := false
+	for key := range b.states {
+		if _, ok := keys[key]; !ok {
+			delete(b.states, key)
+			removed = true
+		}
+	}
+	if removed {
+		b.saveLocked()
+	}
+}
+
+func (b *stateBook) saveLocked() {
+	if b.store == nil {
+		return
+	}
+	if err := b.store.Save(b.states); err != nil {
+		slog.Warn("保存同步状态失败", "err", err)
+	}
+}
+
+// stateKey 返回子域名同步状态的键
+// 键中包含影响云端记录内容的配置摘要，修改这些配置后旧状态失效，下次检测时立即同步
+func stateKey(p *config.Provider, record *config.Record, subDomain string) string {
+	proxied := ""
+	if record.Proxied != nil {
+		proxied = fmt.Sprint(*record.Proxied)
+	}
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%s\x00%s\x00%d\x00%d\x00%d\x00%d\x00%s",
+		p.Provider, p.Server, p.KeyID, record.Value, record.Priority, record.Weight, record.Port, record.TTL, proxied))
+	return fmt.Sprintf("%s/%s/%s/%x", p.Name, subDomain, record.RecordType(), sum[:4])
+}
+
+// stateKeys 返回配置中所有子域名的状态键
+func stateKeys(cfg *config.Config) map[string]struct{} {
+	keys := make(map[string]struct{})
+	for i := range cfg.Providers {
+		p := &cfg.Providers[i]
+		for j := range p.Records {
+			for _, subDomain := range p.Records[j].SubDomains {
+				keys[stateKey(p, &p.Records[j], subDomain)] = struct{}{}
+			}
+		}
+	}
+	return keys
+}
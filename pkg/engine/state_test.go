@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
+)
+
+func TestFileStateStoreRoundTrip(t *testing.T) {
+	store := NewFileStateStore(StatePath(filepath.Join(t.TempDir(), "config.yaml")))
+	states, err := store.Load()
+	if err != nil || len(states) != 0 {
+		t.Fatalf("Load() of missing file = %v, %v", states, err)
+	}
+	want := SubDomainInfo{
+		Addr:              netip.MustParseAddr("2001:db8::1"),
+		LastSyncAt:        time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		FailCount:         2,
+		NextRetryGap:      time.Minute,
+		NextForceInterval: 3 * time.Minute,
+	}
+	if err := store.Save(map[string]SubDomainInfo{"home/nas.example.com/AAAA/00": want}); err != nil {
+		t.Fatal(err)
+	}
+	states, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := states["home/nas.example.com/AAAA/00"]
+	if got.Addr != want.Addr || !got.LastSyncAt.Equal(want.LastSyncAt) || got.FailCount != want.FailCount ||
+		got.NextRetryGap != want.NextRetryGap || got.NextForceInterval != want.NextForceInterval {
+		t.Fatalf("Load() = %#v, want %#v", got, want)
+	}
+}
+
+func TestRecordStateRestoresPersistedState(t *testing.T) {
+	store := NewFileStateStore(filepath.Join(t.TempDir(), "state.json"))
+	p := &config.Provider{Name: "home", Provider: "aliyun"}
+	record := &config.Record{Name: "nas", SubDomains: []string{"nas.example.com"}, IPVersion: provider.IPv4, TTL: 600}
+	keys := map[string]string{"nas.example.com": stateKey(p, record, "nas.example.com")}
+	address := netip.MustParseAddr("8.8.8.8")
+
+	first := &RecordState{cacheSubDomain: map[string]SubDomainInfo{}}
+	first.restore(newStateBook(store), keys)
+	first.UpdateCache("nas.example.com", address, "", 15)
+
+	// 模拟重启：重新从文件加载
+	restarted := &RecordState{cacheSubDomain: map[string]SubDomainInfo{}}
+	restarted.restore(newStateBook(store), keys)
+	if need, _ := restarted.ShouldSync("nas.example.com", address); need {
+		t.Fatal("restored state did not suppress the sync of an unchanged address")
+	}
+
+	// 修改影响记录内容的配置后旧状态失效
+	changed := *record
+	changed.TTL = 60
+	book := newStateBook(store)
+	book.retain(map[string]struct{}{stateKey(p, &changed, "nas.example.com"): {}})
+	if states, _ := store.Load(); len(states) != 0 {
+		t.Fatalf("stale states were kept: %v", states)
+	}
+}
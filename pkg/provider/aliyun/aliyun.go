@@ -34,6 +34,20 @@ type Aliyun struct {
 	AccessKeySecret string
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "aliyun",
+		Label: "阿里云",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "AccessKey ID", Required: true},
+			{Key: provider.FieldKeySecret, Label: "AccessKey Secret", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewAliyun(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 // NewAliyun 新建阿里云DNS
 // 参数说明：
 // AccessKeyId和AccessKeySecret：阿里云密钥
@@ -208,8 +222,12 @@ func (a *Aliyun) addAndUpdate(ctx context.Context, r *provider.Record) error {
 	}
 	body["Type"] = r.Type
 	body["RR"] = r.RR
-	body["Value"] = r.Value
+	// SRV 的优先级、权重、端口写在记录值中，MX 优先级使用 Priority 参数
+	body["Value"] = provider.InlineValue(*r, false)
 	body["TTL"] = r.TTL
+	if r.Type == provider.TypeMX {
+		body["Priority"] = r.Priority
+	}
 
 	req.headers["content-type"] = "application/x-www-form-urlencoded"
 	str := formDataToString(body)
@@ -316,6 +334,7 @@ func parseResponse(resp []byte) ([]provider.Record, error) {
 				Type       string `json:"Type"`
 				Value      string `json:"Value"`
 				TTL        int64  `json:"TTL"`
+				Priority   int    `json:"Priority"`
 			} `json:"Record"`
 		} `json:"DomainRecords"`
 	}
@@ -332,14 +351,18 @@ func parseResponse(resp []byte) ([]provider.Record, error) {
 	//使用make预分配内存，减少append内存扩容
 	records := make([]provider.Record, 0, len(respData.DomainRecords.Record))
 	for _, r := range respData.DomainRecords.Record {
-		records = append(records, provider.Record{
+		record := provider.Record{
 			RecordId:   r.RecordId,
 			DomainName: r.DomainName,
 			RR:         r.RR,
 			Type:       r.Type,
 			Value:      r.Value,
 			TTL:        r.TTL,
-		})
+			Priority:   r.Priority,
+		}
+		// 无法解析的 SRV 记录保留原值，同步时会被视为不同的记录
+		_ = provider.ParseInlineValue(&record, false)
+		records = append(records, record)
 	}
 	if len(records) == 0 {
 		return nil, fmt.Errorf("parseResponse: 没有解析到域名记录 ， API返回: %s", provider.ResponseBodySummary(resp, false))
@@ -0,0 +1,16 @@
+package all
+
+// 导入所有内置的 DNS 服务商，导入后即完成注册
+// 第三方服务商可以在自己的包中调用 provider.Register，再在 main 中导入
+
+import (
+	_ "ddns/pkg/provider/aliyun"
+	_ "ddns/pkg/provider/baidu"
+	_ "ddns/pkg/provider/cloudflare"
+	_ "ddns/pkg/provider/dnsla"
+	_ "ddns/pkg/provider/dyndns2"
+	_ "ddns/pkg/provider/huawei"
+	_ "ddns/pkg/provider/rfc2136"
+	_ "ddns/pkg/provider/tencent"
+	_ "ddns/pkg/provider/volcengine"
+)
@@ -20,6 +20,20 @@ type Baidu struct {
 	SecretAccessKey string
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "baidu",
+		Label: "百度云",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "Access Key ID", Required: true},
+			{Key: provider.FieldKeySecret, Label: "Secret Access Key", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewBaidu(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 func NewBaidu(accessKeyId, secretAccessKey string) *Baidu {
 	return &Baidu{AccessKeyId: accessKeyId, SecretAccessKey: secretAccessKey}
 }
@@ -195,8 +209,13 @@ func recordDomain(record *provider.Record) string {
 	return record.DomainName
 }
 
+// recordPayload 生成记录请求体，SRV 的优先级、权重、端口写在记录值中，MX 优先级使用 priority 字段
 func recordPayload(record *provider.Record) map[string]any {
-	return map[string]any{"rr": record.RR, "type": record.Type, "value": record.Value, "ttl": record.TTL, "line": "default"}
+	payload := map[string]any{"rr": record.RR, "type": record.Type, "value": provider.InlineValue(*record, false), "ttl": record.TTL, "line": "default"}
+	if record.Type == provider.TypeMX {
+		payload["priority"] = record.Priority
+	}
+	return payload
 }
 
 func parseResponse(body []byte, domain, recordType string) ([]provider.Record, error) {
@@ -208,6 +227,7 @@ func parseResponse(body []byte, domain, recordType string) ([]provider.Record, e
 			Type     string `json:"type"`
 			Value    string `json:"value"`
 			TTL      int64  `json:"ttl"`
+			Priority int    `json:"priority"`
 		} `json:"records"`
 		Result struct {
 			Records []struct {
@@ -217,6 +237,7 @@ func parseResponse(body []byte, domain, recordType string) ([]provider.Record, e
 				Type     string `json:"type"`
 				Value    string `json:"value"`
 				TTL      int64  `json:"ttl"`
+				Priority int    `json:"priority"`
 			} `json:"records"`
 		} `json:"result"`
 	}
@@ -239,7 +260,9 @@ func parseResponse(body []byte, domain, recordType string) ([]provider.Record, e
 		if recordID == "" {
 			recordID = record.ID
 		}
-		result = append(result, provider.Record{RecordId: recordID, DomainName: domain, RR: record.RR, Type: record.Type, Value: record.Value, TTL: record.TTL})
+		item := provider.Record{RecordId: recordID, DomainName: domain, RR: record.RR, Type: record.Type, Value: record.Value, TTL: record.TTL, Priority: record.Priority}
+		_ = provider.ParseInlineValue(&item, false)
+		result = append(result, item)
 	}
 	if len(result) == 0 {
 		return nil, provider.ErrRecordNotFound
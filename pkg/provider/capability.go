@@ -0,0 +1,41 @@
+package provider
+
+import "errors"
+
+// ErrNotSupported 服务商不支持该操作，例如只能更新的 DynDNS2 协议不支持查询和删除
+var ErrNotSupported = errors.New("operation not supported")
+
+// Capability 服务商支持的操作，按位组合
+type Capability uint8
+
+const (
+	// CapQuery 支持查询记录（GetAll、GetSub）
+	CapQuery Capability = 1 << iota
+	// CapCreate 支持创建记录
+	CapCreate
+	// CapUpdate 支持更新记录
+	CapUpdate
+	// CapDelete 支持删除记录
+	CapDelete
+
+	// CapAll 完整的增删改查能力，没有声明能力的服务商默认拥有
+	CapAll = CapQuery | CapCreate | CapUpdate | CapDelete
+)
+
+// Has 是否包含全部指定能力
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// CapabilityDeclarer 服务商声明自身能力的可选接口
+type CapabilityDeclarer interface {
+	Capabilities() Capability
+}
+
+// CapabilitiesOf 获取服务商能力，没有实现 CapabilityDeclarer 时视为 CapAll
+func CapabilitiesOf(v any) Capability {
+	if declarer, ok := v.(CapabilityDeclarer); ok {
+		return declarer.Capabilities()
+	}
+	return CapAll
+}
@@ -0,0 +1,456 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"ddns/pkg/provider"
+	"ddns/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// API 地址
+	endpoint    = "https://api.cloudflare.com/client/v4"
+	contentType = "application/json; charset=utf-8"
+	// Cloudflare 中 TTL 为 1 表示自动
+	autoTTL = 1
+	// 非自动 TTL 的最小值
+	minTTL = 60
+)
+
+// Cloudflare Cloudflare DNS，使用带 Zone.DNS 编辑权限的 API Token 认证
+type Cloudflare struct {
+	APIToken string
+
+	baseURL       string
+	zoneIDCacheMu sync.RWMutex
+	zoneIDCache   map[string]string
+}
+
+func init() {
+	// Cloudflare 只使用 API Token，保存在 keySecret 中
+	provider.Register(provider.Registration{
+		Name:  "cloudflare",
+		Label: "Cloudflare",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeySecret, Label: "API Token", Placeholder: "需要 Zone:Read 和 DNS:Edit 权限", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewCloudflare(c.KeySecret), nil
+		},
+	})
+}
+
+// NewCloudflare 新建 Cloudflare DNS
+// 参数说明：
+// apiToken：Cloudflare API Token，需要 Zone:Read 和 DNS:Edit 权限
+func NewCloudflare(apiToken string) *Cloudflare {
+	return &Cloudflare{APIToken: apiToken, baseURL: endpoint, zoneIDCache: make(map[string]string)}
+}
+
+// GetAll 获取所有域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// domain: 域名，例如example.com
+// provider.Version: IP地址版本，所有/4/6
+// 返回值：[]provider.Record: 记录列表，error: 错误信息，ErrRecordNotFound:没有记录
+func (c *Cloudflare) GetAll(ctx context.Context, domain string, v provider.Version) ([]provider.Record, error) {
+	if err := c.validate(domain); err != nil {
+		return nil, fmt.Errorf("Cloudflare GetAll: %w", err)
+	}
+	return c.list(ctx, domain, "", v)
+}
+
+// GetSub 获取子域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// subdomain: 子域名，例如www.example.com
+// provider.Version: IP地址版本，4/6
+// 返回值：[]provider.Record: 记录列表，error: 错误信息，ErrRecordNotFound:没有记录
+func (c *Cloudflare) GetSub(ctx context.Context, subdomain string, v provider.Version) ([]provider.Record, error) {
+	if err := c.validate(subdomain); err != nil {
+		return nil, fmt.Errorf("Cloudflare GetSub: %w", err)
+	}
+	_, domain, err := utils.ParseDomain(subdomain)
+	if err != nil {
+		return nil, err
+	}
+	return c.list(ctx, domain, subdomain, v)
+}
+
+func (c *Cloudflare) list(ctx context.Context, domain, name string, v provider.Version) ([]provider.Record, error) {
+	zoneID, err := c.resolveZoneID(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("page", "1")
+	query.Set("per_page", "100")
+	if name != "" {
+		query.Set("name", name)
+	}
+	if recordType := v.RecordType(); recordType != "" {
+		query.Set("type", recordType)
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/zones/"+url.PathEscape(zoneID)+"/dns_records", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecordListResponse(resp, domain)
+}
+
+// Create 创建域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// Record: 记录信息，必传DomainName、RR、Type、Value，Proxied 为空时使用 Cloudflare 默认值（不代理）
+func (c *Cloudflare) Create(ctx context.Context, r *provider.Record) (*provider.Record, error) {
+	if r == nil {
+		return nil, fmt.Errorf("Cloudflare Create: record 为空")
+	}
+	if err := c.validate(r.DomainName); err != nil {
+		return nil, fmt.Errorf("Cloudflare Create: %w", err)
+	}
+	if r.RR == "" || r.Type == "" || r.Value == "" {
+		return nil, fmt.Errorf("Cloudflare Create: 记录参数不完整")
+	}
+
+	payload, err := recordPayload(r)
+	if err != nil {
+		return nil, fmt.Errorf("Cloudflare Create: %w", err)
+	}
+	zoneID, err := c.resolveZoneID(ctx, r.DomainName)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/zones/"+url.PathEscape(zoneID)+"/dns_records", nil, payload)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parseSuccessfulResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("Cloudflare Create: %w", err)
+	}
+	var data struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(result.Result, &data); err != nil {
+		return nil, fmt.Errorf("Cloudflare Create: 响应解析失败: %w", err)
+	}
+	if data.ID == "" {
+		return nil, fmt.Errorf("Cloudflare Create: 创建记录失败，未返回 RecordId，err: %s", provider.ResponseBodySummary(resp, false))
+	}
+	r.RecordId = data.ID
+	return r, nil
+}
+
+// Update 更新域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// Record: 记录信息，必传RecordId、DomainName、RR、Type、Value，Proxied 为空时保持云端代理设置
+func (c *Cloudflare) Update(ctx context.Context, r *provider.Record) error {
+	if r == nil || r.RecordId == "" {
+		return fmt.Errorf("Cloudflare Update: RecordId 为空")
+	}
+	if err := c.validate(r.DomainName); err != nil {
+		return fmt.Errorf("Cloudflare Update: %w", err)
+	}
+	if r.RR == "" || r.Type == "" || r.Value == "" {
+		return fmt.Errorf("Cloudflare Update: 记录参数不完整")
+	}
+
+	payload, err := recordPayload(r)
+	if err != nil {
+		return fmt.Errorf("Cloudflare Update: %w", err)
+	}
+	zoneID, err := c.resolveZoneID(ctx, r.DomainName)
+	if err != nil {
+		return err
+	}
+	// 使用 PATCH，未提交的字段（例如 proxied）保持云端原值
+	resp, err := c.do(ctx, http.MethodPatch, "/zones/"+url.PathEscape(zoneID)+"/dns_records/"+url.PathEscape(r.RecordId), nil, payload)
+	if err != nil {
+		return err
+	}
+	if _, err := parseSuccessfulResponse(resp); err != nil {
+		return fmt.Errorf("Cloudflare Update: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// recordId: 记录ID，domain: 记录所在的主域名
+func (c *Cloudflare) Delete(ctx context.Context, recordId, domain string) error {
+	if recordId == "" || domain == "" {
+		return fmt.Errorf("Cloudflare Delete: RecordId 或 domain 为空")
+	}
+	if err := c.validate(domain); err != nil {
+		return fmt.Errorf("Cloudflare Delete: %w", err)
+	}
+	zoneID, err := c.resolveZoneID(ctx, domain)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, http.MethodDelete, "/zones/"+url.PathEscape(zoneID)+"/dns_records/"+url.PathEscape(recordId), nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := parseSuccessfulResponse(resp); err != nil {
+		return fmt.Errorf("Cloudflare Delete: %w", err)
+	}
+	return nil
+}
+
+func (c *Cloudflare) do(ctx context.Context, method, path string, query url.Values, payload any) ([]byte, error) {
+	requestURL := c.baseURL + path
+	if encodedQuery := query.Encode(); encodedQuery != "" {
+		requestURL += "?" + encodedQuery
+	}
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("Cloudflare 请求体序列化失败: %w", err)
+		}
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.APIToken)
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Accept", "application/json")
+	resp, err := provider.HTTPClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		responseSummary, err := provider.ReadErrorResponseBody(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		// 尝试解析错误 body，解析失败时把原始内容返回
+		var response apiResponse
+		if json.Unmarshal([]byte(responseSummary), &response) == nil && len(response.Errors) > 0 {
+			return nil, fmt.Errorf("Cloudflare API 返回 HTTP %d: %s", resp.StatusCode, response.errorMessage())
+		}
+		return nil, fmt.Errorf("Cloudflare API 返回 HTTP %d: %s", resp.StatusCode, responseSummary)
+	}
+	return provider.ReadResponseBody(resp.Body)
+}
+
+func (c *Cloudflare) validate(domain string) error {
+	if c.APIToken == "" {
+		return fmt.Errorf("APIToken 为空")
+	}
+	if domain == "" {
+		return fmt.Errorf("domain 为空")
+	}
+	return nil
+}
+
+// resolveZoneID 根据主域名查询 Zone ID，查询结果会被缓存
+func (c *Cloudflare) resolveZoneID(ctx context.Context, domain string) (string, error) {
+	if domain == "" {
+		return "", fmt.Errorf("Cloudflare resolveZoneID: domain 为空")
+	}
+	c.zoneIDCacheMu.RLock()
+	cachedID := c.zoneIDCache[domain]
+	c.zoneIDCacheMu.RUnlock()
+	if cachedID != "" {
+		return cachedID, nil
+	}
+
+	query := url.Values{}
+	query.Set("name", domain)
+	resp, err := c.do(ctx, http.MethodGet, "/zones", query, nil)
+	if err != nil {
+		return "", fmt.Errorf("Cloudflare 获取 Zone ID 失败: %w", err)
+	}
+	id, err := parseZoneIDResponse(resp, domain)
+	if err != nil {
+		return "", fmt.Errorf("Cloudflare 获取 Zone ID 失败: %w", err)
+	}
+
+	c.zoneIDCacheMu.Lock()
+	if c.zoneIDCache == nil {
+		c.zoneIDCache = make(map[string]string)
+	}
+	c.zoneIDCache[domain] = id
+	c.zoneIDCacheMu.Unlock()
+	return id, nil
+}
+
+type apiResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+func (r apiResponse) errorMessage() string {
+	messages := make([]string, 0, len(r.Errors))
+	for _, item := range r.Errors {
+		messages = append(messages, fmt.Sprintf("code=%d, msg=%s", item.Code, item.Message))
+	}
+	return provider.ErrorSummary(strings.Join(messages, "; "))
+}
+
+func parseSuccessfulResponse(body []byte) (apiResponse, error) {
+	var response apiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return apiResponse{}, fmt.Errorf("Cloudflare 响应解析失败: %w", err)
+	}
+	if !response.Success {
+		if len(response.Errors) > 0 {
+			return apiResponse{}, fmt.Errorf("Cloudflare API 返回业务错误: %s", response.errorMessage())
+		}
+		return apiResponse{}, fmt.Errorf("Cloudflare API 返回业务错误: %s", provider.ResponseBodySummary(body, false))
+	}
+	return response, nil
+}
+
+func parseZoneIDResponse(body []byte, domain string) (string, error) {
+	response, err := parseSuccessfulResponse(body)
+	if err != nil {
+		return "", err
+	}
+	var zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(response.Result, &zones); err != nil {
+		return "", fmt.Errorf("Cloudflare Zone 列表响应解析失败: %w", err)
+	}
+	for _, zone := range zones {
+		if strings.EqualFold(zone.Name, domain) && zone.ID != "" {
+			return zone.ID, nil
+		}
+	}
+	return "", fmt.Errorf("Cloudflare 未找到域名 %q 的 Zone ID", domain)
+}
+
+func parseRecordListResponse(body []byte, domain string) ([]provider.Record, error) {
+	response, err := parseSuccessfulResponse(body)
+	if err != nil {
+		return nil, err
+	}
+	var items []struct {
+		ID       string     `json:"id"`
+		Name     string     `json:"name"`
+		Type     string     `json:"type"`
+		Content  string     `json:"content"`
+		TTL      int64      `json:"ttl"`
+		Proxied  *bool      `json:"proxied"`
+		Priority int        `json:"priority"`
+		Data     recordData `json:"data"`
+	}
+	if err := json.Unmarshal(response.Result, &items); err != nil {
+		return nil, fmt.Errorf("Cloudflare 记录列表响应解析失败: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, provider.ErrRecordNotFound
+	}
+	records := make([]provider.Record, 0, len(items))
+	for _, item := range items {
+		record := provider.Record{
+			RecordId:   item.ID,
+			DomainName: domain,
+			RR:         recordRR(item.Name, domain),
+			Type:       item.Type,
+			Value:      item.Content,
+			TTL:        item.TTL,
+			Proxied:    item.Proxied,
+			Priority:   item.Priority,
+		}
+		// SRV 和 CAA 的各字段在 data 中返回
+		switch item.Type {
+		case provider.TypeSRV:
+			record.Priority, record.Weight, record.Port, record.Value = item.Data.Priority, item.Data.Weight, item.Data.Port, item.Data.Target
+		case provider.TypeCAA:
+			record.Value = provider.CAAValue(item.Data.Flags, item.Data.Tag, item.Data.Value)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// recordData Cloudflare 返回的 SRV 和 CAA 记录结构化数据
+type recordData struct {
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+	Port     int    `json:"port"`
+	Target   string `json:"target"`
+	Flags    int    `json:"flags"`
+	Tag      string `json:"tag"`
+	Value    string `json:"value"`
+}
+
+// recordPayload 生成创建和更新记录的请求体，Proxied 为空时不提交该字段
+// MX 优先级使用 priority 字段，SRV 和 CAA 使用 data 提交结构化数据
+func recordPayload(r *provider.Record) (map[string]any, error) {
+	payload := map[string]any{
+		"type": r.Type,
+		"name": recordName(r.RR, r.DomainName),
+		"ttl":  recordTTL(r.TTL),
+	}
+	switch r.Type {
+	case provider.TypeMX:
+		payload["content"] = r.Value
+		payload["priority"] = r.Priority
+	case provider.TypeSRV:
+		payload["data"] = map[string]any{"priority": r.Priority, "weight": r.Weight, "port": r.Port, "target": r.Value}
+	case provider.TypeCAA:
+		flags, tag, value, err := provider.ParseCAA(r.Value)
+		if err != nil {
+			return nil, err
+		}
+		payload["data"] = map[string]any{"flags": flags, "tag": tag, "value": value}
+	default:
+		payload["content"] = r.Value
+	}
+	// 只有地址和 CNAME 记录可以开启代理
+	if r.Proxied != nil && (r.Type == provider.TypeA || r.Type == provider.TypeAAAA || r.Type == provider.TypeCNAME) {
+		payload["proxied"] = *r.Proxied
+	}
+	return payload, nil
+}
+
+// recordName 把 RR 和主域名拼接为 Cloudflare 使用的完整记录名
+func recordName(rr, domain string) string {
+	if rr == "" || rr == "@" {
+		return domain
+	}
+	return rr + "." + domain
+}
+
+// recordRR 把 Cloudflare 返回的完整记录名转换为 RR
+func recordRR(name, domain string) string {
+	name = strings.TrimSuffix(name, ".")
+	if strings.EqualFold(name, domain) {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+domain)
+}
+
+// recordTTL Cloudflare 只接受 1（自动）或 60-86400 秒
+func recordTTL(ttl int64) int64 {
+	if ttl == autoTTL || ttl >= minTTL {
+		return ttl
+	}
+	if ttl <= 0 {
+		return autoTTL
+	}
+	return minTTL
+}
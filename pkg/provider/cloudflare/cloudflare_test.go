@@ -0,0 +1,198 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"ddns/pkg/provider"
+)
+
+func newTestCloudflare(t *testing.T, handler http.HandlerFunc) *Cloudflare {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c := NewCloudflare("token")
+	c.baseURL = server.URL
+	return c
+}
+
+func TestDoRejectsNonSuccessAndOversizedResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "non success", status: http.StatusBadRequest, body: strings.Repeat("x", provider.MaxErrorResponseBodyBytes+1), want: "[truncated]"},
+		{name: "api errors", status: http.StatusForbidden, body: `{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`, want: "Authentication error"},
+		{name: "oversized", status: http.StatusOK, body: strings.Repeat("x", provider.MaxResponseBodyBytes+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCloudflare(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			})
+			_, err := c.do(context.Background(), http.MethodGet, "/zones", nil, nil)
+			if err == nil {
+				t.Fatal("do() succeeded")
+			}
+			if tt.want != "" && !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("do() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecordListResponse(t *testing.T) {
+	records, err := parseRecordListResponse([]byte(`{"success":true,"errors":[],"result":[
+		{"id":"r1","name":"www.example.com","type":"A","content":"1.2.3.4","ttl":1,"proxied":true},
+		{"id":"r2","name":"example.com","type":"AAAA","content":"2001:db8::1","ttl":300,"proxied":false}
+	]}`), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].RR != "www" || records[1].RR != "@" {
+		t.Fatalf("parseRecordListResponse() = %#v", records)
+	}
+	if records[0].Proxied == nil || !*records[0].Proxied || records[1].Proxied == nil || *records[1].Proxied {
+		t.Fatalf("proxied flags were not parsed: %#v", records)
+	}
+	if _, err := parseRecordListResponse([]byte(`{"success":true,"result":[]}`), "example.com"); err != provider.ErrRecordNotFound {
+		t.Fatalf("empty result error = %v, want ErrRecordNotFound", err)
+	}
+	if _, err := parseRecordListResponse([]byte(`{"success":false,"errors":[{"code":7003,"message":"bad zone"}]}`), "example.com"); err == nil || !strings.Contains(err.Error(), "bad zone") {
+		t.Fatalf("business error = %v", err)
+	}
+}
+
+func TestStructuredRecordData(t *testing.T) {
+	records, err := parseRecordListResponse([]byte(`{"success":true,"result":[
+		{"id":"mx","name":"example.com","type":"MX","content":"mail.example.com","priority":10,"ttl":1},
+		{"id":"srv","name":"_minecraft._tcp.example.com","type":"SRV","content":"5 25565 mc.example.com","priority":0,"data":{"priority":0,"weight":5,"port":25565,"target":"mc.example.com"},"ttl":1},
+		{"id":"caa","name":"example.com","type":"CAA","content":"0 issue \"letsencrypt.org\"","data":{"flags":0,"tag":"issue","value":"letsencrypt.org"},"ttl":1}
+	]}`), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if records[0].Priority != 10 || records[0].Value != "mail.example.com" {
+		t.Fatalf("MX record = %#v", records[0])
+	}
+	if records[1].Weight != 5 || records[1].Port != 25565 || records[1].Value != "mc.example.com" || records[1].RR != "_minecraft._tcp" {
+		t.Fatalf("SRV record = %#v", records[1])
+	}
+	if records[2].Value != `0 issue "letsencrypt.org"` {
+		t.Fatalf("CAA record = %#v", records[2])
+	}
+
+	payload, err := recordPayload(&records[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(payload)
+	if !strings.Contains(string(data), `"data":{"port":25565,"priority":0,"target":"mc.example.com","weight":5}`) || strings.Contains(string(data), "content") {
+		t.Fatalf("SRV payload = %s", data)
+	}
+	if _, err := recordPayload(&provider.Record{Type: "CAA", Value: "issue letsencrypt.org"}); err == nil {
+		t.Fatal("recordPayload() accepted invalid CAA value")
+	}
+}
+
+func TestRecordTTL(t *testing.T) {
+	tests := map[int64]int64{0: 1, 1: 1, 30: 60, 60: 60, 600: 600}
+	for ttl, want := range tests {
+		if got := recordTTL(ttl); got != want {
+			t.Fatalf("recordTTL(%d) = %d, want %d", ttl, got, want)
+		}
+	}
+}
+
+func TestCRUDUsesSuccessfulResponses(t *testing.T) {
+	var mu sync.Mutex
+	zoneLookups := 0
+	var payloads []map[string]any
+	c := newTestCloudflare(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Body != nil {
+			var payload map[string]any
+			if json.NewDecoder(r.Body).Decode(&payload) == nil {
+				payloads = append(payloads, payload)
+			}
+		}
+		switch {
+		case r.URL.Path == "/zones":
+			zoneLookups++
+			_, _ = io.WriteString(w, `{"success":true,"result":[{"id":"zone1","name":"example.com"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone1/dns_records":
+			if r.URL.Query().Get("name") != "www.example.com" || r.URL.Query().Get("type") != "A" {
+				t.Errorf("unexpected list query: %s", r.URL.RawQuery)
+			}
+			_, _ = io.WriteString(w, `{"success":true,"result":[{"id":"r1","name":"www.example.com","type":"A","content":"1.2.3.4","ttl":600,"proxied":true}]}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone1/dns_records":
+			_, _ = io.WriteString(w, `{"success":true,"result":{"id":"created"}}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/zones/zone1/dns_records/created":
+			_, _ = io.WriteString(w, `{"success":true,"result":{"id":"created"}}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/zones/zone1/dns_records/created":
+			_, _ = io.WriteString(w, `{"success":true,"result":{"id":"created"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"success":false,"errors":[{"code":7000,"message":"not found"}]}`)
+		}
+	})
+
+	records, err := c.GetSub(context.Background(), "www.example.com", provider.IPv4)
+	if err != nil || len(records) != 1 || records[0].RecordId != "r1" {
+		t.Fatalf("GetSub() = %#v, %v", records, err)
+	}
+	proxied := false
+	record, err := c.Create(context.Background(), &provider.Record{DomainName: "example.com", RR: "www", Type: "A", Value: "1.2.3.4", TTL: 600, Proxied: &proxied})
+	if err != nil || record.RecordId != "created" {
+		t.Fatalf("Create() = %#v, %v", record, err)
+	}
+	record.Proxied = nil
+	if err := c.Update(context.Background(), record); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+	if err := c.Delete(context.Background(), record.RecordId, record.DomainName); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if zoneLookups != 1 {
+		t.Fatalf("zone lookups = %d, want cached after first lookup", zoneLookups)
+	}
+	if len(payloads) != 2 {
+		t.Fatalf("payloads = %#v", payloads)
+	}
+	if payloads[0]["name"] != "www.example.com" || payloads[0]["proxied"] != false {
+		t.Fatalf("create payload = %#v", payloads[0])
+	}
+	if _, ok := payloads[1]["proxied"]; ok {
+		t.Fatalf("update payload should keep cloud proxied setting: %#v", payloads[1])
+	}
+}
+
+func TestCRUDRejectsNilRecord(t *testing.T) {
+	c := NewCloudflare("token")
+	if err := c.Update(context.Background(), nil); err == nil {
+		t.Fatal("Update() accepted nil record")
+	}
+	if _, err := c.Create(context.Background(), nil); err == nil {
+		t.Fatal("Create() accepted nil record")
+	}
+	if _, err := NewCloudflare("").GetSub(context.Background(), "www.example.com", provider.IPv4); err == nil {
+		t.Fatal("GetSub() accepted empty token")
+	}
+}
@@ -30,6 +30,20 @@ type DNSLA struct {
 	domainIDCache   map[string]string
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "dnsla",
+		Label: "DNSLA",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "API ID", Required: true},
+			{Key: provider.FieldKeySecret, Label: "API 密钥", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewDNSLA(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 func NewDNSLA(apiID, apiSecret string) *DNSLA {
 	return &DNSLA{APIID: apiID, APISecret: apiSecret, domainIDCache: make(map[string]string)}
 }
@@ -91,13 +105,8 @@ func (d *DNSLA) Create(ctx context.Context, record *provider.Record) (*provider.
 		return nil, err
 	}
 
-	payload := map[string]any{
-		"domainId": domainID,
-		"type":     recordTypeCode(record.Type),
-		"host":     record.RR,
-		"data":     record.Value,
-		"ttl":      record.TTL,
-	}
+	payload := recordPayload(record)
+	payload["domainId"] = domainID
 	resp, err := d.do(ctx, http.MethodPost, "/record", nil, payload)
 	if err != nil {
 		return nil, err
@@ -127,13 +136,8 @@ func (d *DNSLA) Update(ctx context.Context, record *provider.Record) error {
 	if record.DomainName == "" || record.RR == "" || record.Type == "" || record.Value == "" {
 		return fmt.Errorf("DNSLA Update: 记录参数不完整")
 	}
-	payload := map[string]any{
-		"id":   record.RecordId,
-		"type": recordTypeCode(record.Type),
-		"host": record.RR,
-		"data": record.Value,
-		"ttl":  record.TTL,
-	}
+	payload := recordPayload(record)
+	payload["id"] = record.RecordId
 	resp, err := d.do(ctx, http.MethodPut, "/record", nil, payload)
 	if err != nil {
 		return err
@@ -299,6 +303,20 @@ func parseSuccessfulResponse(body []byte) (apiResponse, error) {
 	return response, nil
 }
 
+// recordPayload 生成创建和更新记录的公共请求体，MX 优先级使用 preference 字段，SRV 的优先级、权重、端口写在记录值中
+func recordPayload(record *provider.Record) map[string]any {
+	payload := map[string]any{
+		"type": recordTypeCode(record.Type),
+		"host": record.RR,
+		"data": provider.InlineValue(*record, false),
+		"ttl":  record.TTL,
+	}
+	if record.Type == provider.TypeMX {
+		payload["preference"] = record.Priority
+	}
+	return payload
+}
+
 func recordTypeCode(recordType string) int {
 	switch strings.ToUpper(recordType) {
 	case "A":
@@ -330,14 +348,15 @@ func parseRecordListResponse(body []byte, domain string) ([]provider.Record, err
 		Data struct {
 			Total   int `json:"total"`
 			Results []struct {
-				ID       string `json:"id"`
-				Host     string `json:"host"`
-				Type     int    `json:"type"`
-				Data     string `json:"data"`
-				TTL      int64  `json:"ttl"`
-				Disable  bool   `json:"disable"`
-				System   bool   `json:"system"`
-				DomainID string `json:"domainId"`
+				ID         string `json:"id"`
+				Host       string `json:"host"`
+				Type       int    `json:"type"`
+				Data       string `json:"data"`
+				TTL        int64  `json:"ttl"`
+				Preference int    `json:"preference"`
+				Disable    bool   `json:"disable"`
+				System     bool   `json:"system"`
+				DomainID   string `json:"domainId"`
 			} `json:"results"`
 		} `json:"data"`
 	}
@@ -352,14 +371,17 @@ func parseRecordListResponse(body []byte, domain string) ([]provider.Record, err
 	}
 	result := make([]provider.Record, 0, len(response.Data.Results))
 	for _, item := range response.Data.Results {
-		result = append(result, provider.Record{
+		record := provider.Record{
 			RecordId:   item.ID,
 			DomainName: domain,
 			RR:         item.Host,
 			Type:       recordTypeName(item.Type),
 			Value:      item.Data,
 			TTL:        item.TTL,
-		})
+			Priority:   item.Preference,
+		}
+		_ = provider.ParseInlineValue(&record, false)
+		result = append(result, record)
 	}
 	return result, nil
 }
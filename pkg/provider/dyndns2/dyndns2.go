@@ -0,0 +1,196 @@
+package dyndns2
+
+import (
+	"context"
+	"ddns/pkg/provider"
+	"ddns/pkg/version"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// DynDNS2 通用 DynDNS2 协议（/nic/update）客户端，适用于 No-IP、Dynu、花生壳、ChangeIP 等服务
+// 协议只能更新记录，不能查询、创建和删除，通过 Capabilities 声明为只支持更新
+
+const (
+	// updatePath 服务器地址没有路径时使用的默认路径
+	updatePath = "/nic/update"
+)
+
+// DynDNS2 DynDNS2 协议服务商
+type DynDNS2 struct {
+	// Server 服务器地址，例如 https://dynupdate.no-ip.com
+	Server   string
+	Username string
+	Password string
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "dyndns2",
+		Label: "DynDNS2",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldServer, Label: "更新地址", Placeholder: "如 https://dynupdate.no-ip.com/nic/update", Required: true},
+			{Key: provider.FieldKeyID, Label: "用户名", Required: true},
+			{Key: provider.FieldKeySecret, Label: "密码", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewDynDNS2(c.Server, c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
+// NewDynDNS2 新建 DynDNS2 服务商
+// 参数说明：
+// server：更新地址，没有路径时使用 /nic/update
+// username 和 password：HTTP Basic 认证的用户名和密码
+func NewDynDNS2(server, username, password string) *DynDNS2 {
+	return &DynDNS2{Server: server, Username: username, Password: password}
+}
+
+// Capabilities DynDNS2 协议只支持更新记录
+func (d *DynDNS2) Capabilities() provider.Capability {
+	return provider.CapUpdate
+}
+
+// GetAll DynDNS2 协议不支持查询记录
+func (d *DynDNS2) GetAll(ctx context.Context, domain string, v provider.Version) ([]provider.Record, error) {
+	return nil, fmt.Errorf("DynDNS2 GetAll: %w", provider.ErrNotSupported)
+}
+
+// GetSub DynDNS2 协议不支持查询记录
+func (d *DynDNS2) GetSub(ctx context.Context, subdomain string, v provider.Version) ([]provider.Record, error) {
+	return nil, fmt.Errorf("DynDNS2 GetSub: %w", provider.ErrNotSupported)
+}
+
+// Create DynDNS2 协议不支持创建记录，主机名需要先在服务商处创建
+func (d *DynDNS2) Create(ctx context.Context, record *provider.Record) (*provider.Record, error) {
+	return nil, fmt.Errorf("DynDNS2 Create: %w", provider.ErrNotSupported)
+}
+
+// Delete DynDNS2 协议不支持删除记录
+func (d *DynDNS2) Delete(ctx context.Context, recordId, domain string) error {
+	return fmt.Errorf("DynDNS2 Delete: %w", provider.ErrNotSupported)
+}
+
+// Update 把主机名更新为记录值
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// Record: 记录信息，必传DomainName、RR、Value，不需要 RecordId
+func (d *DynDNS2) Update(ctx context.Context, record *provider.Record) error {
+	if record == nil {
+		return fmt.Errorf("DynDNS2 Update: record 为空")
+	}
+	if record.DomainName == "" || record.RR == "" || record.Value == "" {
+		return fmt.Errorf("DynDNS2 Update: 记录参数不完整")
+	}
+	if record.Type != provider.TypeA && record.Type != provider.TypeAAAA {
+		return fmt.Errorf("DynDNS2 Update: 只支持 A 和 AAAA 记录，%s 记录: %w", record.Type, provider.ErrNotSupported)
+	}
+	if _, err := netip.ParseAddr(record.Value); err != nil {
+		return fmt.Errorf("DynDNS2 Update: 记录值 %q 不是有效的 IP 地址", record.Value)
+	}
+	endpoint, err := d.endpoint()
+	if err != nil {
+		return fmt.Errorf("DynDNS2 Update: %w", err)
+	}
+
+	hostname := record.DomainName
+	if record.RR != "@" {
+		hostname = record.RR + "." + record.DomainName
+	}
+	query := endpoint.Query()
+	query.Set("hostname", hostname)
+	query.Set("myip", record.Value)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("DynDNS2 Update: %w", err)
+	}
+	req.SetBasicAuth(d.Username, d.Password)
+	// 协议要求携带可识别的 User-Agent，否则可能返回 badagent
+	req.Header.Set("User-Agent", "ddns/"+version.Version)
+
+	resp, err := provider.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("DynDNS2 Update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("DynDNS2 Update: %w", responseError("badauth"))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, readErr := provider.ReadErrorResponseBody(resp.Body)
+		if readErr != nil {
+			return fmt.Errorf("DynDNS2 Update: HTTP %d，读取响应失败: %w", resp.StatusCode, readErr)
+		}
+		return fmt.Errorf("DynDNS2 Update: HTTP %d: %s", resp.StatusCode, body)
+	}
+	body, err := provider.ReadResponseBody(resp.Body)
+	if err != nil {
+		return fmt.Errorf("DynDNS2 Update: %w", err)
+	}
+	if err := parseResponse(string(body)); err != nil {
+		return fmt.Errorf("DynDNS2 Update: %w", err)
+	}
+	return nil
+}
+
+// endpoint 解析更新地址，没有路径时补全 /nic/update
+func (d *DynDNS2) endpoint() (*url.URL, error) {
+	if d.Server == "" {
+		return nil, errors.New("更新地址为空")
+	}
+	if d.Username == "" || d.Password == "" {
+		return nil, errors.New("用户名或密码为空")
+	}
+	endpoint, err := url.Parse(strings.TrimSpace(d.Server))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("更新地址 %q 无效，需要以 http:// 或 https:// 开头", d.Server)
+	}
+	if endpoint.Path == "" || endpoint.Path == "/" {
+		endpoint.Path = updatePath
+	}
+	return endpoint, nil
+}
+
+// parseResponse 解析返回码，good 和 nochg 表示成功
+func parseResponse(body string) error {
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return errors.New("响应为空")
+	}
+	switch fields[0] {
+	case "good", "nochg":
+		return nil
+	default:
+		return responseError(fields[0])
+	}
+}
+
+// responseError 把返回码转换为错误信息，只有 911、dnserr 是服务器的临时故障，其他返回码重试也不会成功
+func responseError(code string) error {
+	messages := map[string]string{
+		"badauth":  "用户名或密码错误",
+		"!donator": "账号不支持该功能",
+		"notfqdn":  "主机名不是完整域名",
+		"nohost":   "主机名不存在或不属于该账号",
+		"numhost":  "一次更新的主机名过多",
+		"abuse":    "主机名因滥用被封禁",
+		"badagent": "User-Agent 被拒绝",
+		"dnserr":   "服务器 DNS 错误",
+		"911":      "服务器维护中，请稍后重试",
+	}
+	if message, ok := messages[code]; ok {
+		err := fmt.Errorf("%s: %s", code, message)
+		if code == "911" || code == "dnserr" {
+			return err
+		}
+		return &provider.PermanentError{Err: err}
+	}
+	return fmt.Errorf("未知的响应: %s", provider.ErrorSummary(code))
+}
@@ -0,0 +1,91 @@
+package dyndns2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ddns/pkg/provider"
+)
+
+func TestUpdateSendsHostnameAndIP(t *testing.T) {
+	var gotPath, gotQuery, gotUser, gotPassword, gotAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		gotUser, gotPassword, _ = r.BasicAuth()
+		gotAgent = r.UserAgent()
+		_, _ = io.WriteString(w, "good 1.2.3.4\n")
+	}))
+	defer server.Close()
+
+	d := NewDynDNS2(server.URL, "user", "pass")
+	if err := d.Update(context.Background(), &provider.Record{DomainName: "example.com", RR: "www", Type: "A", Value: "1.2.3.4"}); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+	if gotPath != "/nic/update" || gotQuery != "hostname=www.example.com&myip=1.2.3.4" {
+		t.Fatalf("request = %s?%s", gotPath, gotQuery)
+	}
+	if gotUser != "user" || gotPassword != "pass" || !strings.HasPrefix(gotAgent, "ddns/") {
+		t.Fatalf("auth = %q:%q, agent = %q", gotUser, gotPassword, gotAgent)
+	}
+}
+
+func TestUpdateResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		want      string
+		permanent bool
+	}{
+		{name: "good", status: http.StatusOK, body: "good 1.2.3.4"},
+		{name: "nochg", status: http.StatusOK, body: "nochg 1.2.3.4"},
+		{name: "badauth", status: http.StatusOK, body: "badauth", want: "用户名或密码错误", permanent: true},
+		{name: "http unauthorized", status: http.StatusUnauthorized, body: "", want: "badauth", permanent: true},
+		{name: "abuse", status: http.StatusOK, body: "abuse", want: "abuse", permanent: true},
+		{name: "nohost", status: http.StatusOK, body: "nohost", want: "nohost", permanent: true},
+		{name: "maintenance", status: http.StatusOK, body: "911", want: "911"},
+		{name: "unknown", status: http.StatusOK, body: "whatever", want: "未知的响应"},
+		{name: "server error", status: http.StatusBadGateway, body: "bad gateway", want: "HTTP 502"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+			err := NewDynDNS2(server.URL+"/custom/update", "user", "pass").Update(context.Background(), &provider.Record{DomainName: "example.com", RR: "@", Type: "AAAA", Value: "2001:db8::1"})
+			if tt.want == "" && err != nil {
+				t.Fatalf("Update() = %v", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("Update() error = %v, want %q", err, tt.want)
+			}
+			var permanent *provider.PermanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Fatalf("Update() error = %v, permanent = %v", err, !tt.permanent)
+			}
+		})
+	}
+}
+
+func TestUpdateOnlyCapabilities(t *testing.T) {
+	d := NewDynDNS2("https://example.com", "user", "pass")
+	if caps := provider.CapabilitiesOf(d); caps.Has(provider.CapQuery) || !caps.Has(provider.CapUpdate) {
+		t.Fatalf("Capabilities() = %b", caps)
+	}
+	if _, err := d.GetSub(context.Background(), "www.example.com", provider.IPv4); !errors.Is(err, provider.ErrNotSupported) {
+		t.Fatalf("GetSub() error = %v, want ErrNotSupported", err)
+	}
+	if err := d.Delete(context.Background(), "id", "example.com"); !errors.Is(err, provider.ErrNotSupported) {
+		t.Fatalf("Delete() error = %v, want ErrNotSupported", err)
+	}
+	if err := NewDynDNS2("ftp://example.com", "user", "pass").Update(context.Background(), &provider.Record{DomainName: "example.com", RR: "www", Value: "1.2.3.4"}); err == nil {
+		t.Fatal("Update() accepted non-http server")
+	}
+}
@@ -33,6 +33,20 @@ type Huawei struct {
 	mu     sync.RWMutex
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "huawei",
+		Label: "华为云",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "Access Key ID", Required: true},
+			{Key: provider.FieldKeySecret, Label: "Secret Access Key", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewHuawei(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 // NewHuawei 新建华为云DNS
 // 参数说明：
 // key和secret：华为云密钥
@@ -179,7 +193,7 @@ func (h *Huawei) addAndUpdate(ctx context.Context, r *provider.Record) error {
 	}{
 		Name:    name,
 		Type:    r.Type,
-		Records: []string{r.Value},
+		Records: []string{recordValue(r)},
 		Ttl:     r.TTL,
 	}
 
@@ -220,6 +234,15 @@ func (h *Huawei) addAndUpdate(ctx context.Context, r *provider.Record) error {
 	return nil
 }
 
+// recordValue 生成记录集中的记录值
+// 华为云的 MX 优先级和 SRV 的优先级、权重、端口都写在记录值中，TXT 记录值需要带引号
+func recordValue(r *provider.Record) string {
+	if r.Type == provider.TypeTXT {
+		return provider.QuoteTXT(r.Value)
+	}
+	return provider.InlineValue(*r, true)
+}
+
 // do 发送请求
 func (h *Huawei) do(ctx context.Context, action, urlStr string, body string) ([]byte, error) {
 	httpReq, err := http.NewRequestWithContext(ctx, action, urlStr, strings.NewReader(body))
@@ -291,14 +314,19 @@ func (h *Huawei) parseResponse(resp []byte) ([]provider.Record, error) {
 			val = Record.Records[0]
 		}
 
-		records = append(records, provider.Record{
+		record := provider.Record{
 			RecordId:   Record.RecordId,
 			DomainName: domainName,
 			RR:         rr,
 			Type:       Record.Type,
 			Value:      val,
 			TTL:        Record.TTL,
-		})
+		}
+		if record.Type == provider.TypeTXT {
+			record.Value = provider.UnquoteTXT(record.Value)
+		}
+		_ = provider.ParseInlineValue(&record, true)
+		records = append(records, record)
 	}
 
 	if len(records) == 0 {
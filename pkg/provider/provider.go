@@ -36,6 +36,15 @@ var (
 	}
 )
 
+// PermanentError 服务商拒绝请求且重试也不会成功的错误，如认证失败、主机名不存在，修改配置后才能恢复
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+
+func (e *PermanentError) Unwrap() error { return e.Err }
+
 const (
 	MaxResponseBodyBytes      = 1 << 20
 	MaxErrorResponseBodyBytes = 8 << 10
@@ -162,7 +171,11 @@ type Record struct {
 	RecordId   string // 记录ID
 	DomainName string // 域名
 	RR         string // 记录的子域名部分
-	Type       string // A / AAAA / CNAME ...
-	Value      string // 记录值，IP地址或CNAME等
+	Type       string // A / AAAA / CNAME / TXT / MX / SRV / CAA
+	Value      string // 记录值，IP地址、目标主机名、TXT 文本或 CAA 的 "flags tag value"
 	TTL        int64  // 生存时间，单位秒
+	Proxied    *bool  // 是否经 Cloudflare 代理，nil 表示不修改，其他服务商忽略
+	Priority   int    // MX、SRV 优先级
+	Weight     int    // SRV 权重
+	Port       int    // SRV 端口
 }
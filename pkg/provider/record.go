@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 记录类型和记录值的通用处理
+// Record.Value 只保存记录的主体：CNAME、MX、SRV 为目标主机名，TXT 为不带引号的文本，CAA 为 "flags tag value"
+// MX 的优先级和 SRV 的优先级、权重、端口保存在 Priority、Weight、Port 中，
+// 需要把这些字段写在记录值里的服务商使用 InlineValue 和 ParseInlineValue 转换
+
+const (
+	TypeA     = "A"
+	TypeAAAA  = "AAAA"
+	TypeCNAME = "CNAME"
+	TypeTXT   = "TXT"
+	TypeMX    = "MX"
+	TypeSRV   = "SRV"
+	TypeCAA   = "CAA"
+)
+
+// RecordTypes 支持同步的记录类型
+var RecordTypes = []string{TypeA, TypeAAAA, TypeCNAME, TypeTXT, TypeMX, TypeSRV, TypeCAA}
+
+// VersionOf 返回查询指定类型记录时使用的 Version，非地址记录返回 IPvAll，由调用方按类型过滤
+func VersionOf(recordType string) Version {
+	switch strings.ToUpper(recordType) {
+	case TypeA:
+		return IPv4
+	case TypeAAAA:
+		return IPv6
+	default:
+		return IPvAll
+	}
+}
+
+// InlineValue 把优先级、权重、端口拼接到记录值前面
+// SRV 为 "priority weight port target"，mxPriority 为 true 时 MX 为 "priority exchange"，其他类型返回原值
+func InlineValue(r Record, mxPriority bool) string {
+	switch strings.ToUpper(r.Type) {
+	case TypeSRV:
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Value)
+	case TypeMX:
+		if mxPriority {
+			return fmt.Sprintf("%d %s", r.Priority, r.Value)
+		}
+	}
+	return r.Value
+}
+
+// ParseInlineValue 解析 InlineValue 格式的记录值，把优先级、权重、端口拆分到对应字段
+func ParseInlineValue(r *Record, mxPriority bool) error {
+	var fields []string
+	switch strings.ToUpper(r.Type) {
+	case TypeSRV:
+		fields = strings.Fields(r.Value)
+		if len(fields) != 4 {
+			return fmt.Errorf("SRV 记录值格式无效: %q", r.Value)
+		}
+	case TypeMX:
+		if !mxPriority {
+			return nil
+		}
+		fields = strings.Fields(r.Value)
+		if len(fields) != 2 {
+			return fmt.Errorf("MX 记录值格式无效: %q", r.Value)
+		}
+	default:
+		return nil
+	}
+	numbers := make([]int, len(fields)-1)
+	for i, field := range fields[:len(fields)-1] {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 0 || number > 65535 {
+			return fmt.Errorf("%s 记录值格式无效: %q", r.Type, r.Value)
+		}
+		numbers[i] = number
+	}
+	r.Priority = numbers[0]
+	if len(numbers) == 3 {
+		r.Weight, r.Port = numbers[1], numbers[2]
+	}
+	r.Value = fields[len(fields)-1]
+	return nil
+}
+
+// QuoteTXT 把文本转换为带引号的 TXT 记录值
+func QuoteTXT(value string) string {
+	return strconv.Quote(value)
+}
+
+// UnquoteTXT 去掉 TXT 记录值的引号，多段文本直接拼接
+func UnquoteTXT(value string) string {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, `"`) {
+		return value
+	}
+	var b strings.Builder
+	for value != "" {
+		value = strings.TrimLeft(value, " ")
+		prefix, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			b.WriteString(value)
+			break
+		}
+		text, _ := strconv.Unquote(prefix)
+		b.WriteString(text)
+		value = value[len(prefix):]
+	}
+	return b.String()
+}
+
+// ParseCAA 解析 "flags tag value" 格式的 CAA 记录值，value 可以带引号
+func ParseCAA(value string) (flags int, tag, tagValue string, err error) {
+	fields := strings.SplitN(strings.TrimSpace(value), " ", 3)
+	if len(fields) != 3 {
+		return 0, "", "", fmt.Errorf("CAA 记录值格式无效，应为 \"flags tag value\": %q", value)
+	}
+	flags, err = strconv.Atoi(fields[0])
+	if err != nil || flags < 0 || flags > 255 {
+		return 0, "", "", fmt.Errorf("CAA 记录 flags 无效: %q", fields[0])
+	}
+	tag = strings.ToLower(fields[1])
+	if tag == "" {
+		return 0, "", "", fmt.Errorf("CAA 记录 tag 为空")
+	}
+	tagValue = strings.TrimSpace(fields[2])
+	if strings.HasPrefix(tagValue, `"`) {
+		if tagValue, err = strconv.Unquote(tagValue); err != nil {
+			return 0, "", "", fmt.Errorf("CAA 记录值引号不匹配: %q", fields[2])
+		}
+	}
+	return flags, tag, tagValue, nil
+}
+
+// CAAValue 生成 "flags tag "value"" 格式的 CAA 记录值
+func CAAValue(flags int, tag, tagValue string) string {
+	return fmt.Sprintf("%d %s %s", flags, tag, strconv.Quote(tagValue))
+}
+
+// SameData 比较两条记录的类型和数据是否一致，忽略主机名大小写、末尾的点和 TXT 引号
+func SameData(current, desired Record) bool {
+	if !strings.EqualFold(current.Type, desired.Type) {
+		return false
+	}
+	switch strings.ToUpper(desired.Type) {
+	case TypeCNAME:
+		return sameHost(current.Value, desired.Value)
+	case TypeMX:
+		return current.Priority == desired.Priority && sameHost(current.Value, desired.Value)
+	case TypeSRV:
+		return current.Priority == desired.Priority && current.Weight == desired.Weight &&
+			current.Port == desired.Port && sameHost(current.Value, desired.Value)
+	case TypeTXT:
+		return UnquoteTXT(current.Value) == UnquoteTXT(desired.Value)
+	case TypeCAA:
+		currentFlags, currentTag, currentValue, err := ParseCAA(current.Value)
+		if err != nil {
+			return false
+		}
+		desiredFlags, desiredTag, desiredValue, err := ParseCAA(desired.Value)
+		return err == nil && currentFlags == desiredFlags && strings.EqualFold(currentTag, desiredTag) && currentValue == desiredValue
+	default:
+		return current.Value == desired.Value
+	}
+}
+
+func sameHost(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
@@ -0,0 +1,44 @@
+package provider
+
+import "testing"
+
+func TestInlineValueRoundTrip(t *testing.T) {
+	srv := Record{Type: TypeSRV, Value: "mc.example.com", Priority: 1, Weight: 5, Port: 25565}
+	if got := InlineValue(srv, false); got != "1 5 25565 mc.example.com" {
+		t.Fatalf("InlineValue(SRV) = %q", got)
+	}
+	mx := Record{Type: TypeMX, Value: "mail.example.com", Priority: 10}
+	if got := InlineValue(mx, false); got != "mail.example.com" {
+		t.Fatalf("InlineValue(MX, false) = %q", got)
+	}
+	parsed := Record{Type: TypeMX, Value: InlineValue(mx, true)}
+	if err := ParseInlineValue(&parsed, true); err != nil || !SameData(parsed, mx) {
+		t.Fatalf("ParseInlineValue(MX) = %#v, %v", parsed, err)
+	}
+	parsed = Record{Type: TypeSRV, Value: "1 5 25565 MC.example.com."}
+	if err := ParseInlineValue(&parsed, false); err != nil || !SameData(parsed, srv) {
+		t.Fatalf("ParseInlineValue(SRV) = %#v, %v", parsed, err)
+	}
+	if err := ParseInlineValue(&Record{Type: TypeSRV, Value: "1 5 mc.example.com"}, false); err == nil {
+		t.Fatal("ParseInlineValue() accepted SRV value without port")
+	}
+}
+
+func TestSameDataNormalizesValues(t *testing.T) {
+	tests := []struct {
+		current, desired Record
+		want             bool
+	}{
+		{Record{Type: "TXT", Value: `"ip=1.2.3.4"`}, Record{Type: TypeTXT, Value: "ip=1.2.3.4"}, true},
+		{Record{Type: "TXT", Value: `"ip=" "1.2.3.4"`}, Record{Type: TypeTXT, Value: "ip=1.2.3.4"}, true},
+		{Record{Type: TypeCNAME, Value: "Target.example.com."}, Record{Type: TypeCNAME, Value: "target.example.com"}, true},
+		{Record{Type: TypeCAA, Value: `0 issue "letsencrypt.org"`}, Record{Type: TypeCAA, Value: "0 issue letsencrypt.org"}, true},
+		{Record{Type: TypeMX, Value: "mail.example.com", Priority: 20}, Record{Type: TypeMX, Value: "mail.example.com", Priority: 10}, false},
+		{Record{Type: TypeA, Value: "1.2.3.4"}, Record{Type: TypeAAAA, Value: "1.2.3.4"}, false},
+	}
+	for _, tt := range tests {
+		if got := SameData(tt.current, tt.desired); got != tt.want {
+			t.Fatalf("SameData(%#v, %#v) = %v, want %v", tt.current, tt.desired, got, tt.want)
+		}
+	}
+}
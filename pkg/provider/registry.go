@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// 服务商注册表，每个服务商包在 init() 中调用 Register 注册自己
+// 新增服务商只需要导入对应的包，例如：import _ "ddns/pkg/provider/aliyun"
+// 内置服务商统一由 ddns/pkg/provider/all 导入
+
+// Operator 域名解析记录操作接口，组合了 CRUD 所有操作
+type Operator interface {
+	Getter
+	Creator
+	Updater
+	Deleter
+}
+
+// 凭据字段名称，对应配置文件 providers 中的字段
+const (
+	FieldKeyID     = "keyId"
+	FieldKeySecret = "keySecret"
+	FieldServer    = "server"
+)
+
+// CredentialField 服务商凭据字段说明，用于配置校验和 Web 表单
+type CredentialField struct {
+	// Key 字段名称，FieldKeyID、FieldKeySecret 或 FieldServer
+	Key string
+	// Label 表单中显示的名称
+	Label string
+	// Placeholder 表单输入提示
+	Placeholder string
+	// Required 是否必填
+	Required bool
+	// Secret 是否为密钥，表单中不回显
+	Secret bool
+}
+
+// Credentials 创建服务商实例使用的凭据
+type Credentials struct {
+	KeyID     string
+	KeySecret string
+	Server    string
+}
+
+// Get 根据字段名称获取凭据值
+func (c Credentials) Get(key string) string {
+	switch key {
+	case FieldKeyID:
+		return c.KeyID
+	case FieldKeySecret:
+		return c.KeySecret
+	case FieldServer:
+		return c.Server
+	default:
+		return ""
+	}
+}
+
+// Registration 服务商注册信息
+type Registration struct {
+	// Name 服务商类型，配置文件 provider 字段的值
+	Name string
+	// Label 显示名称
+	Label string
+	// Fields 需要填写的凭据字段
+	Fields []CredentialField
+	// New 根据凭据创建服务商实例
+	New func(Credentials) (Operator, error)
+}
+
+// Field 根据字段名称获取凭据字段说明
+func (r Registration) Field(key string) (CredentialField, bool) {
+	for _, field := range r.Fields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return CredentialField{}, false
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Registration)
+)
+
+// Register 注册服务商，名称为空、缺少构造函数或重复注册时 panic
+func Register(r Registration) {
+	if r.Name == "" || r.New == nil {
+		panic("provider: Register 名称或构造函数为空")
+	}
+	for _, field := range r.Fields {
+		if field.Key != FieldKeyID && field.Key != FieldKeySecret && field.Key != FieldServer {
+			panic(fmt.Sprintf("provider: %s 的凭据字段 %q 无效", r.Name, field.Key))
+		}
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[r.Name]; ok {
+		panic("provider: 重复注册服务商 " + r.Name)
+	}
+	registry[r.Name] = r
+}
+
+// Lookup 根据服务商类型获取注册信息
+func Lookup(name string) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	r, ok := registry[name]
+	return r, ok
+}
+
+// Registrations 返回所有已注册的服务商，按名称排序
+func Registrations() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	list := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		list = append(list, r)
+	}
+	slices.SortFunc(list, func(a, b Registration) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// Names 返回所有已注册的服务商类型，按名称排序
+func Names() []string {
+	list := Registrations()
+	names := make([]string, 0, len(list))
+	for _, r := range list {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// New 根据服务商类型和凭据创建服务商实例
+func New(name string, credentials Credentials) (Operator, error) {
+	r, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("不支持的DNS运营商：%v", name)
+	}
+	return r.New(credentials)
+}
@@ -0,0 +1,69 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+type fakeOperator struct{}
+
+func (fakeOperator) GetAll(context.Context, string, Version) ([]Record, error) { return nil, nil }
+func (fakeOperator) GetSub(context.Context, string, Version) ([]Record, error) { return nil, nil }
+func (fakeOperator) Create(_ context.Context, r *Record) (*Record, error)      { return r, nil }
+func (fakeOperator) Update(context.Context, *Record) error                     { return nil }
+func (fakeOperator) Delete(context.Context, string, string) error              { return nil }
+
+func TestRegisterLookupAndNew(t *testing.T) {
+	var got Credentials
+	Register(Registration{
+		Name:   "test-registry",
+		Label:  "测试",
+		Fields: []CredentialField{{Key: FieldServer, Label: "服务器", Required: true}},
+		New: func(c Credentials) (Operator, error) {
+			got = c
+			return fakeOperator{}, nil
+		},
+	})
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, "test-registry")
+		registryMu.Unlock()
+	})
+
+	registration, ok := Lookup("test-registry")
+	if !ok || registration.Label != "测试" {
+		t.Fatalf("Lookup() = %#v, %v", registration, ok)
+	}
+	if field, ok := registration.Field(FieldServer); !ok || !field.Required {
+		t.Fatalf("Field() = %#v, %v", field, ok)
+	}
+	if !slices.Contains(Names(), "test-registry") {
+		t.Fatalf("Names() = %v", Names())
+	}
+	if _, err := New("test-registry", Credentials{Server: "ns1.example.com"}); err != nil || got.Get(FieldServer) != "ns1.example.com" {
+		t.Fatalf("New() credentials = %#v, err = %v", got, err)
+	}
+	if _, err := New("missing", Credentials{}); err == nil {
+		t.Fatal("New() accepted unregistered provider")
+	}
+}
+
+func TestRegisterRejectsInvalidRegistrations(t *testing.T) {
+	newOperator := func(Credentials) (Operator, error) { return fakeOperator{}, nil }
+	tests := []Registration{
+		{Name: "", New: newOperator},
+		{Name: "no-constructor"},
+		{Name: "bad-field", Fields: []CredentialField{{Key: "token"}}, New: newOperator},
+	}
+	for _, registration := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Register(%q) did not panic", registration.Name)
+				}
+			}()
+			Register(registration)
+		}()
+	}
+}
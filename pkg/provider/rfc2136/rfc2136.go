@@ -0,0 +1,629 @@
+package rfc2136
+
+import (
+	"context"
+	"ddns/pkg/provider"
+	"ddns/pkg/utils"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// RFC2136 通过 DNS UPDATE（RFC 2136）报文直接更新权威 DNS 服务器，例如 BIND、Knot、PowerDNS
+// 所有报文经 TCP 发送，并使用 TSIG（hmac-sha256）签名
+// DNS 没有记录 ID，RecordId 使用记录的文本格式："www.example.com. A 1.2.3.4"、"_sip._tcp.example.com. SRV 10 5 5060 sip.example.com."
+
+const (
+	// defaultPort DNS 默认端口
+	defaultPort = "53"
+	// timeout 单次请求超时时间
+	timeout = 10 * time.Second
+	// defaultTTL 记录没有 TTL 时使用的默认值
+	defaultTTL = 600
+	// opcodeUpdate DNS UPDATE 操作码
+	opcodeUpdate = dnsmessage.OpCode(5)
+
+	classNONE = dnsmessage.Class(254)
+)
+
+// RFC2136 动态更新服务商
+type RFC2136 struct {
+	// Server 权威 DNS 服务器地址，host 或 host:port
+	Server string
+	// KeyName TSIG 密钥名称
+	KeyName string
+	// Secret Base64 编码的 TSIG 密钥
+	Secret string
+}
+
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "rfc2136",
+		Label: "RFC 2136",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldServer, Label: "DNS 服务器", Placeholder: "如 ns1.example.com:53", Required: true},
+			{Key: provider.FieldKeyID, Label: "TSIG 密钥名称", Required: true},
+			{Key: provider.FieldKeySecret, Label: "TSIG 密钥（Base64，hmac-sha256）", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewRFC2136(c.Server, c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
+// NewRFC2136 新建 RFC2136 动态更新服务商
+// 参数说明：
+// server：权威 DNS 服务器地址，端口默认 53
+// keyName 和 secret：TSIG 密钥名称和 Base64 编码的密钥，算法为 hmac-sha256
+func NewRFC2136(server, keyName, secret string) *RFC2136 {
+	return &RFC2136{Server: server, KeyName: keyName, Secret: secret}
+}
+
+// GetAll 列出整个区域需要区域传送（AXFR），RFC2136 服务商不支持
+func (r *RFC2136) GetAll(ctx context.Context, domain string, v provider.Version) ([]provider.Record, error) {
+	return nil, fmt.Errorf("RFC2136 GetAll: 不支持列出全部记录")
+}
+
+// GetSub 查询子域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// subdomain: 子域名，例如www.example.com
+// provider.Version: IP地址版本，所有/4/6
+// 返回值：[]provider.Record: 记录列表，error: 错误信息，ErrRecordNotFound:没有记录
+func (r *RFC2136) GetSub(ctx context.Context, subdomain string, v provider.Version) ([]provider.Record, error) {
+	key, err := r.validate(subdomain)
+	if err != nil {
+		return nil, fmt.Errorf("RFC2136 GetSub: %w", err)
+	}
+	rr, domain, err := utils.ParseDomain(subdomain)
+	if err != nil {
+		return nil, err
+	}
+
+	// DNS 查询只能按类型进行，IPvAll 时查询所有支持的类型
+	var types []dnsmessage.Type
+	switch v {
+	case provider.IPv4:
+		types = []dnsmessage.Type{dnsmessage.TypeA}
+	case provider.IPv6:
+		types = []dnsmessage.Type{dnsmessage.TypeAAAA}
+	default:
+		for _, recordType := range provider.RecordTypes {
+			types = append(types, recordTypes[recordType])
+		}
+	}
+	var records []provider.Record
+	for _, recordType := range types {
+		answers, err := r.query(ctx, key, subdomain, recordType)
+		if err != nil {
+			return nil, fmt.Errorf("RFC2136 GetSub: %w", err)
+		}
+		for _, answer := range answers {
+			data, ok := resourceData(answer)
+			if !ok || answer.Header.Type != recordType || !strings.EqualFold(answer.Header.Name.String(), fqdn(subdomain)) {
+				continue
+			}
+			typeName := typeName(recordType)
+			record := provider.Record{
+				RecordId:   recordID(subdomain, typeName, data),
+				DomainName: domain,
+				RR:         rr,
+				Type:       typeName,
+				Value:      data,
+				TTL:        int64(answer.Header.TTL),
+			}
+			if record.Type == provider.TypeTXT {
+				record.Value = provider.UnquoteTXT(record.Value)
+			}
+			_ = provider.ParseInlineValue(&record, true)
+			records = append(records, record)
+		}
+	}
+	if len(records) == 0 {
+		return nil, provider.ErrRecordNotFound
+	}
+	return records, nil
+}
+
+// Create 创建域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// Record: 记录信息，必传DomainName、RR、Type、Value、TTL
+func (r *RFC2136) Create(ctx context.Context, record *provider.Record) (*provider.Record, error) {
+	if record == nil {
+		return nil, fmt.Errorf("RFC2136 Create: record 为空")
+	}
+	key, err := r.validate(record.DomainName)
+	if err != nil {
+		return nil, fmt.Errorf("RFC2136 Create: %w", err)
+	}
+	if record.RR == "" || record.Type == "" || record.Value == "" {
+		return nil, fmt.Errorf("RFC2136 Create: 记录参数不完整")
+	}
+	name := recordName(record.RR, record.DomainName)
+	data := presentation(record)
+	add, err := newResource(name, record.Type, data, dnsmessage.ClassINET, recordTTL(record.TTL))
+	if err != nil {
+		return nil, fmt.Errorf("RFC2136 Create: %w", err)
+	}
+	if err := r.update(ctx, key, record.DomainName, nil, []resource{add}); err != nil {
+		return nil, fmt.Errorf("RFC2136 Create: %w", err)
+	}
+	record.RecordId = recordID(name, record.Type, data)
+	return record, nil
+}
+
+// Update 更新域名解析记录，以旧记录存在为前提条件，删除旧记录并添加新记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// Record: 记录信息，必传RecordId（GetSub 返回的旧记录）、DomainName、RR、Type、Value
+func (r *RFC2136) Update(ctx context.Context, record *provider.Record) error {
+	if record == nil || record.RecordId == "" {
+		return fmt.Errorf("RFC2136 Update: RecordId 为空")
+	}
+	key, err := r.validate(record.DomainName)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	if record.RR == "" || record.Type == "" || record.Value == "" {
+		return fmt.Errorf("RFC2136 Update: 记录参数不完整")
+	}
+	oldName, oldType, oldData, err := parseRecordID(record.RecordId)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	// 前提条件：旧记录仍然存在（与值相关，TTL 必须为 0）
+	exists, err := newResource(oldName, oldType, oldData, dnsmessage.ClassINET, 0)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	remove, err := newResource(oldName, oldType, oldData, classNONE, 0)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	name := recordName(record.RR, record.DomainName)
+	data := presentation(record)
+	add, err := newResource(name, record.Type, data, dnsmessage.ClassINET, recordTTL(record.TTL))
+	if err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	if err := r.update(ctx, key, record.DomainName, []resource{exists}, []resource{remove, add}); err != nil {
+		return fmt.Errorf("RFC2136 Update: %w", err)
+	}
+	record.RecordId = recordID(name, record.Type, data)
+	return nil
+}
+
+// Delete 删除域名解析记录
+// 参数说明：
+// ctx: 上下文，用于控制超时和取消
+// recordId: GetSub 或 Create 返回的 RecordId，domain: 记录所在的区域
+func (r *RFC2136) Delete(ctx context.Context, recordId, domain string) error {
+	if recordId == "" || domain == "" {
+		return fmt.Errorf("RFC2136 Delete: RecordId 或 domain 为空")
+	}
+	key, err := r.validate(domain)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Delete: %w", err)
+	}
+	name, recordType, data, err := parseRecordID(recordId)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Delete: %w", err)
+	}
+	remove, err := newResource(name, recordType, data, classNONE, 0)
+	if err != nil {
+		return fmt.Errorf("RFC2136 Delete: %w", err)
+	}
+	if err := r.update(ctx, key, domain, nil, []resource{remove}); err != nil {
+		return fmt.Errorf("RFC2136 Delete: %w", err)
+	}
+	return nil
+}
+
+// query 查询指定名称和类型的记录，NXDOMAIN 返回空结果
+func (r *RFC2136) query(ctx context.Context, key *tsigKey, name string, recordType dnsmessage.Type) ([]dnsmessage.Resource, error) {
+	questionName, err := dnsmessage.NewName(fqdn(name))
+	if err != nil {
+		return nil, fmt.Errorf("域名格式无效: %w", err)
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Uint32())})
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: questionName, Type: recordType, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	msg, err := builder.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	var parser dnsmessage.Parser
+	header, err := r.exchange(ctx, key, msg, &parser)
+	if err != nil {
+		return nil, err
+	}
+	if header.RCode == dnsmessage.RCodeNameError {
+		return nil, nil
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("查询 %s %s 失败: %s", name, typeName(recordType), rcodeName(header.RCode))
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, fmt.Errorf("应答解析失败: %w", err)
+	}
+	answers, err := parser.AllAnswers()
+	if err != nil {
+		return nil, fmt.Errorf("应答解析失败: %w", err)
+	}
+	return answers, nil
+}
+
+// update 发送 UPDATE 报文，zone 为区域名，prerequisites 为前提条件，updates 为更新操作
+func (r *RFC2136) update(ctx context.Context, key *tsigKey, zone string, prerequisites, updates []resource) error {
+	zoneName, err := dnsmessage.NewName(fqdn(zone))
+	if err != nil {
+		return fmt.Errorf("区域名格式无效: %w", err)
+	}
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: uint16(rand.Uint32()), OpCode: opcodeUpdate})
+	// UPDATE 报文的 Zone、Prerequisite、Update 段分别复用 Question、Answer、Authority 段
+	if err := builder.StartQuestions(); err != nil {
+		return err
+	}
+	if err := builder.Question(dnsmessage.Question{Name: zoneName, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}); err != nil {
+		return err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return err
+	}
+	for _, item := range prerequisites {
+		if err := builder.UnknownResource(item.header, item.body); err != nil {
+			return err
+		}
+	}
+	if err := builder.StartAuthorities(); err != nil {
+		return err
+	}
+	for _, item := range updates {
+		if err := builder.UnknownResource(item.header, item.body); err != nil {
+			return err
+		}
+	}
+	msg, err := builder.Finish()
+	if err != nil {
+		return err
+	}
+
+	var parser dnsmessage.Parser
+	header, err := r.exchange(ctx, key, msg, &parser)
+	if err != nil {
+		return err
+	}
+	if header.RCode != dnsmessage.RCodeSuccess {
+		return fmt.Errorf("服务器拒绝更新: %s", rcodeName(header.RCode))
+	}
+	return nil
+}
+
+// exchange 签名并通过 TCP 发送报文，校验应答签名后用 parser 解析应答头部
+func (r *RFC2136) exchange(ctx context.Context, key *tsigKey, msg []byte, parser *dnsmessage.Parser) (dnsmessage.Header, error) {
+	signed, requestMAC, err := key.sign(msg, nil, time.Now())
+	if err != nil {
+		return dnsmessage.Header{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", serverAddress(r.Server))
+	if err != nil {
+		return dnsmessage.Header{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// 取消时关闭连接，结束阻塞的读写
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(signed)))); err != nil {
+		return dnsmessage.Header{}, err
+	}
+	if _, err := conn.Write(signed); err != nil {
+		return dnsmessage.Header{}, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return dnsmessage.Header{}, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return dnsmessage.Header{}, err
+	}
+
+	header, err := parser.Start(resp)
+	if err != nil {
+		return dnsmessage.Header{}, fmt.Errorf("应答解析失败: %w", err)
+	}
+	if header.ID != binary.BigEndian.Uint16(msg[0:2]) {
+		return dnsmessage.Header{}, errors.New("应答报文 ID 不匹配")
+	}
+	if _, err := key.verify(resp, requestMAC, time.Now()); err != nil {
+		// 服务器不认识密钥时会返回未签名的 NOTAUTH 或 REFUSED
+		if header.RCode != dnsmessage.RCodeSuccess {
+			return dnsmessage.Header{}, fmt.Errorf("服务器返回 %s: %w", rcodeName(header.RCode), err)
+		}
+		return dnsmessage.Header{}, err
+	}
+	return header, nil
+}
+
+// validate 检查配置，返回 TSIG 密钥
+func (r *RFC2136) validate(domain string) (*tsigKey, error) {
+	if r.Server == "" {
+		return nil, errors.New("Server 为空")
+	}
+	if r.KeyName == "" || r.Secret == "" {
+		return nil, errors.New("TSIG KeyName 或 Secret 为空")
+	}
+	if domain == "" {
+		return nil, errors.New("domain 为空")
+	}
+	secret, err := base64.StdEncoding.DecodeString(r.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("TSIG Secret 不是有效的 Base64: %w", err)
+	}
+	return &tsigKey{name: strings.ToLower(fqdn(r.KeyName)), secret: secret}, nil
+}
+
+// resource 待写入 UPDATE 报文的资源记录
+type resource struct {
+	header dnsmessage.ResourceHeader
+	body   dnsmessage.UnknownResource
+}
+
+// newResource 生成资源记录，data 为记录数据的文本格式，class 为 NONE 时表示删除指定记录
+func newResource(name, recordType, data string, class dnsmessage.Class, ttl uint32) (resource, error) {
+	resourceName, err := dnsmessage.NewName(fqdn(name))
+	if err != nil {
+		return resource{}, fmt.Errorf("域名格式无效: %w", err)
+	}
+	recordType = strings.ToUpper(recordType)
+	resourceType, ok := recordTypes[recordType]
+	if !ok {
+		return resource{}, fmt.Errorf("不支持的记录类型: %s", recordType)
+	}
+	rdata, err := packData(recordType, data)
+	if err != nil {
+		return resource{}, err
+	}
+	body := dnsmessage.UnknownResource{Type: resourceType, Data: rdata}
+	return resource{header: dnsmessage.ResourceHeader{Name: resourceName, Class: class, TTL: ttl}, body: body}, nil
+}
+
+// packData 把记录数据的文本格式编码为 RDATA
+func packData(recordType, data string) ([]byte, error) {
+	switch recordType {
+	case provider.TypeA, provider.TypeAAAA:
+		addr, err := netip.ParseAddr(data)
+		if err != nil {
+			return nil, fmt.Errorf("记录值 %q 不是有效的 IP 地址", data)
+		}
+		if recordType == provider.TypeA {
+			if !addr.Is4() {
+				return nil, fmt.Errorf("A 记录值 %q 不是 IPv4 地址", data)
+			}
+			bytes := addr.As4()
+			return bytes[:], nil
+		}
+		if !addr.Is6() || addr.Is4In6() {
+			return nil, fmt.Errorf("AAAA 记录值 %q 不是 IPv6 地址", data)
+		}
+		bytes := addr.As16()
+		return bytes[:], nil
+	case provider.TypeCNAME:
+		return packName(data)
+	case provider.TypeMX, provider.TypeSRV:
+		record := provider.Record{Type: recordType, Value: data}
+		if err := provider.ParseInlineValue(&record, true); err != nil {
+			return nil, err
+		}
+		target, err := packName(record.Value)
+		if err != nil {
+			return nil, err
+		}
+		rdata := binary.BigEndian.AppendUint16(nil, uint16(record.Priority))
+		if recordType == provider.TypeSRV {
+			rdata = binary.BigEndian.AppendUint16(rdata, uint16(record.Weight))
+			rdata = binary.BigEndian.AppendUint16(rdata, uint16(record.Port))
+		}
+		return append(rdata, target...), nil
+	case provider.TypeTXT:
+		// 单个字符串最长 255 字节，超出时拆分为多个字符串
+		text := provider.UnquoteTXT(data)
+		var rdata []byte
+		for {
+			chunk := text[:min(len(text), 255)]
+			rdata = append(append(rdata, byte(len(chunk))), chunk...)
+			text = text[len(chunk):]
+			if text == "" {
+				return rdata, nil
+			}
+		}
+	case provider.TypeCAA:
+		flags, tag, value, err := provider.ParseCAA(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(tag) > 255 {
+			return nil, fmt.Errorf("CAA 记录 tag 过长")
+		}
+		rdata := append([]byte{byte(flags), byte(len(tag))}, tag...)
+		return append(rdata, value...), nil
+	default:
+		return nil, fmt.Errorf("不支持的记录类型: %s", recordType)
+	}
+}
+
+// packName 把域名编码为不压缩的 wire 格式
+func packName(name string) ([]byte, error) {
+	if _, err := dnsmessage.NewName(fqdn(name)); err != nil {
+		return nil, fmt.Errorf("目标主机名 %q 无效: %w", name, err)
+	}
+	var data []byte
+	for _, label := range strings.Split(strings.TrimSuffix(fqdn(name), "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("目标主机名 %q 无效", name)
+		}
+		data = append(append(data, byte(len(label))), label...)
+	}
+	return append(data, 0), nil
+}
+
+// resourceData 返回记录数据的文本格式，与 presentation 的格式一致
+func resourceData(answer dnsmessage.Resource) (string, bool) {
+	switch body := answer.Body.(type) {
+	case *dnsmessage.AResource:
+		return netip.AddrFrom4(body.A).String(), true
+	case *dnsmessage.AAAAResource:
+		return netip.AddrFrom16(body.AAAA).String(), true
+	case *dnsmessage.CNAMEResource:
+		return body.CNAME.String(), true
+	case *dnsmessage.MXResource:
+		return fmt.Sprintf("%d %s", body.Pref, body.MX.String()), true
+	case *dnsmessage.SRVResource:
+		return fmt.Sprintf("%d %d %d %s", body.Priority, body.Weight, body.Port, body.Target.String()), true
+	case *dnsmessage.TXTResource:
+		return provider.QuoteTXT(strings.Join(body.TXT, "")), true
+	case *dnsmessage.UnknownResource:
+		// dnsmessage 不解析 CAA，按 RFC 8659 手动解析
+		if body.Type != recordTypes[provider.TypeCAA] || len(body.Data) < 2 || len(body.Data) < 2+int(body.Data[1]) {
+			return "", false
+		}
+		tagEnd := 2 + int(body.Data[1])
+		return provider.CAAValue(int(body.Data[0]), string(body.Data[2:tagEnd]), string(body.Data[tagEnd:])), true
+	default:
+		return "", false
+	}
+}
+
+// presentation 返回记录数据的文本格式，MX、SRV 的数字字段写在记录值前面，主机名补全末尾的点，TXT 带引号
+func presentation(record *provider.Record) string {
+	switch strings.ToUpper(record.Type) {
+	case provider.TypeTXT:
+		return provider.QuoteTXT(record.Value)
+	case provider.TypeCAA:
+		if flags, tag, value, err := provider.ParseCAA(record.Value); err == nil {
+			return provider.CAAValue(flags, tag, value)
+		}
+	case provider.TypeCNAME, provider.TypeMX, provider.TypeSRV:
+		target := *record
+		target.Value = fqdn(strings.ToLower(target.Value))
+		return provider.InlineValue(target, true)
+	}
+	return record.Value
+}
+
+// recordTypes 支持的记录类型
+var recordTypes = map[string]dnsmessage.Type{
+	provider.TypeA:     dnsmessage.TypeA,
+	provider.TypeAAAA:  dnsmessage.TypeAAAA,
+	provider.TypeCNAME: dnsmessage.TypeCNAME,
+	provider.TypeTXT:   dnsmessage.TypeTXT,
+	provider.TypeMX:    dnsmessage.TypeMX,
+	provider.TypeSRV:   dnsmessage.TypeSRV,
+	provider.TypeCAA:   dnsmessage.Type(257),
+}
+
+// typeName 返回记录类型的名称，例如 A、CAA
+func typeName(recordType dnsmessage.Type) string {
+	for name, value := range recordTypes {
+		if value == recordType {
+			return name
+		}
+	}
+	return strings.TrimPrefix(recordType.String(), "Type")
+}
+
+// recordID 生成记录的文本格式，作为 RecordId
+func recordID(name, recordType, data string) string {
+	return fqdn(strings.ToLower(name)) + " " + strings.ToUpper(recordType) + " " + data
+}
+
+// parseRecordID 解析 recordID 生成的 RecordId，记录数据中可以包含空格
+func parseRecordID(recordId string) (string, string, string, error) {
+	fields := strings.SplitN(strings.TrimSpace(recordId), " ", 3)
+	if len(fields) != 3 || fields[2] == "" {
+		return "", "", "", fmt.Errorf("RecordId 格式无效: %q", recordId)
+	}
+	return fields[0], fields[1], fields[2], nil
+}
+
+// recordName 把 RR 和主域名拼接为完整域名
+func recordName(rr, domain string) string {
+	if rr == "" || rr == "@" {
+		return domain
+	}
+	return rr + "." + domain
+}
+
+func recordTTL(ttl int64) uint32 {
+	if ttl <= 0 {
+		return defaultTTL
+	}
+	return uint32(ttl)
+}
+
+// fqdn 为域名补全末尾的点
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// serverAddress 为没有端口的服务器地址补全默认端口
+func serverAddress(server string) string {
+	server = strings.TrimSpace(server)
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(strings.Trim(server, "[]"), defaultPort)
+}
+
+func rcodeName(code dnsmessage.RCode) string {
+	switch code {
+	case dnsmessage.RCodeFormatError:
+		return "FORMERR"
+	case dnsmessage.RCodeServerFailure:
+		return "SERVFAIL"
+	case dnsmessage.RCodeNameError:
+		return "NXDOMAIN"
+	case dnsmessage.RCodeNotImplemented:
+		return "NOTIMP"
+	case dnsmessage.RCodeRefused:
+		return "REFUSED"
+	case 6:
+		return "YXDOMAIN"
+	case 7:
+		return "YXRRSET"
+	case 8:
+		return "NXRRSET（前提条件不满足）"
+	case 9:
+		return "NOTAUTH"
+	case 10:
+		return "NOTZONE"
+	default:
+		return fmt.Sprintf("RCODE %d", code)
+	}
+}
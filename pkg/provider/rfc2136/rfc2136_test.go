@@ -0,0 +1,319 @@
+package rfc2136
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ddns/pkg/provider"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testKeyName = "ddns-key."
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// fakeServer 内存中的权威 DNS 服务器，校验 TSIG 并处理查询与 UPDATE 报文
+type fakeServer struct {
+	mu      sync.Mutex
+	records map[string]bool // 以 recordID 格式保存记录
+	updates int
+}
+
+func newTestRFC2136(t *testing.T, records ...string) (*RFC2136, *fakeServer) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	server := &fakeServer{records: map[string]bool{}}
+	for _, record := range records {
+		server.records[record] = true
+	}
+	key := &tsigKey{name: testKeyName, secret: testSecret}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(t, key, conn)
+		}
+	}()
+	return NewRFC2136(listener.Addr().String(), "ddns-key", base64.StdEncoding.EncodeToString(testSecret)), server
+}
+
+func (s *fakeServer) serve(t *testing.T, key *tsigKey, conn net.Conn) {
+	defer conn.Close()
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return
+	}
+	requestMAC, err := key.verify(msg, nil, time.Now())
+	if err != nil {
+		t.Errorf("server verify() = %v", err)
+		return
+	}
+	resp, err := s.handle(msg)
+	if err != nil {
+		t.Errorf("server handle() = %v", err)
+		return
+	}
+	signed, _, err := key.sign(resp, requestMAC, time.Now())
+	if err != nil {
+		t.Errorf("server sign() = %v", err)
+		return
+	}
+	_, _ = conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(signed))))
+	_, _ = conn.Write(signed)
+}
+
+func (s *fakeServer) handle(msg []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(msg)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	respHeader := dnsmessage.Header{ID: header.ID, Response: true, OpCode: header.OpCode}
+	if header.OpCode != opcodeUpdate {
+		var answers []resource
+		for record := range s.records {
+			name, recordType, data, _ := parseRecordID(record)
+			if name != strings.ToLower(question.Name.String()) || recordType != typeName(question.Type) {
+				continue
+			}
+			answer, err := newResource(name, recordType, data, dnsmessage.ClassINET, 600)
+			if err != nil {
+				return nil, err
+			}
+			answers = append(answers, answer)
+		}
+		if len(answers) == 0 {
+			respHeader.RCode = dnsmessage.RCodeNameError
+		}
+		return buildResponse(respHeader, question, answers)
+	}
+
+	if question.Type != dnsmessage.TypeSOA || question.Name.String() != "example.com." {
+		respHeader.RCode = 10 // NOTZONE
+		return buildResponse(respHeader, question, nil)
+	}
+	prerequisites, err := parser.AllAnswers()
+	if err != nil {
+		return nil, err
+	}
+	for _, prerequisite := range prerequisites {
+		if !s.records[resourceID(prerequisite)] {
+			respHeader.RCode = 8 // NXRRSET
+			return buildResponse(respHeader, question, nil)
+		}
+	}
+	updates, err := parser.AllAuthorities()
+	if err != nil {
+		return nil, err
+	}
+	for _, update := range updates {
+		if update.Header.Class == classNONE {
+			delete(s.records, resourceID(update))
+		} else {
+			s.records[resourceID(update)] = true
+		}
+	}
+	s.updates++
+	return buildResponse(respHeader, question, nil)
+}
+
+func resourceID(resource dnsmessage.Resource) string {
+	data, _ := resourceData(resource)
+	return recordID(resource.Header.Name.String(), typeName(resource.Header.Type), data)
+}
+
+func buildResponse(header dnsmessage.Header, question dnsmessage.Question, answers []resource) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, header)
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, answer := range answers {
+		if err := builder.UnknownResource(answer.header, answer.body); err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+func TestTSIGSignVerify(t *testing.T) {
+	key := &tsigKey{name: testKeyName, secret: testSecret}
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[0:2], 0x1234)
+	now := time.Unix(1700000000, 0)
+	signed, mac, err := key.sign(msg, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint16(signed[10:12]) != 1 {
+		t.Fatalf("ARCOUNT = %d, want 1", binary.BigEndian.Uint16(signed[10:12]))
+	}
+	got, err := key.verify(signed, nil, now.Add(time.Minute))
+	if err != nil || string(got) != string(mac) {
+		t.Fatalf("verify() = %x, %v", got, err)
+	}
+	if _, err := key.verify(signed, nil, now.Add(time.Hour)); err == nil {
+		t.Fatal("verify() accepted expired signature")
+	}
+	wrong := &tsigKey{name: testKeyName, secret: []byte("wrong")}
+	if _, err := wrong.verify(signed, nil, now); err == nil {
+		t.Fatal("verify() accepted wrong secret")
+	}
+	tampered := append([]byte(nil), signed...)
+	tampered[2] ^= 0x80
+	if _, err := key.verify(tampered, nil, now); err == nil {
+		t.Fatal("verify() accepted tampered message")
+	}
+}
+
+func TestCRUDSendsSignedUpdates(t *testing.T) {
+	r, server := newTestRFC2136(t, "www.example.com. A 1.2.3.4", "www.example.com. AAAA 2001:db8::1")
+	ctx := context.Background()
+
+	records, err := r.GetSub(ctx, "www.example.com", provider.IPv4)
+	if err != nil || len(records) != 1 || records[0].RecordId != "www.example.com. A 1.2.3.4" || records[0].RR != "www" || records[0].DomainName != "example.com" {
+		t.Fatalf("GetSub() = %#v, %v", records, err)
+	}
+	if records, err := r.GetSub(ctx, "www.example.com", provider.IPvAll); err != nil || len(records) != 2 {
+		t.Fatalf("GetSub(All) = %#v, %v", records, err)
+	}
+	if _, err := r.GetSub(ctx, "new.example.com", provider.IPv4); !errors.Is(err, provider.ErrRecordNotFound) {
+		t.Fatalf("GetSub(missing) error = %v, want ErrRecordNotFound", err)
+	}
+
+	record := records[0]
+	record.Value = "5.6.7.8"
+	if err := r.Update(ctx, &record); err != nil {
+		t.Fatalf("Update() = %v", err)
+	}
+	if record.RecordId != "www.example.com. A 5.6.7.8" {
+		t.Fatalf("Update() RecordId = %q", record.RecordId)
+	}
+	// 旧记录已不存在，前提条件不满足
+	stale := records[0]
+	stale.Value = "9.9.9.9"
+	if err := r.Update(ctx, &stale); err == nil || !strings.Contains(err.Error(), "NXRRSET") {
+		t.Fatalf("Update(stale) error = %v, want NXRRSET", err)
+	}
+
+	created, err := r.Create(ctx, &provider.Record{DomainName: "example.com", RR: "@", Type: "AAAA", Value: "2001:db8::2", TTL: 300})
+	if err != nil || created.RecordId != "example.com. AAAA 2001:db8::2" {
+		t.Fatalf("Create() = %#v, %v", created, err)
+	}
+	if err := r.Delete(ctx, created.RecordId, created.DomainName); err != nil {
+		t.Fatalf("Delete() = %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	want := map[string]bool{"www.example.com. A 5.6.7.8": true, "www.example.com. AAAA 2001:db8::1": true}
+	if len(server.records) != len(want) || server.updates != 3 {
+		t.Fatalf("server records = %v, updates = %d", server.records, server.updates)
+	}
+	for record := range want {
+		if !server.records[record] {
+			t.Fatalf("server records = %v, missing %q", server.records, record)
+		}
+	}
+}
+
+func TestRecordTypesRoundTrip(t *testing.T) {
+	r, server := newTestRFC2136(t)
+	ctx := context.Background()
+	records := []provider.Record{
+		{Type: "CNAME", RR: "www", Value: "target.example.net"},
+		{Type: "TXT", RR: "txt", Value: `ip="1.2.3.4" ` + strings.Repeat("x", 300)},
+		{Type: "MX", RR: "@", Value: "mail.example.com", Priority: 10},
+		{Type: "SRV", RR: "_minecraft._tcp", Value: "mc.example.com", Priority: 1, Weight: 5, Port: 25565},
+		{Type: "CAA", RR: "@", Value: "0 issue letsencrypt.org"},
+	}
+	for _, record := range records {
+		record.DomainName = "example.com"
+		if _, err := r.Create(ctx, &record); err != nil {
+			t.Fatalf("Create(%s) = %v", record.Type, err)
+		}
+		got, err := r.GetSub(ctx, recordName(record.RR, record.DomainName), provider.IPvAll)
+		if err != nil {
+			t.Fatalf("GetSub(%s) = %v", record.Type, err)
+		}
+		found := false
+		for _, item := range got {
+			if provider.SameData(item, record) {
+				found = item.RecordId == record.RecordId
+			}
+		}
+		if !found {
+			t.Fatalf("GetSub(%s) = %#v, want %#v", record.Type, got, record)
+		}
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !server.records[`example.com. CAA 0 issue "letsencrypt.org"`] || !server.records["_minecraft._tcp.example.com. SRV 1 5 25565 mc.example.com."] {
+		t.Fatalf("server records = %v", server.records)
+	}
+}
+
+func TestValidateAndRecordValues(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewRFC2136("", "key", "c2VjcmV0").GetSub(ctx, "www.example.com", provider.IPv4); err == nil {
+		t.Fatal("GetSub() accepted empty server")
+	}
+	if _, err := NewRFC2136("127.0.0.1", "key", "not base64!").GetSub(ctx, "www.example.com", provider.IPv4); err == nil {
+		t.Fatal("GetSub() accepted invalid secret")
+	}
+	if _, err := NewRFC2136("127.0.0.1", "key", "c2VjcmV0").GetAll(ctx, "example.com", provider.IPvAll); err == nil {
+		t.Fatal("GetAll() should not be supported")
+	}
+	if _, err := newResource("www.example.com", "A", "2001:db8::1", dnsmessage.ClassINET, 600); err == nil {
+		t.Fatal("newResource() accepted IPv6 value for A record")
+	}
+	if _, err := newResource("www.example.com", "NS", "ns1.example.com", dnsmessage.ClassINET, 600); err == nil {
+		t.Fatal("newResource() accepted unsupported type")
+	}
+	if _, err := newResource("www.example.com", "CAA", "issue letsencrypt.org", dnsmessage.ClassINET, 600); err == nil {
+		t.Fatal("newResource() accepted invalid CAA value")
+	}
+	if _, _, _, err := parseRecordID("www.example.com. A"); err == nil {
+		t.Fatal("parseRecordID() accepted invalid id")
+	}
+	tests := map[string]string{"127.0.0.1": "127.0.0.1:53", "ns.example.com:5353": "ns.example.com:5353", "::1": "[::1]:53", "[::1]:53": "[::1]:53"}
+	for server, want := range tests {
+		if got := serverAddress(server); got != want {
+			t.Fatalf("serverAddress(%q) = %q, want %q", server, got, want)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package rfc2136
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TSIG（RFC 8945）签名与校验，只实现 hmac-sha256 算法
+
+const (
+	// tsigAlgorithm 签名算法名称
+	tsigAlgorithm = "hmac-sha256."
+	// tsigFudge 允许的时间误差，单位秒
+	tsigFudge = 300
+
+	typeTSIG = dnsmessage.Type(250)
+	classANY = dnsmessage.Class(255)
+)
+
+// TSIG 错误码
+const (
+	tsigBadSig  = 16
+	tsigBadKey  = 17
+	tsigBadTime = 18
+)
+
+// tsigKey TSIG 密钥
+type tsigKey struct {
+	// name 密钥名称，小写且以点结尾
+	name   string
+	secret []byte
+}
+
+// tsigRecord 解析后的 TSIG 记录
+type tsigRecord struct {
+	keyName    string
+	algorithm  string
+	timeSigned uint64
+	fudge      uint16
+	mac        []byte
+	originalID uint16
+	err        uint16
+	other      []byte
+}
+
+// sign 为已生成的 DNS 报文追加 TSIG 记录，返回签名后的报文和 MAC
+// requestMAC 仅在签名应答报文时使用，请求报文传 nil
+func (k *tsigKey) sign(msg []byte, requestMAC []byte, now time.Time) ([]byte, []byte, error) {
+	if len(msg) < 12 {
+		return nil, nil, errors.New("TSIG 签名: 报文长度无效")
+	}
+	record := tsigRecord{
+		keyName:    k.name,
+		algorithm:  tsigAlgorithm,
+		timeSigned: uint64(now.Unix()),
+		fudge:      tsigFudge,
+		originalID: binary.BigEndian.Uint16(msg[0:2]),
+	}
+	mac, err := k.digest(msg, requestMAC, &record)
+	if err != nil {
+		return nil, nil, err
+	}
+	record.mac = mac
+
+	signed := append([]byte(nil), msg...)
+	if signed, err = appendTSIG(signed, &record); err != nil {
+		return nil, nil, err
+	}
+	// ARCOUNT 加一
+	binary.BigEndian.PutUint16(signed[10:12], binary.BigEndian.Uint16(signed[10:12])+1)
+	return signed, mac, nil
+}
+
+// verify 校验报文末尾的 TSIG 记录，requestMAC 为对应请求的 MAC
+// 返回签名的 MAC，用于继续校验后续报文
+func (k *tsigKey) verify(msg []byte, requestMAC []byte, now time.Time) ([]byte, error) {
+	unsigned, record, err := splitTSIG(msg)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(record.keyName, k.name) {
+		return nil, fmt.Errorf("TSIG 校验: 密钥名称不匹配: %s", record.keyName)
+	}
+	if !strings.EqualFold(record.algorithm, tsigAlgorithm) {
+		return nil, fmt.Errorf("TSIG 校验: 不支持的算法: %s", record.algorithm)
+	}
+	if record.err != 0 {
+		return nil, fmt.Errorf("TSIG 校验: 服务器返回 %s", tsigErrorName(record.err))
+	}
+	expected, err := k.digest(unsigned, requestMAC, record)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(expected, record.mac) {
+		return nil, errors.New("TSIG 校验: 签名不匹配")
+	}
+	signedAt := int64(record.timeSigned)
+	if delta := now.Unix() - signedAt; delta > int64(record.fudge) || -delta > int64(record.fudge) {
+		return nil, fmt.Errorf("TSIG 校验: 签名时间超出允许误差 %d 秒", record.fudge)
+	}
+	return record.mac, nil
+}
+
+// digest 计算 TSIG MAC，msg 为不含 TSIG 记录的报文
+func (k *tsigKey) digest(msg []byte, requestMAC []byte, record *tsigRecord) ([]byte, error) {
+	keyName, err := canonicalName(record.keyName)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := canonicalName(record.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	if requestMAC != nil {
+		mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(requestMAC))))
+		mac.Write(requestMAC)
+	}
+	// 报文 ID 使用原始 ID
+	header := append([]byte(nil), msg[:12]...)
+	binary.BigEndian.PutUint16(header[0:2], record.originalID)
+	mac.Write(header)
+	mac.Write(msg[12:])
+
+	var variables []byte
+	variables = append(variables, keyName...)
+	variables = binary.BigEndian.AppendUint16(variables, uint16(classANY))
+	variables = binary.BigEndian.AppendUint32(variables, 0)
+	variables = append(variables, algorithm...)
+	variables = appendUint48(variables, record.timeSigned)
+	variables = binary.BigEndian.AppendUint16(variables, record.fudge)
+	variables = binary.BigEndian.AppendUint16(variables, record.err)
+	variables = binary.BigEndian.AppendUint16(variables, uint16(len(record.other)))
+	variables = append(variables, record.other...)
+	mac.Write(variables)
+	return mac.Sum(nil), nil
+}
+
+// appendTSIG 把 TSIG 记录追加到报文末尾，不修改 ARCOUNT
+func appendTSIG(msg []byte, record *tsigRecord) ([]byte, error) {
+	keyName, err := canonicalName(record.keyName)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := canonicalName(record.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var rdata []byte
+	rdata = append(rdata, algorithm...)
+	rdata = appendUint48(rdata, record.timeSigned)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.fudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(record.mac)))
+	rdata = append(rdata, record.mac...)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.originalID)
+	rdata = binary.BigEndian.AppendUint16(rdata, record.err)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(record.other)))
+	rdata = append(rdata, record.other...)
+
+	msg = append(msg, keyName...)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(typeTSIG))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(classANY))
+	msg = binary.BigEndian.AppendUint32(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata)))
+	return append(msg, rdata...), nil
+}
+
+// splitTSIG 拆分报文，返回不含 TSIG 的报文（ARCOUNT 已减一）和解析后的 TSIG 记录
+func splitTSIG(msg []byte) ([]byte, *tsigRecord, error) {
+	var parser dnsmessage.Parser
+	if _, err := parser.Start(msg); err != nil {
+		return nil, nil, fmt.Errorf("TSIG 校验: 报文解析失败: %w", err)
+	}
+	if err := parser.SkipAllQuestions(); err != nil {
+		return nil, nil, fmt.Errorf("TSIG 校验: 报文解析失败: %w", err)
+	}
+	if err := parser.SkipAllAnswers(); err != nil {
+		return nil, nil, fmt.Errorf("TSIG 校验: 报文解析失败: %w", err)
+	}
+	if err := parser.SkipAllAuthorities(); err != nil {
+		return nil, nil, fmt.Errorf("TSIG 校验: 报文解析失败: %w", err)
+	}
+	additionals, err := parser.AllAdditionals()
+	if err != nil {
+		return nil, nil, fmt.Errorf("TSIG 校验: 报文解析失败: %w", err)
+	}
+	if len(additionals) == 0 || additionals[len(additionals)-1].Header.Type != typeTSIG {
+		return nil, nil, errors.New("TSIG 校验: 应答报文未签名")
+	}
+	last := additionals[len(additionals)-1]
+	body, ok := last.Body.(*dnsmessage.UnknownResource)
+	if !ok {
+		return nil, nil, errors.New("TSIG 校验: TSIG 记录格式无效")
+	}
+	record, err := parseTSIGData(body.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	record.keyName = last.Header.Name.String()
+
+	offset, err := lastRecordOffset(msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	unsigned := append([]byte(nil), msg[:offset]...)
+	binary.BigEndian.PutUint16(unsigned[10:12], binary.BigEndian.Uint16(unsigned[10:12])-1)
+	return unsigned, record, nil
+}
+
+// parseTSIGData 解析 TSIG 记录的 RDATA
+func parseTSIGData(data []byte) (*tsigRecord, error) {
+	invalid := errors.New("TSIG 校验: TSIG 记录格式无效")
+	algorithm, n, err := readName(data, 0)
+	if err != nil {
+		return nil, invalid
+	}
+	if len(data) < n+10 {
+		return nil, invalid
+	}
+	record := &tsigRecord{algorithm: algorithm}
+	record.timeSigned = uint64(binary.BigEndian.Uint16(data[n:]))<<32 | uint64(binary.BigEndian.Uint32(data[n+2:]))
+	record.fudge = binary.BigEndian.Uint16(data[n+6:])
+	macSize := int(binary.BigEndian.Uint16(data[n+8:]))
+	n += 10
+	if len(data) < n+macSize+6 {
+		return nil, invalid
+	}
+	record.mac = data[n : n+macSize]
+	n += macSize
+	record.originalID = binary.BigEndian.Uint16(data[n:])
+	record.err = binary.BigEndian.Uint16(data[n+2:])
+	otherSize := int(binary.BigEndian.Uint16(data[n+4:]))
+	n += 6
+	if len(data) != n+otherSize {
+		return nil, invalid
+	}
+	record.other = data[n:]
+	return record, nil
+}
+
+// lastRecordOffset 返回报文中最后一条资源记录的起始偏移
+func lastRecordOffset(msg []byte) (int, error) {
+	counts := binary.BigEndian.Uint16(msg[6:8]) + binary.BigEndian.Uint16(msg[8:10]) + binary.BigEndian.Uint16(msg[10:12])
+	offset := 12
+	var err error
+	for i := uint16(0); i < binary.BigEndian.Uint16(msg[4:6]); i++ {
+		if offset, err = skipName(msg, offset); err != nil {
+			return 0, err
+		}
+		offset += 4
+	}
+	last := offset
+	for i := uint16(0); i < counts; i++ {
+		last = offset
+		if offset, err = skipName(msg, offset); err != nil {
+			return 0, err
+		}
+		if len(msg) < offset+10 {
+			return 0, errors.New("TSIG 校验: 报文长度无效")
+		}
+		offset += 10 + int(binary.BigEndian.Uint16(msg[offset+8:offset+10]))
+	}
+	if offset != len(msg) {
+		return 0, errors.New("TSIG 校验: 报文长度无效")
+	}
+	return last, nil
+}
+
+// skipName 跳过报文中 offset 处的域名，返回域名之后的偏移
+func skipName(msg []byte, offset int) (int, error) {
+	for {
+		if offset >= len(msg) {
+			return 0, errors.New("TSIG 校验: 域名格式无效")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xC0 == 0xC0:
+			// 压缩指针占 2 字节，指针之后域名结束
+			return offset + 2, nil
+		default:
+			offset += length + 1
+		}
+	}
+}
+
+// readName 读取未压缩的域名，返回域名和之后的偏移
+func readName(data []byte, offset int) (string, int, error) {
+	var labels []string
+	for {
+		if offset >= len(data) {
+			return "", 0, errors.New("域名格式无效")
+		}
+		length := int(data[offset])
+		offset++
+		if length == 0 {
+			return strings.Join(labels, ".") + ".", offset, nil
+		}
+		if length&0xC0 != 0 || offset+length > len(data) {
+			return "", 0, errors.New("域名格式无效")
+		}
+		labels = append(labels, string(data[offset:offset+length]))
+		offset += length
+	}
+}
+
+// canonicalName 把域名转换为小写的未压缩报文格式
+func canonicalName(name string) ([]byte, error) {
+	name = strings.ToLower(fqdn(name))
+	if name == "." {
+		return []byte{0}, nil
+	}
+	var wire []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("域名格式无效: %s", name)
+		}
+		wire = append(wire, byte(len(label)))
+		wire = append(wire, label...)
+	}
+	return append(wire, 0), nil
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(v>>32))
+	return binary.BigEndian.AppendUint32(b, uint32(v))
+}
+
+func tsigErrorName(code uint16) string {
+	switch code {
+	case tsigBadSig:
+		return "BADSIG"
+	case tsigBadKey:
+		return "BADKEY"
+	case tsigBadTime:
+		return "BADTIME"
+	default:
+		return fmt.Sprintf("TSIG 错误码 %d", code)
+	}
+}
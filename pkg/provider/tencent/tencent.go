@@ -32,6 +32,20 @@ type Tencent struct {
 	secretKey string
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "tencent",
+		Label: "腾讯云",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "SecretId", Required: true},
+			{Key: provider.FieldKeySecret, Label: "SecretKey", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewTencent(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 // NewTencent 新建腾讯云DNS
 // 参数说明：
 // accessKeyId和accessKeySecret：腾讯云密钥
@@ -57,7 +71,7 @@ func (t *Tencent) GetAll(ctx context.Context, domain string, v provider.Version)
 	}
 	payload := struct {
 		Domain     string `json:"Domain"`
-		RecordType string `json:"RecordType"`
+		RecordType string `json:"RecordType,omitempty"`
 	}{
 		Domain:     domain,
 		RecordType: v.RecordType(),
@@ -94,7 +108,7 @@ func (t *Tencent) GetSub(ctx context.Context, subdomain string, v provider.Versi
 	}
 	payload := struct {
 		Domain     string `json:"Domain"`
-		RecordType string `json:"RecordType"`
+		RecordType string `json:"RecordType,omitempty"`
 		SubDomain  string `json:"SubDomain"`
 	}{
 		Domain:     domain,
@@ -247,10 +261,14 @@ func (t *Tencent) addAndUpdate(ctx context.Context, r *provider.Record) error {
 		"Domain":     r.DomainName,
 		"RecordType": r.Type,
 		"RecordLine": "默认",
-		"Value":      r.Value,
+		"Value":      provider.InlineValue(*r, false),
 		"SubDomain":  r.RR,
 		"TTL":        r.TTL,
 	}
+	// MX 优先级使用 MX 参数，SRV 的优先级、权重、端口写在记录值中
+	if r.Type == provider.TypeMX {
+		payload["MX"] = r.Priority
+	}
 
 	var action string
 	if r.RecordId == "" {
@@ -323,6 +341,7 @@ func parseResponse(resp []byte, domain string) ([]provider.Record, error) {
 				Type     string `json:"Type"`     // 记录类型，如 A, CNAME, NS
 				Value    string `json:"Value"`    // 记录值
 				TTL      int64  `json:"TTL"`      // 生存时间
+				MX       int    `json:"MX"`       // MX 优先级
 			} `json:"RecordList"`
 			Error struct {
 				Code    string `json:"Code"`
@@ -355,15 +374,17 @@ func parseResponse(resp []byte, domain string) ([]provider.Record, error) {
 	//使用make预分配内存，减少append内存扩容
 	records := make([]provider.Record, 0, len(respData.Response.RecordList))
 	for _, r := range respData.Response.RecordList {
-		records = append(records, provider.Record{
-
+		record := provider.Record{
 			RecordId:   strconv.FormatInt(r.RecordId, 10),
 			RR:         r.Name,
 			Type:       r.Type,
 			Value:      r.Value,
 			TTL:        r.TTL,
 			DomainName: domain,
-		})
+			Priority:   r.MX,
+		}
+		_ = provider.ParseInlineValue(&record, false)
+		records = append(records, record)
 	}
 	if len(records) == 0 {
 		return nil, fmt.Errorf("parseResponse: 没有解析到域名记录 ， API返回: %s", provider.ResponseBodySummary(resp, false))
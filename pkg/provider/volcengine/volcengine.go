@@ -26,6 +26,20 @@ type Volcengine struct {
 	SecretAccessKey string
 }
 
+func init() {
+	provider.Register(provider.Registration{
+		Name:  "volcengine",
+		Label: "火山引擎",
+		Fields: []provider.CredentialField{
+			{Key: provider.FieldKeyID, Label: "Access Key ID", Required: true},
+			{Key: provider.FieldKeySecret, Label: "Secret Access Key", Required: true, Secret: true},
+		},
+		New: func(c provider.Credentials) (provider.Operator, error) {
+			return NewVolcengine(c.KeyID, c.KeySecret), nil
+		},
+	})
+}
+
 func NewVolcengine(accessKeyID, secretAccessKey string) *Volcengine {
 	return &Volcengine{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey}
 }
@@ -277,7 +291,9 @@ func parseRecords(body []byte, domain, wantedRR, wantedType string) ([]provider.
 		if parsedRR, parsedDomain, err := utils.ParseDomain(strings.TrimSuffix(item.Host, ".")); err == nil && normalizeName(parsedDomain) == normalizeName(domain) {
 			rr = parsedRR
 		}
-		result = append(result, provider.Record{RecordId: scalarString(item.RecordID), DomainName: domain, RR: rr, Type: item.Type, Value: item.Value, TTL: item.TTL})
+		record := provider.Record{RecordId: scalarString(item.RecordID), DomainName: domain, RR: rr, Type: item.Type, Value: item.Value, TTL: item.TTL}
+		_ = provider.ParseInlineValue(&record, true)
+		result = append(result, record)
 	}
 	if len(result) == 0 {
 		return nil, provider.ErrRecordNotFound
@@ -292,8 +308,9 @@ func validateRecord(record *provider.Record) error {
 	return nil
 }
 
+// createPayload 生成创建记录的请求体，MX 优先级和 SRV 的优先级、权重、端口都写在记录值中
 func createPayload(record *provider.Record) map[string]any {
-	return map[string]any{"Host": record.RR, "Type": record.Type, "Value": record.Value, "Line": "default", "TTL": record.TTL}
+	return map[string]any{"Host": record.RR, "Type": record.Type, "Value": provider.InlineValue(*record, true), "Line": "default", "TTL": record.TTL}
 }
 
 func updatePayload(record *provider.Record) map[string]any {
@@ -301,7 +318,7 @@ func updatePayload(record *provider.Record) map[string]any {
 		"RecordID": record.RecordId,
 		"Host":     record.RR,
 		"Type":     record.Type,
-		"Value":    record.Value,
+		"Value":    provider.InlineValue(*record, true),
 		"Line":     "default",
 		"TTL":      record.TTL,
 	}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -49,7 +50,8 @@ func deleteCloudRecords(ctx context.Context, operator CloudOperator, record conf
 		if err != nil {
 			return nil, fmt.Errorf("解析云端记录 %q 失败: %w", subDomain, err)
 		}
-		cloudRecords, err := operator.GetSub(ctx, subDomain, record.IPVersion)
+		recordType := record.RecordType()
+		cloudRecords, err := operator.GetSub(ctx, subDomain, provider.VersionOf(recordType))
 		if errors.Is(err, provider.ErrRecordNotFound) {
 			continue
 		}
@@ -58,9 +60,21 @@ func deleteCloudRecords(ctx context.Context, operator CloudOperator, record conf
 		}
 		matches := make([]provider.Record, 0, 1)
 		for _, cloudRecord := range cloudRecords {
-			if sameCloudRecord(cloudRecord, rr, domain, record.IPVersion.RecordType()) {
-				matches = append(matches, cloudRecord)
+			if !sameCloudRecord(cloudRecord, rr, domain, recordType) {
+				continue
 			}
+			// 静态的 TXT、MX 等记录可能与其他同名同类型记录共存，按记录值只删除自己写入的那条
+			if !record.NeedsAddr() {
+				value, err := record.RenderValue(subDomain, netip.Addr{})
+				if err != nil {
+					return nil, fmt.Errorf("生成云端记录 %q 的记录值失败: %w", subDomain, err)
+				}
+				desired := provider.Record{Type: recordType, Value: value, Priority: record.Priority, Weight: record.Weight, Port: record.Port}
+				if !provider.SameData(cloudRecord, desired) {
+					continue
+				}
+			}
+			matches = append(matches, cloudRecord)
 		}
 		if len(matches) > 1 {
 			return nil, fmt.Errorf("云端记录 %q 存在 %d 条同名同类型记录，无法安全删除", subDomain, len(matches))
@@ -103,5 +117,5 @@ func bestEffortRestoreCloudRecords(ctx context.Context, operator CloudOperator,
 func sameCloudRecord(record provider.Record, rr, domain, recordType string) bool {
 	return strings.EqualFold(strings.TrimSuffix(record.RR, "."), strings.TrimSuffix(rr, ".")) &&
 		strings.EqualFold(strings.TrimSuffix(record.DomainName, "."), strings.TrimSuffix(domain, ".")) &&
-		record.Type == recordType
+		strings.EqualFold(record.Type, recordType)
 }
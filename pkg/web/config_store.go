@@ -16,9 +16,14 @@ func cloneConfig(cfg config.Config) config.Config {
 		clone.Providers[i].Records = slices.Clone(cfg.Providers[i].Records)
 		for j := range clone.Providers[i].Records {
 			clone.Providers[i].Records[j].SubDomains = slices.Clone(cfg.Providers[i].Records[j].SubDomains)
+			clone.Providers[i].Records[j].Hosts = slices.Clone(cfg.Providers[i].Records[j].Hosts)
+			clone.Providers[i].Records[j].Fallbacks = slices.Clone(cfg.Providers[i].Records[j].Fallbacks)
+			clone.Providers[i].Records[j].Command.Args = slices.Clone(cfg.Providers[i].Records[j].Command.Args)
+			clone.Providers[i].Records[j].Command.Env = slices.Clone(cfg.Providers[i].Records[j].Command.Env)
 		}
 	}
 	clone.Webhook.Headers = slices.Clone(cfg.Webhook.Headers)
+	clone.DynDNSClients = slices.Clone(cfg.DynDNSClients)
 	return clone
 }
 
@@ -0,0 +1,184 @@
+package web
+
+import (
+	"crypto/subtle"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"ddns/pkg/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 内置 DynDNS2 接口：旧路由器、摄像头等设备调用 /nic/update 上报 IP 地址，
+// 地址交给 AddrPusher，由 getType 为 dyndns 的记录按正常流程同步到 DNS 服务商。
+// 响应遵循 DynDNS2 协议：good、nochg、badauth、nohost、notfqdn、abuse、911。
+
+// AddrPusher 接收 DynDNS2 客户端推送的 IP 地址
+type AddrPusher interface {
+	// Push 保存客户端推送的地址，返回地址是否发生变化
+	Push(client string, addrs []netip.Addr) bool
+}
+
+const maxDynDNSHostnames = 20
+
+func (s *Server) nicUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if s.pusher == nil {
+		writeDynDNS(w, http.StatusServiceUnavailable, "911")
+		return
+	}
+	clientKey := loginClientKey(r)
+	if retryAfter, locked := s.dyndnsLimit.check(clientKey, time.Now()); locked {
+		setRetryAfter(w, retryAfter)
+		writeDynDNS(w, http.StatusTooManyRequests, "abuse")
+		return
+	}
+	cfg, err := s.readConfig()
+	if err != nil {
+		slog.Error("DynDNS 接口读取配置失败", "err", err)
+		writeDynDNS(w, http.StatusInternalServerError, "911")
+		return
+	}
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="DDNS"`)
+		writeDynDNS(w, http.StatusUnauthorized, "badauth")
+		return
+	}
+	if !validDynDNSClient(cfg.DynDNSClients, username, password) {
+		if retryAfter, locked := s.dyndnsLimit.failure(clientKey, time.Now()); locked {
+			setRetryAfter(w, retryAfter)
+			writeDynDNS(w, http.StatusTooManyRequests, "abuse")
+			return
+		}
+		slog.Warn("DynDNS 客户端认证失败", "client", clientKey, "username", username)
+		w.Header().Set("WWW-Authenticate", `Basic realm="DDNS"`)
+		writeDynDNS(w, http.StatusUnauthorized, "badauth")
+		return
+	}
+	s.dyndnsLimit.success(clientKey)
+
+	hostnames := splitDynDNSList(r.URL.Query().Get("hostname"))
+	if len(hostnames) == 0 || len(hostnames) > maxDynDNSHostnames {
+		writeDynDNS(w, http.StatusOK, "notfqdn")
+		return
+	}
+	allowed := dyndnsHostnames(cfg, username)
+	for _, hostname := range hostnames {
+		if !allowed[hostname] {
+			slog.Warn("DynDNS 客户端更新了未配置的主机名", "username", username, "hostname", hostname)
+			writeDynDNS(w, http.StatusOK, "nohost")
+			return
+		}
+	}
+
+	addrs, ok := dyndnsAddrs(r)
+	if !ok {
+		writeDynDNS(w, http.StatusBadRequest, "badip")
+		return
+	}
+	code := "nochg"
+	if s.pusher.Push(username, addrs) {
+		code = "good"
+		slog.Info("DynDNS 客户端推送新地址", "username", username, "hostnames", hostnames, "IP", addrs)
+	}
+	values := make([]string, len(addrs))
+	for i, addr := range addrs {
+		values[i] = addr.String()
+	}
+	lines := make([]string, len(hostnames))
+	for i := range hostnames {
+		lines[i] = code + " " + strings.Join(values, ",")
+	}
+	writeDynDNS(w, http.StatusOK, strings.Join(lines, "\n"))
+}
+
+// dyndnsDummyHash 用户名不存在时比较的哈希，使认证失败的耗时与用户名存在时一致
+var dyndnsDummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("ddns"), bcrypt.DefaultCost)
+	return hash
+})
+
+// validDynDNSClient 校验客户端凭据，与 Web 登录一样使用 bcrypt 比较密码
+// 始终比较全部用户名，用户名不存在时也比较一次哈希，避免时间差
+func validDynDNSClient(clients []config.DynDNSClient, username, password string) bool {
+	var hash []byte
+	for _, client := range clients {
+		if subtle.ConstantTimeCompare([]byte(username), []byte(client.Username)) == 1 {
+			hash = []byte(client.PasswordHash)
+		}
+	}
+	if hash == nil {
+		_ = bcrypt.CompareHashAndPassword(dyndnsDummyHash(), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// dyndnsHostnames 返回客户端可以更新的主机名，即 getType 为 dyndns 且 getValue 为该客户端的记录的子域名
+func dyndnsHostnames(cfg config.Config, username string) map[string]bool {
+	hostnames := make(map[string]bool)
+	for _, p := range cfg.Providers {
+		for _, record := range p.Records {
+			if record.GetType != "dyndns" || record.GetValue != username {
+				continue
+			}
+			for _, subDomain := range record.SubDomains {
+				hostnames[subDomain] = true
+			}
+		}
+	}
+	return hostnames
+}
+
+// dyndnsAddrs 读取 myip 参数，多个地址用逗号分隔；没有 myip 时使用请求来源地址
+func dyndnsAddrs(r *http.Request) ([]netip.Addr, bool) {
+	values := splitDynDNSList(r.URL.Query().Get("myip"))
+	if len(values) == 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		values = []string{host}
+	}
+	addrs := make([]netip.Addr, 0, len(values))
+	for _, value := range values {
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, false
+		}
+		addr = addr.Unmap()
+		if !slices.Contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, true
+}
+
+func splitDynDNSList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(item)), ".")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func writeDynDNS(w http.ResponseWriter, status int, body string) {
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body+"\n")
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"ddns/pkg/config"
+	"ddns/pkg/provider"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakePusher struct {
+	pushes map[string][]netip.Addr
+}
+
+func (f *fakePusher) Push(client string, addrs []netip.Addr) bool {
+	changed := len(f.pushes[client]) == 0 || f.pushes[client][0] != addrs[0]
+	f.pushes[client] = addrs
+	return changed
+}
+
+type staticConfigStore struct {
+	cfg config.Config
+}
+
+func (s *staticConfigStore) Get() (*config.Config, error) { return &s.cfg, nil }
+func (s *staticConfigStore) Save(*config.Config) error    { return nil }
+
+func TestNicUpdatePushesAddressForConfiguredHostname(t *testing.T) {
+	pusher := &fakePusher{pushes: map[string][]netip.Addr{}}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &staticConfigStore{cfg: config.Config{
+		DynDNSClients: []config.DynDNSClient{{Username: "camera", PasswordHash: string(hash)}},
+		Providers: []config.Provider{{Name: "home", Provider: "aliyun", Records: []config.Record{
+			{Name: "cam", SubDomains: []string{"cam.example.com"}, IPVersion: provider.IPv4, GetType: "dyndns", GetValue: "camera"},
+			{Name: "nas", SubDomains: []string{"nas.example.com"}, IPVersion: provider.IPv4, GetType: "url", GetValue: "https://example.com"},
+		}}},
+	}}
+	server, err := New(Options{ConfigStore: store, Pusher: pusher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close(t.Context()) })
+
+	tests := []struct {
+		name     string
+		query    string
+		user     string
+		password string
+		status   int
+		want     string
+	}{
+		{name: "good", query: "hostname=CAM.example.com.&myip=1.2.3.4", user: "camera", password: "secret", status: http.StatusOK, want: "good 1.2.3.4"},
+		{name: "nochg", query: "hostname=cam.example.com&myip=1.2.3.4", user: "camera", password: "secret", status: http.StatusOK, want: "nochg 1.2.3.4"},
+		{name: "remote address", query: "hostname=cam.example.com", user: "camera", password: "secret", status: http.StatusOK, want: "good 192.0.2.1"},
+		{name: "bad auth", query: "hostname=cam.example.com&myip=1.2.3.4", user: "camera", password: "wrong", status: http.StatusUnauthorized, want: "badauth"},
+		{name: "unknown user", query: "hostname=cam.example.com&myip=1.2.3.4", user: "doorbell", password: "secret", status: http.StatusUnauthorized, want: "badauth"},
+		{name: "other record", query: "hostname=nas.example.com&myip=1.2.3.4", user: "camera", password: "secret", status: http.StatusOK, want: "nohost"},
+		{name: "missing hostname", query: "myip=1.2.3.4", user: "camera", password: "secret", status: http.StatusOK, want: "notfqdn"},
+		{name: "bad ip", query: "hostname=cam.example.com&myip=bad", user: "camera", password: "secret", status: http.StatusBadRequest, want: "badip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/nic/update?"+tt.query, nil)
+			request.SetBasicAuth(tt.user, tt.password)
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			if response.Code != tt.status || strings.TrimSpace(response.Body.String()) != tt.want {
+				t.Fatalf("response = %d %q, want %d %q", response.Code, response.Body.String(), tt.status, tt.want)
+			}
+		})
+	}
+	if got := pusher.pushes["camera"]; len(got) != 1 || got[0] != netip.MustParseAddr("192.0.2.1") {
+		t.Fatalf("pushed = %v", got)
+	}
+}
@@ -35,6 +35,12 @@ const (
 var (
 	ipv4Preset = "https://myip.ipip.net, https://ddns.oray.com/checkip, https://ip.3322.net, https://4.ipw.cn, https://v4.yinghualuo.cn/bejson"
 	ipv6Preset = "https://speed.neu6.edu.cn/getIP.php, https://v6.ident.me, https://6.ipw.cn, https://v6.yinghualuo.cn/bejson"
+	// stunPreset 同时支持 IPv4 和 IPv6 的公共 STUN 服务器
+	stunPreset = "stun.cloudflare.com:3478, stun.l.google.com:19302, stun.miwifi.com:3478"
+	// dnsPreset 返回查询来源地址的公共 DNS 服务，按记录的 IP 版本通过 IPv4 或 IPv6 查询
+	dnsPreset = "myip.opendns.com @resolver1.opendns.com, whoami.cloudflare CH TXT @one.one.one.one, o-o.myaddr.l.google.com TXT @ns1.google.com"
+	// routerPreset 自动发现网关，依次尝试 UPnP IGD、NAT-PMP 和 PCP
+	routerPreset = "upnp, natpmp, pcp"
 )
 
 type Reloader interface {
@@ -71,6 +77,8 @@ type Server struct {
 	templates            *template.Template
 	sessions             *sessionStore
 	loginLimit           *loginLimiter
+	dyndnsLimit          *loginLimiter
+	pusher               AddrPusher
 	cloudOperatorFactory CloudOperatorFactory
 	cloudCleanupQueue    chan cloudCleanupJob
 	cloudCleanupCtx      context.Context
@@ -90,6 +98,8 @@ type Options struct {
 	ConfigChanges        ConfigChangeRegistrar
 	Logs                 *ddnslog.Buffer
 	CloudOperatorFactory CloudOperatorFactory
+	// Pusher 接收 /nic/update 推送的 IP 地址，为空时该接口返回 911
+	Pusher AddrPusher
 }
 
 func New(options Options) (*Server, error) {
@@ -112,6 +122,8 @@ func New(options Options) (*Server, error) {
 		templates:            tmpl,
 		sessions:             newSessionStore(),
 		loginLimit:           newLoginLimiter(),
+		dyndnsLimit:          newLoginLimiter(),
+		pusher:               options.Pusher,
 		cloudOperatorFactory: options.CloudOperatorFactory,
 		cloudCleanupQueue:    make(chan cloudCleanupJob, 16),
 		cloudCleanupCtx:      cleanupCtx,
@@ -158,6 +170,10 @@ func (s *Server) runCloudCleanup(job cloudCleanupJob) {
 		slog.Warn("删除解析记录失败", "provider", job.provider.Name, "record", job.record.Name, "stage", "cloud_init", "err", err)
 		return
 	}
+	if !provider.CapabilitiesOf(operator).Has(provider.CapQuery | provider.CapDelete) {
+		slog.Info("服务商不支持删除云端记录，跳过云端清理", "provider", job.provider.Name, "providerType", job.provider.Provider, "record", job.record.Name)
+		return
+	}
 	ctx, cancel := context.WithTimeout(s.cloudCleanupCtx, 45*time.Second)
 	defer cancel()
 	if err := deleteCloudRecordsWithRetry(ctx, operator, job.record, 3, time.Second); err != nil {
@@ -217,6 +233,8 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		s.importConfig(w, r)
 	case path == "export":
 		s.requireAuth(s.exportConfig)(w, r)
+	case path == "nic/update":
+		s.nicUpdate(w, r)
 	case path == "login":
 		s.login(w, r)
 	case path == "logout":
@@ -459,7 +477,7 @@ func (s *Server) providerForm(idx int) http.HandlerFunc {
 				return
 			}
 			p := cfg.Providers[idx]
-			form = providerForm{Name: p.Name, Provider: p.Provider, KeyID: p.KeyID, ForceInterval: fmt.Sprint(int64(p.ForceInterval)), Records: recordForms(p.Records)}
+			form = providerForm{Name: p.Name, Provider: p.Provider, KeyID: p.KeyID, Server: p.Server, ForceInterval: fmt.Sprint(int64(p.ForceInterval)), Records: recordForms(p.Records)}
 			title = "编辑服务商"
 			action = fmt.Sprintf("/providers/%d", idx)
 		}
@@ -506,8 +524,9 @@ func (s *Server) saveProvider(idx int) http.HandlerFunc {
 			}
 			cfg.Providers[idx] = p
 		} else {
-			if p.KeySecret == "" {
-				s.renderProviderError(w, r, idx, fmt.Errorf("Access Key Secret 不能为空"))
+			registration, _ := provider.Lookup(p.Provider)
+			if field, ok := registration.Field(provider.FieldKeySecret); ok && field.Required && p.KeySecret == "" {
+				s.renderProviderError(w, r, idx, fmt.Errorf("%s 不能为空", field.Label))
 				return
 			}
 			cfg.Providers = append(cfg.Providers, p)
@@ -573,12 +592,7 @@ func (s *Server) recordForm(pIdx, rIdx int) http.HandlerFunc {
 				http.NotFound(w, r)
 				return
 			}
-			rec := cfg.Providers[pIdx].Records[rIdx]
-			form = recordForm{
-				Name: rec.Name, SubDomains: strings.Join(rec.SubDomains, ", "), IPVersion: fmt.Sprint(rec.IPVersion),
-				TTL: fmt.Sprint(rec.TTL), Interval: fmt.Sprint(int64(rec.Interval)), GetType: rec.GetType,
-				GetValue: rec.GetValue, Rule: rec.Rule,
-			}
+			form = newRecordForm(cfg.Providers[pIdx].Records[rIdx])
 			title = "编辑解析记录"
 			action = fmt.Sprintf("/providers/%d/records/%d", pIdx, rIdx)
 		}
@@ -822,7 +836,7 @@ func (s *Server) renderConfigError(w http.ResponseWriter, err error) {
 }
 
 func (s *Server) renderProviderError(w http.ResponseWriter, r *http.Request, idx int, err error) {
-	form := providerForm{Name: r.FormValue("name"), Provider: r.FormValue("provider"), KeyID: r.FormValue("keyId"), ForceInterval: r.FormValue("forceInterval"), Records: []recordForm{{IPVersion: "4", GetType: "url"}}}
+	form := providerForm{Name: r.FormValue("name"), Provider: r.FormValue("provider"), KeyID: r.FormValue("keyId"), Server: r.FormValue("server"), ForceInterval: r.FormValue("forceInterval"), Records: []recordForm{{IPVersion: "4", GetType: "url"}}}
 	action := "/providers"
 	if idx >= 0 {
 		action = fmt.Sprintf("/providers/%d", idx)
@@ -831,7 +845,7 @@ func (s *Server) renderProviderError(w http.ResponseWriter, r *http.Request, idx
 }
 
 func (s *Server) renderRecordError(w http.ResponseWriter, r *http.Request, pIdx, rIdx int, err error) {
-	form := recordForm{Name: r.FormValue("name"), SubDomains: r.FormValue("subDomains"), IPVersion: r.FormValue("ipVersion"), TTL: r.FormValue("ttl"), Interval: r.FormValue("interval"), GetType: r.FormValue("getType"), GetValue: r.FormValue("getValue"), Rule: r.FormValue("rule")}
+	form := recordFormFromRequest(r)
 	action := fmt.Sprintf("/providers/%d/records", pIdx)
 	if rIdx >= 0 {
 		action = fmt.Sprintf("/providers/%d/records/%d", pIdx, rIdx)
@@ -844,14 +858,27 @@ type providerForm struct {
 	Name          string
 	Provider      string
 	KeyID         string
+	Server        string
 	ForceInterval string
 	Records       []recordForm
 }
 
+// CredentialValue 返回凭据字段的回显值，密钥字段不回显
+func (f providerForm) CredentialValue(key string) string {
+	switch key {
+	case provider.FieldKeyID:
+		return f.KeyID
+	case provider.FieldServer:
+		return f.Server
+	default:
+		return ""
+	}
+}
+
 func recordForms(records []config.Record) []recordForm {
 	forms := make([]recordForm, 0, len(records))
 	for _, rec := range records {
-		forms = append(forms, recordForm{Name: rec.Name, SubDomains: strings.Join(rec.SubDomains, ", "), IPVersion: fmt.Sprint(rec.IPVersion), TTL: fmt.Sprint(rec.TTL), Interval: fmt.Sprint(int64(rec.Interval)), GetType: rec.GetType, GetValue: rec.GetValue, Rule: rec.Rule})
+		forms = append(forms, newRecordForm(rec))
 	}
 	if len(forms) == 0 {
 		return []recordForm{{IPVersion: "4", GetType: "url"}}
@@ -881,11 +908,41 @@ func parseProviderRecords(r *http.Request) ([]config.Record, error) {
 	for i := range names {
 		getType := r.FormValue(fmt.Sprintf("recordGetType%d", i))
 		form := recordForm{Name: names[i], SubDomains: r.Form["recordSubDomains"][i], IPVersion: r.Form["recordIPVersion"][i], TTL: r.Form["recordTTL"][i], Interval: r.Form["recordInterval"][i], GetType: getType, GetValue: r.Form["recordGetValue"][i], Rule: r.Form["recordRule"][i]}
-		if form.GetType == "url" && strings.TrimSpace(form.GetValue) == "" {
-			if form.IPVersion == "6" {
-				form.GetValue = ipv6Preset
-			} else {
-				form.GetValue = ipv4Preset
+		// recordProxied 只在 Cloudflare 表单中出现，记录类型相关字段旧页面不会提交，缺少时保持为空
+		optional := map[string]*string{
+			"recordProxied":        &form.Proxied,
+			"recordExtract":        &form.Extract,
+			"recordQuorum":         &form.Quorum,
+			"recordFallbacks":      &form.Fallbacks,
+			"recordSourceTimeout":  &form.SourceTimeout,
+			"recordCommandArgs":    &form.CommandArgs,
+			"recordCommandDir":     &form.CommandDir,
+			"recordCommandEnv":     &form.CommandEnv,
+			"recordCommandTimeout": &form.CommandTimeout,
+			"recordHealthType":     &form.HealthType,
+			"recordHealthPort":     &form.HealthPort,
+			"recordHealthPath":     &form.HealthPath,
+			"recordHealthHost":     &form.HealthHost,
+			"recordHealthStatus":   &form.HealthStatus,
+			"recordHealthTimeout":  &form.HealthTimeout,
+			"recordPublish":        &form.Publish,
+			"recordPolicyInclude":  &form.PolicyInclude,
+			"recordPolicyExclude":  &form.PolicyExclude,
+			"recordPolicyPrivate":  &form.PolicyPrivate,
+			"recordPolicyULA":      &form.PolicyULA,
+			"recordPolicyCGNAT":    &form.PolicyCGNAT,
+			"recordPolicyIPv6":     &form.PolicyIPv6,
+			"recordPrefixLength":   &form.PrefixLength,
+			"recordHosts":          &form.Hosts,
+			"recordType":           &form.Type,
+			"recordValue":          &form.Value,
+			"recordPriority":       &form.Priority,
+			"recordWeight":         &form.Weight,
+			"recordPort":           &form.Port,
+		}
+		for name, field := range optional {
+			if values := r.Form[name]; i < len(values) {
+				*field = values[i]
 			}
 		}
 		rec, err := parseRecordForm(form)
@@ -902,6 +959,7 @@ func parseProvider(r *http.Request) (config.Provider, error) {
 	p := config.Provider{
 		Name: strings.TrimSpace(r.FormValue("name")), Provider: strings.TrimSpace(r.FormValue("provider")),
 		KeyID: strings.TrimSpace(r.FormValue("keyId")), KeySecret: strings.TrimSpace(r.FormValue("keySecret")),
+		Server:        strings.TrimSpace(r.FormValue("server")),
 		ForceInterval: forceInterval, Records: []config.Record{},
 	}
 	if p.Name == "" {
@@ -910,26 +968,164 @@ func parseProvider(r *http.Request) (config.Provider, error) {
 	if p.Provider == "" {
 		return p, fmt.Errorf("请选择服务商类型")
 	}
-	if p.KeyID == "" {
-		return p, fmt.Errorf("Access Key ID 不能为空")
+	registration, ok := provider.Lookup(p.Provider)
+	if !ok {
+		return p, fmt.Errorf("不支持的服务商类型：%s", p.Provider)
+	}
+	// 密钥字段编辑时可以留空保持不变，由调用方处理
+	credentials := p.Credentials()
+	for _, field := range registration.Fields {
+		if field.Required && !field.Secret && credentials.Get(field.Key) == "" {
+			return p, fmt.Errorf("%s 不能为空", field.Label)
+		}
 	}
 	return p, nil
 }
 
 type recordForm struct {
-	Name       string
-	SubDomains string
-	IPVersion  string
-	TTL        string
-	Interval   string
-	GetType    string
-	GetValue   string
-	Rule       string
+	Name          string
+	SubDomains    string
+	IPVersion     string
+	TTL           string
+	Interval      string
+	GetType       string
+	GetValue      string
+	Rule          string
+	Extract       string
+	Quorum        string
+	Fallbacks     string
+	SourceTimeout string
+	// 结构化命令，参数和环境变量每行一个
+	CommandArgs    string
+	CommandDir     string
+	CommandEnv     string
+	CommandTimeout string
+	// 候选地址的健康检查，检查方式为空时不检查
+	HealthType    string
+	HealthPort    string
+	HealthPath    string
+	HealthHost    string
+	HealthStatus  string
+	HealthTimeout string
+	// 发布方式，all 发布所有地址
+	Publish       string
+	PolicyInclude string
+	PolicyExclude string
+	PolicyPrivate string
+	PolicyULA     string
+	PolicyCGNAT   string
+	PolicyIPv6    string
+	PrefixLength  string
+	Hosts         string
+	Proxied       string
+	Type          string
+	Value         string
+	Priority      string
+	Weight        string
+	Port          string
+}
+
+// staticGetType 表单中“不获取 IP”选项的值，对应配置文件中空的 getType
+const staticGetType = "static"
+
+// newRecordForm 把配置中的记录转换为表单值
+func newRecordForm(rec config.Record) recordForm {
+	form := recordForm{
+		Name: rec.Name, SubDomains: strings.Join(rec.SubDomains, ", "), IPVersion: fmt.Sprint(rec.IPVersion),
+		TTL: fmt.Sprint(rec.TTL), Interval: fmt.Sprint(int64(rec.Interval)), GetType: rec.GetType,
+		GetValue: rec.GetValue, Rule: rec.Rule, Extract: rec.Extract, Proxied: proxiedValue(rec.Proxied),
+		Type: rec.Type, Value: rec.Value,
+	}
+	if !rec.NeedsAddr() {
+		form.GetType = staticGetType
+	}
+	if rec.Quorum != 0 {
+		form.Quorum = fmt.Sprint(rec.Quorum)
+	}
+	form.Fallbacks = fallbacksText(rec.Fallbacks)
+	form.CommandArgs, form.CommandDir, form.CommandEnv = strings.Join(rec.Command.Args, "\n"), rec.Command.Dir, strings.Join(rec.Command.Env, "\n")
+	if rec.Command.Timeout != 0 {
+		form.CommandTimeout = fmt.Sprint(rec.Command.Timeout)
+	}
+	if rec.SourceTimeout != 0 {
+		form.SourceTimeout = fmt.Sprint(rec.SourceTimeout)
+	}
+	form.HealthType, form.HealthPath, form.HealthHost = rec.Health.Type, rec.Health.Path, rec.Health.Host
+	form.Publish = rec.Publish
+	if rec.Health.Port != 0 {
+		form.HealthPort = fmt.Sprint(rec.Health.Port)
+	}
+	if rec.Health.Status != 0 {
+		form.HealthStatus = fmt.Sprint(rec.Health.Status)
+	}
+	if rec.Health.Timeout != 0 {
+		form.HealthTimeout = fmt.Sprint(rec.Health.Timeout)
+	}
+	form.PolicyInclude = strings.Join(rec.Policy.Include, ", ")
+	form.PolicyExclude = strings.Join(rec.Policy.Exclude, ", ")
+	form.PolicyPrivate, form.PolicyULA, form.PolicyCGNAT = boolValue(rec.Policy.AllowPrivate), boolValue(rec.Policy.AllowULA), boolValue(rec.Policy.AllowCGNAT)
+	form.PolicyIPv6 = rec.Policy.IPv6
+	if rec.PrefixLength != 0 {
+		form.PrefixLength = fmt.Sprint(rec.PrefixLength)
+	}
+	form.Hosts = hostsText(rec.Hosts)
+	if rec.Priority != 0 || rec.RecordType() == provider.TypeMX || rec.RecordType() == provider.TypeSRV {
+		form.Priority = fmt.Sprint(rec.Priority)
+	}
+	if rec.Weight != 0 || rec.RecordType() == provider.TypeSRV {
+		form.Weight = fmt.Sprint(rec.Weight)
+	}
+	if rec.Port != 0 {
+		form.Port = fmt.Sprint(rec.Port)
+	}
+	return form
+}
+
+// recordFormFromRequest 读取单条记录表单提交的值
+func recordFormFromRequest(r *http.Request) recordForm {
+	return recordForm{
+		Name:           r.FormValue("name"),
+		SubDomains:     r.FormValue("subDomains"),
+		IPVersion:      r.FormValue("ipVersion"),
+		TTL:            r.FormValue("ttl"),
+		Interval:       r.FormValue("interval"),
+		GetType:        r.FormValue("getType"),
+		GetValue:       r.FormValue("getValue"),
+		Rule:           r.FormValue("rule"),
+		Extract:        r.FormValue("extract"),
+		Quorum:         r.FormValue("quorum"),
+		Fallbacks:      r.FormValue("fallbacks"),
+		SourceTimeout:  r.FormValue("sourceTimeout"),
+		CommandArgs:    r.FormValue("commandArgs"),
+		CommandDir:     r.FormValue("commandDir"),
+		CommandEnv:     r.FormValue("commandEnv"),
+		CommandTimeout: r.FormValue("commandTimeout"),
+		HealthType:     r.FormValue("healthType"),
+		HealthPort:     r.FormValue("healthPort"),
+		HealthPath:     r.FormValue("healthPath"),
+		HealthHost:     r.FormValue("healthHost"),
+		HealthStatus:   r.FormValue("healthStatus"),
+		HealthTimeout:  r.FormValue("healthTimeout"),
+		Publish:        r.FormValue("publish"),
+		PolicyInclude:  r.FormValue("policyInclude"),
+		PolicyExclude:  r.FormValue("policyExclude"),
+		PolicyPrivate:  r.FormValue("policyPrivate"),
+		PolicyULA:      r.FormValue("policyULA"),
+		PolicyCGNAT:    r.FormValue("policyCGNAT"),
+		PolicyIPv6:     r.FormValue("policyIPv6"),
+		PrefixLength:   r.FormValue("prefixLength"),
+		Hosts:          r.FormValue("hosts"),
+		Proxied:        r.FormValue("proxied"),
+		Type:           r.FormValue("type"),
+		Value:          r.FormValue("value"),
+		Priority:       r.FormValue("priority"),
+		Weight:         r.FormValue("weight"),
+		Port:           r.FormValue("port"),
+	}
 }
 
 func parseRecord(r *http.Request) (config.Record, error) {
-	form := recordForm{Name: r.FormValue("name"), SubDomains: r.FormValue("subDomains"), IPVersion: r.FormValue("ipVersion"), TTL: r.FormValue("ttl"), Interval: r.FormValue("interval"), GetType: r.FormValue("getType"), GetValue: r.FormValue("getValue"), Rule: r.FormValue("rule")}
-	return parseRecordForm(form)
+	return parseRecordForm(recordFormFromRequest(r))
 }
 
 func parseRecordForm(form recordForm) (config.Record, error) {
@@ -938,6 +1134,9 @@ func parseRecordForm(form recordForm) (config.Record, error) {
 	interval := int64(parseIntDefault(form.Interval, 30))
 	getType := strings.TrimSpace(form.GetType)
 	getValue := strings.TrimSpace(form.GetValue)
+	if getType == staticGetType {
+		getType, getValue = "", ""
+	}
 	if getType == "url" && getValue == "" {
 		if ipVersion == provider.IPv6 {
 			getValue = ipv6Preset
@@ -945,10 +1144,89 @@ func parseRecordForm(form recordForm) (config.Record, error) {
 			getValue = ipv4Preset
 		}
 	}
+	if getType == "stun" && getValue == "" {
+		getValue = stunPreset
+	}
+	if getType == "dns" && getValue == "" {
+		getValue = dnsPreset
+	}
+	if getType == "router" && getValue == "" {
+		getValue = routerPreset
+	}
 	rec := config.Record{
 		Name: strings.TrimSpace(form.Name), SubDomains: splitDomains(form.SubDomains),
 		IPVersion: ipVersion, TTL: ttl, GetType: getType, GetValue: getValue,
 		Interval: interval, Rule: strings.TrimSpace(form.Rule),
+		Type: strings.ToUpper(strings.TrimSpace(form.Type)), Value: strings.TrimSpace(form.Value),
+		Priority: parseIntDefault(form.Priority, 0), Weight: parseIntDefault(form.Weight, 0), Port: parseIntDefault(form.Port, 0),
+	}
+	// 提取规则只对 URL、系统命令生效，其他获取方式不保存隐藏的输入
+	if getType == "url" || getType == "cmd" {
+		rec.Extract = strings.TrimSpace(form.Extract)
+	}
+	if getType == "url" {
+		rec.Quorum = parseIntDefault(form.Quorum, 0)
+	}
+	// 结构化命令的参数每行一个，不经过 shell 解析
+	if getType == "cmd" {
+		rec.Command = config.CommandSpec{
+			Args: splitLines(form.CommandArgs), Dir: strings.TrimSpace(form.CommandDir),
+			Env: splitLines(form.CommandEnv), Timeout: parseIntDefault(form.CommandTimeout, 0),
+		}
+	}
+	// 推送方式不支持备用获取方式，不保存隐藏的输入
+	if getType != "" && getType != "dyndns" {
+		fallbacks, err := parseFallbacks(form.Fallbacks)
+		if err != nil {
+			return rec, err
+		}
+		rec.Fallbacks, rec.SourceTimeout = fallbacks, parseIntDefault(form.SourceTimeout, 0)
+	}
+	// 没有选择检查方式时不保存隐藏的输入，HTTP 的路径、Host、状态码只在 HTTP 检查时保存
+	if healthType := strings.TrimSpace(form.HealthType); getType != "" && healthType != "" {
+		rec.Health = config.HealthCheck{Type: healthType, Port: parseIntDefault(form.HealthPort, 0), Timeout: parseIntDefault(form.HealthTimeout, 0)}
+		if healthType == config.HealthHTTP {
+			rec.Health.Path, rec.Health.Host = strings.TrimSpace(form.HealthPath), strings.TrimSpace(form.HealthHost)
+			rec.Health.Status = parseIntDefault(form.HealthStatus, 0)
+		}
+	}
+	// 静态记录不获取地址，不保存地址策略
+	if getType != "" {
+		rec.Policy = config.AddrPolicy{
+			Include: splitDomains(form.PolicyInclude), Exclude: splitDomains(form.PolicyExclude),
+			AllowPrivate: form.PolicyPrivate == "true", AllowULA: form.PolicyULA == "true", AllowCGNAT: form.PolicyCGNAT == "true",
+			IPv6: strings.TrimSpace(form.PolicyIPv6),
+		}
+	}
+	// 多值记录只用于 A、AAAA 记录
+	if getType != "" && rec.IsAddress() {
+		rec.Publish = strings.TrimSpace(form.Publish)
+	}
+	// 前缀跟踪只用于 AAAA 记录
+	if getType != "" && rec.IsAddress() {
+		hosts, err := parseHosts(form.Hosts)
+		if err != nil {
+			return rec, err
+		}
+		rec.PrefixLength, rec.Hosts = parseIntDefault(form.PrefixLength, 0), hosts
+	}
+	// A、AAAA 由 IP 版本决定，配置文件中不重复保存 type；表单中隐藏的字段按记录类型清空
+	switch rec.RecordType() {
+	case provider.TypeA, provider.TypeAAAA:
+		rec.Type, rec.Value, rec.Priority, rec.Weight, rec.Port = "", "", 0, 0, 0
+	case provider.TypeMX:
+		rec.Weight, rec.Port = 0, 0
+	case provider.TypeSRV:
+	default:
+		rec.Priority, rec.Weight, rec.Port = 0, 0, 0
+	}
+	switch strings.TrimSpace(form.Proxied) {
+	case "true":
+		proxied := true
+		rec.Proxied = &proxied
+	case "false":
+		proxied := false
+		rec.Proxied = &proxied
 	}
 	if rec.Name == "" {
 		return rec, fmt.Errorf("记录名称不能为空")
@@ -956,18 +1234,111 @@ func parseRecordForm(form recordForm) (config.Record, error) {
 	if len(rec.SubDomains) == 0 {
 		return rec, fmt.Errorf("子域名不能为空")
 	}
+	if rec.GetType == "" && rec.IsAddress() {
+		return rec, fmt.Errorf("A、AAAA 记录请选择获取方式")
+	}
 	if rec.GetType == "" {
-		return rec, fmt.Errorf("请选择获取方式")
+		return rec, nil
 	}
 	if rec.GetType == "duid" && rec.IPVersion != provider.IPv6 {
 		return rec, fmt.Errorf("DUID标识仅支持 IPv6")
 	}
-	if rec.GetType != "url" && rec.GetValue == "" {
+	if rec.GetType == "cmd" && rec.GetValue != "" && len(rec.Command.Args) > 0 {
+		return rec, fmt.Errorf("系统命令和命令参数只能填写一个")
+	}
+	if rec.GetType != "url" && rec.GetValue == "" && len(rec.Command.Args) == 0 {
 		return rec, fmt.Errorf("%s 获取方式必须填写对应值", rec.GetType)
 	}
 	return rec, nil
 }
 
+// proxiedValue 把代理设置转换为表单值，空字符串表示保持云端设置
+func proxiedValue(proxied *bool) string {
+	if proxied == nil {
+		return ""
+	}
+	return strconv.FormatBool(*proxied)
+}
+
+// hostsText 把前缀跟踪主机转换为表单值，每行一个主机：子域名 后缀或mac@MAC地址 [子网序号]
+func hostsText(hosts []config.PrefixHost) string {
+	lines := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		line := host.SubDomain + " " + host.Suffix
+		if host.MAC != "" {
+			line = host.SubDomain + " mac@" + host.MAC
+		}
+		if host.Subnet != 0 {
+			line += " " + strconv.Itoa(host.Subnet)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseHosts 解析前缀跟踪主机表单值，空行忽略
+func parseHosts(text string) ([]config.PrefixHost, error) {
+	var hosts []config.PrefixHost
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("前缀跟踪主机第 %d 行格式无效，请填写：子域名 后缀或mac@MAC地址 [子网序号]", i+1)
+		}
+		host := config.PrefixHost{SubDomain: fields[0], Suffix: fields[1]}
+		if mac, ok := strings.CutPrefix(fields[1], "mac@"); ok {
+			host.Suffix, host.MAC = "", mac
+		}
+		if len(fields) == 3 {
+			subnet, err := strconv.Atoi(fields[2])
+			if err != nil || subnet < 0 {
+				return nil, fmt.Errorf("前缀跟踪主机第 %d 行的子网序号无效: %s", i+1, fields[2])
+			}
+			host.Subnet = subnet
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// fallbacksText 把备用获取方式转换为表单值，每行一个：获取方式 值
+func fallbacksText(fallbacks []config.Fallback) string {
+	lines := make([]string, 0, len(fallbacks))
+	for _, fb := range fallbacks {
+		lines = append(lines, strings.TrimSpace(fb.GetType+" "+fb.GetValue))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseFallbacks 解析备用获取方式表单值，值中可以包含空格，空行忽略
+func parseFallbacks(text string) ([]config.Fallback, error) {
+	var fallbacks []config.Fallback
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		getType := strings.Fields(line)[0]
+		getValue := strings.TrimSpace(line[len(getType):])
+		getType = strings.ToLower(getType)
+		if getValue == "" && getType != "router" {
+			return nil, fmt.Errorf("备用获取方式第 %d 行格式无效，请填写：获取方式 值", i+1)
+		}
+		fallbacks = append(fallbacks, config.Fallback{GetType: getType, GetValue: getValue})
+	}
+	return fallbacks, nil
+}
+
+// boolValue 把开关转换为表单值，关闭时为空
+func boolValue(value bool) string {
+	if value {
+		return "true"
+	}
+	return ""
+}
+
 type webhookForm struct {
 	URL        string
 	DisplayURL string
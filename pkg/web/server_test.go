@@ -5,6 +5,7 @@ import (
 	"context"
 	"ddns/pkg/config"
 	"ddns/pkg/provider"
+	_ "ddns/pkg/provider/all"
 	"ddns/pkg/version"
 	"fmt"
 	"mime/multipart"
@@ -13,6 +14,8 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -209,6 +212,21 @@ func TestDeleteCloudRecordsMatchesExactRecord(t *testing.T) {
 	}
 }
 
+func TestDeleteCloudRecordsMatchesStaticValue(t *testing.T) {
+	operator := &fakeCloudOperator{records: []provider.Record{
+		{RecordId: "spf", DomainName: "example.com", RR: "nas", Type: "TXT", Value: `"v=spf1 -all"`},
+		{RecordId: "verify", DomainName: "example.com", RR: "nas", Type: "TXT", Value: `"verify=nas.example.com"`},
+	}}
+	record := config.Record{Type: "TXT", Value: "verify={{.SubDomain}}", SubDomains: []string{"nas.example.com"}}
+
+	if _, err := deleteCloudRecords(context.Background(), operator, record); err != nil {
+		t.Fatal(err)
+	}
+	if len(operator.deleted) != 1 || operator.deleted[0] != "verify@example.com" {
+		t.Fatalf("deleted records = %v, want [verify@example.com]", operator.deleted)
+	}
+}
+
 func TestDeleteCloudRecordsRejectsAmbiguousMatches(t *testing.T) {
 	operator := &fakeCloudOperator{records: []provider.Record{
 		{RecordId: "first", DomainName: "example.com", RR: "nas", Type: "A"},
@@ -1211,6 +1229,158 @@ func TestParseProviderRecordsRejectsMismatchedFields(t *testing.T) {
 	}
 }
 
+func TestParseProviderRecordsReadsRecordType(t *testing.T) {
+	form := url.Values{
+		"recordName":       {"mail", "nas"},
+		"recordSubDomains": {"example.com", "nas.example.com"},
+		"recordIPVersion":  {"4", "4"},
+		"recordTTL":        {"600", "600"},
+		"recordInterval":   {"30", "30"},
+		"recordGetType0":   {"static"},
+		"recordGetType1":   {"url"},
+		"recordGetValue":   {"", "https://example.com"},
+		"recordRule":       {"", ""},
+		"recordType":       {"mx", ""},
+		"recordValue":      {"mail.example.com", "hidden"},
+		"recordPriority":   {"10", "5"},
+		"recordWeight":     {"3", ""},
+		"recordPort":       {"", ""},
+	}
+	request := &http.Request{Form: form}
+
+	records, err := parseProviderRecords(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mx := records[0]
+	if mx.Type != "MX" || mx.GetType != "" || mx.GetValue != "" || mx.Value != "mail.example.com" || mx.Priority != 10 || mx.Weight != 0 {
+		t.Fatalf("mx record = %#v", mx)
+	}
+	address := records[1]
+	if address.Type != "" || address.GetType != "url" || address.Value != "" || address.Priority != 0 {
+		t.Fatalf("address record = %#v", address)
+	}
+}
+
+func TestParseRecordFormReadsPrefixHosts(t *testing.T) {
+	form := recordForm{
+		Name: "lan", SubDomains: "nas.example.com, printer.example.com", IPVersion: "6", GetType: "nic", GetValue: "br-lan",
+		PrefixLength: "56", Hosts: "nas.example.com ::10\n\n printer.example.com mac@00:11:22:33:44:55 1 \n",
+	}
+	rec, err := parseRecordForm(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.PrefixHost{
+		{SubDomain: "nas.example.com", Suffix: "::10"},
+		{SubDomain: "printer.example.com", MAC: "00:11:22:33:44:55", Subnet: 1},
+	}
+	if rec.PrefixLength != 56 || !slices.Equal(rec.Hosts, want) {
+		t.Fatalf("record = %d %#v", rec.PrefixLength, rec.Hosts)
+	}
+	if got := newRecordForm(rec).Hosts; got != "nas.example.com ::10\nprinter.example.com mac@00:11:22:33:44:55 1" {
+		t.Fatalf("hosts text = %q", got)
+	}
+
+	form.Hosts = "nas.example.com"
+	if _, err := parseRecordForm(form); err == nil {
+		t.Fatal("parseRecordForm() accepted a host without suffix")
+	}
+}
+
+func TestParseRecordFormReadsFallbacks(t *testing.T) {
+	form := recordForm{
+		Name: "wan", SubDomains: "home.example.com", IPVersion: "4", GetType: "nic", GetValue: "pppoe-wan",
+		Fallbacks: "url https://4.ipw.cn\n\n cmd\tcurl -s https://ip.example.com \nrouter\n", SourceTimeout: "5",
+	}
+	rec, err := parseRecordForm(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.Fallback{
+		{GetType: "url", GetValue: "https://4.ipw.cn"},
+		{GetType: "cmd", GetValue: "curl -s https://ip.example.com"},
+		{GetType: "router"},
+	}
+	if rec.SourceTimeout != 5 || !slices.Equal(rec.Fallbacks, want) {
+		t.Fatalf("record = %d %#v", rec.SourceTimeout, rec.Fallbacks)
+	}
+	if got := newRecordForm(rec).Fallbacks; got != "url https://4.ipw.cn\ncmd curl -s https://ip.example.com\nrouter" {
+		t.Fatalf("fallbacks text = %q", got)
+	}
+
+	form.Fallbacks = "stun"
+	if _, err := parseRecordForm(form); err == nil {
+		t.Fatal("parseRecordForm() accepted a fallback without value")
+	}
+}
+
+func TestParseRecordFormReadsCommand(t *testing.T) {
+	form := recordForm{
+		Name: "router", SubDomains: "home.example.com", IPVersion: "4", GetType: "cmd",
+		CommandArgs: "/usr/bin/ssh\n admin@192.168.1.1 \n\nshow ip interface\n", CommandDir: " /tmp ", CommandEnv: "LANG=C", CommandTimeout: "30",
+	}
+	rec, err := parseRecordForm(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.CommandSpec{Args: []string{"/usr/bin/ssh", "admin@192.168.1.1", "show ip interface"}, Dir: "/tmp", Env: []string{"LANG=C"}, Timeout: 30}
+	if rec.GetValue != "" || !reflect.DeepEqual(rec.Command, want) {
+		t.Fatalf("record = %q %#v", rec.GetValue, rec.Command)
+	}
+	if got := newRecordForm(rec); got.CommandArgs != "/usr/bin/ssh\nadmin@192.168.1.1\nshow ip interface" || got.CommandTimeout != "30" {
+		t.Fatalf("command form = %#v", got)
+	}
+
+	form.GetValue = "ip addr"
+	if _, err := parseRecordForm(form); err == nil {
+		t.Fatal("parseRecordForm() accepted both shell command and args")
+	}
+	form.GetType = "url"
+	if rec, err := parseRecordForm(form); err != nil || !rec.Command.IsZero() {
+		t.Fatalf("url record command = %#v, %v", rec.Command, err)
+	}
+}
+
+func TestParseRecordFormReadsHealthCheck(t *testing.T) {
+	form := recordForm{
+		Name: "home", SubDomains: "home.example.com", IPVersion: "4", GetType: "nic", GetValue: "eth0",
+		HealthType: "tcp", HealthPort: "443", HealthPath: "/healthz", HealthStatus: "204", HealthTimeout: "5",
+	}
+	rec, err := parseRecordForm(form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TCP 检查不保存 HTTP 的隐藏输入
+	if want := (config.HealthCheck{Type: "tcp", Port: 443, Timeout: 5}); rec.Health != want {
+		t.Fatalf("health = %#v", rec.Health)
+	}
+	form.HealthType = "http"
+	if rec, err = parseRecordForm(form); err != nil || rec.Health.Path != "/healthz" || rec.Health.Status != 204 {
+		t.Fatalf("http health = %#v, %v", rec.Health, err)
+	}
+	if got := newRecordForm(rec); got.HealthType != "http" || got.HealthPort != "443" || got.HealthStatus != "204" {
+		t.Fatalf("health form = %#v", got)
+	}
+	form.HealthType = ""
+	if rec, err = parseRecordForm(form); err != nil || !rec.Health.IsZero() {
+		t.Fatalf("disabled health = %#v, %v", rec.Health, err)
+	}
+}
+
+func TestParseRecordFormReadsPublish(t *testing.T) {
+	form := recordForm{Name: "nas", SubDomains: "nas.example.com", IPVersion: "6", GetType: "nic", GetValue: "br-lan", Publish: "all"}
+	rec, err := parseRecordForm(form)
+	if err != nil || rec.Publish != config.PublishAll || newRecordForm(rec).Publish != "all" {
+		t.Fatalf("publish = %q, %v", rec.Publish, err)
+	}
+	// 非地址记录不保存隐藏的发布方式
+	form.Type, form.Value = "TXT", "ip={{.IP}}"
+	if rec, err = parseRecordForm(form); err != nil || rec.Publish != "" {
+		t.Fatalf("TXT publish = %q, %v", rec.Publish, err)
+	}
+}
+
 func TestParseProviderRecordsRejectsEveryMismatchedField(t *testing.T) {
 	fieldNames := []string{"recordSubDomains", "recordIPVersion", "recordTTL", "recordInterval", "recordGetValue", "recordRule"}
 	for _, fieldName := range fieldNames {
@@ -1236,3 +1406,30 @@ func TestParseProviderRecordsRejectsEveryMismatchedField(t *testing.T) {
 		})
 	}
 }
+
+func TestParseProviderUsesRegisteredCredentialFields(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{name: "unknown", form: url.Values{"name": {"home"}, "provider": {"unknown"}}, want: "不支持的服务商类型"},
+		{name: "missing key id", form: url.Values{"name": {"home"}, "provider": {"aliyun"}}, want: "AccessKey ID 不能为空"},
+		{name: "missing server", form: url.Values{"name": {"home"}, "provider": {"rfc2136"}, "keyId": {"key"}}, want: "DNS 服务器 不能为空"},
+		{name: "cloudflare token only", form: url.Values{"name": {"home"}, "provider": {"cloudflare"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseProvider(&http.Request{Form: tt.form})
+			if tt.want == "" && err != nil {
+				t.Fatalf("parseProvider() error = %v", err)
+			}
+			if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+				t.Fatalf("parseProvider() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if got := providerLabel("rfc2136"); got != "RFC 2136" {
+		t.Fatalf("providerLabel() = %q", got)
+	}
+}
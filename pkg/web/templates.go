@@ -1,6 +1,7 @@
 package web
 
 import (
+	"ddns/pkg/provider"
 	"embed"
 	"html/template"
 	"net/http"
@@ -16,15 +17,28 @@ func parseTemplates() (*template.Template, error) {
 	funcs := template.FuncMap{
 		"join":          strings.Join,
 		"providerLabel": providerLabel,
+		"providers":     provider.Registrations,
 		"mask":          mask,
 		"maskWebhook":   maskWebhook,
 		"compactValue":  compactValue,
 		"durNumber":     durNumber,
 		"inc":           func(i int) int { return i + 1 },
+		"valueTypes":    valueRecordTypes,
 	}
 	return template.New("").Funcs(funcs).ParseFS(content, "templates/*.html")
 }
 
+// valueRecordTypes 记录值由模板生成的记录类型，A、AAAA 由 IP 版本决定不在表单中单独列出
+func valueRecordTypes() []string {
+	types := make([]string, 0, len(provider.RecordTypes))
+	for _, recordType := range provider.RecordTypes {
+		if provider.VersionOf(recordType) == provider.IPvAll {
+			types = append(types, recordType)
+		}
+	}
+	return types
+}
+
 func (s *Server) style(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/css; charset=utf-8")
 	data, err := content.ReadFile("static/style.css")
@@ -56,12 +70,8 @@ func (s *Server) configEventsScript(w http.ResponseWriter, r *http.Request) {
 }
 
 func providerLabel(value string) string {
-	labels := map[string]string{
-		"aliyun": "阿里云", "baidu": "百度云", "dnsla": "DNSLA",
-		"tencent": "腾讯云", "huawei": "华为云", "volcengine": "火山引擎",
-	}
-	if label, ok := labels[value]; ok {
-		return label
+	if registration, ok := provider.Lookup(value); ok {
+		return registration.Label
 	}
 	return "未选择"
 }
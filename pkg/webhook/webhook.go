@@ -20,9 +20,12 @@ var (
 )
 
 type WebhookData struct {
-	Domain   string
-	OldAddr  string
-	NewAddr  string
+	Domain  string
+	OldAddr string
+	NewAddr string
+	// 多值记录新增和删除的地址，多个使用英文逗号分隔
+	Added    string
+	Removed  string
 	Provider string
 	State    string
 	Date     string
@@ -46,6 +49,8 @@ func (w *Webhook) Send(ctx context.Context, data *WebhookData) error {
 			"{{Domain}}", url.QueryEscape(data.Domain),
 			"{{OldAddr}}", url.QueryEscape(data.OldAddr),
 			"{{NewAddr}}", url.QueryEscape(data.NewAddr),
+			"{{Added}}", url.QueryEscape(data.Added),
+			"{{Removed}}", url.QueryEscape(data.Removed),
 			"{{Provider}}", url.QueryEscape(data.Provider),
 			"{{State}}", url.QueryEscape(data.State),
 			"{{Date}}", url.QueryEscape(data.Date),
@@ -69,6 +74,8 @@ func (w *Webhook) Send(ctx context.Context, data *WebhookData) error {
 			"{{Domain}}", escapeJSON(data.Domain),
 			"{{OldAddr}}", escapeJSON(data.OldAddr),
 			"{{NewAddr}}", escapeJSON(data.NewAddr),
+			"{{Added}}", escapeJSON(data.Added),
+			"{{Removed}}", escapeJSON(data.Removed),
 			"{{Provider}}", escapeJSON(data.Provider),
 			"{{State}}", escapeJSON(data.State),
 			"{{Date}}", escapeJSON(data.Date),
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -55,6 +56,24 @@ func TestSendGETAndPOST(t *testing.T) {
 	}
 }
 
+func TestSendRecordSetChanges(t *testing.T) {
+	originalClient := httpClient
+	var query url.Values
+	httpClient = http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		query = request.URL.Query()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: make(http.Header)}, nil
+	})}
+	defer func() { httpClient = originalClient }()
+
+	cfg := config.Webhook{URL: "https://example.com/?added={{Added}}&removed={{Removed}}"}
+	if err := NewWebhook(&cfg).Send(context.Background(), &WebhookData{Added: "2001:db8::1,2001:db8::2", Removed: "2001:db8::3"}); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("added") != "2001:db8::1,2001:db8::2" || query.Get("removed") != "2001:db8::3" {
+		t.Fatalf("unexpected query: %v", query)
+	}
+}
+
 func TestSendRejectsNonSuccessOversizeAndCanceledContext(t *testing.T) {
 	tests := []struct {
 		name     string